@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/PayU/kubeobserver/pkg/config"
 	"github.com/PayU/kubeobserver/pkg/controller"
+	"github.com/PayU/kubeobserver/pkg/receivers"
 	"github.com/PayU/kubeobserver/pkg/server"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
@@ -20,6 +23,7 @@ func serve(ctx context.Context) (err error) {
 	mux := http.NewServeMux()
 	mux.Handle("/health", http.HandlerFunc(server.HealthHandler))
 	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/slack/actions", http.HandlerFunc(server.SlackActionsHandler))
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", config.Port()),
@@ -55,15 +59,21 @@ func serve(ctx context.Context) (err error) {
 }
 
 func main() {
+	// the configuration is already loaded at this point,
+	// parsing the flags makes sure no unknown flag was passed
+	flag.Parse()
 	zerolog.SetGlobalLevel(config.LogLevel())
 
+	// build the default receivers and the named receiver instances of the configuration
+	receivers.LoadReceivers()
+
 	// start k8s controller watchers
 	go controller.StartWatch(time.Now())
 
 	// create a channel for listening to OS signals
 	// and connecting OS interrupts to the channel.
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	// create a context with cancel() callback function
 	ctx, cancel := context.WithCancel(context.Background())
@@ -75,8 +85,29 @@ func main() {
 		cancel()
 	}()
 
+	// only the leader replica sends events to the receivers. the lease is released
+	// when the context is cancelled so another replica can take over right away
+	leaderElectionDone := make(chan struct{})
+	go func() {
+		controller.RunLeaderElection(ctx)
+		close(leaderElectionDone)
+	}()
+
+	// reload the configuration file on changes, the informers keep running
+	// and only the receivers are built again from the new configuration.
+	// the routes resources refer to the receivers by name, so they are validated again
+	if config.ConfigFilePath() != "" && config.ConfigReloadInterval() > 0 {
+		go config.WatchConfigFile(ctx.Done(), config.ConfigReloadInterval(), func() {
+			zerolog.SetGlobalLevel(config.LogLevel())
+			receivers.ReloadReceivers()
+			controller.RevalidateRoutes()
+		})
+	}
+
 	// start the http server
 	if err := serve(ctx); err != nil {
 		log.Error().Msg(fmt.Sprintf("failed to serve:%s\n", err))
 	}
+
+	<-leaderElectionDone
 }
@@ -5,9 +5,12 @@ go 1.14
 require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/prometheus/client_golang v1.6.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/rs/zerolog v1.19.0
+	github.com/segmentio/kafka-go v0.3.5
 	github.com/slack-go/slack v0.6.5
 	google.golang.org/appengine v1.5.0
+	gopkg.in/yaml.v2 v2.2.5
 	k8s.io/api v0.17.0
 	k8s.io/apimachinery v0.17.0
 	k8s.io/client-go v0.17.0
@@ -10,6 +10,16 @@ var receiversAnnotationName = "kubeobserver.io/receivers"
 var podCrashLoopbackStringIdentifier = "CrashLoopBackOff"
 var podHpaStringIdentifier = "HorizontalPodAutoscale"
 
+// k8s resource kinds that kubeobserver reports events about
+const (
+	PodKind                     = "Pod"
+	HorizontalPodAutoscalerKind = "HorizontalPodAutoscaler"
+	DeploymentKind              = "Deployment"
+	NodeKind                    = "Node"
+	JobKind                     = "Job"
+	CronJobKind                 = "CronJob"
+)
+
 // PodCrashLoopbackStringIdentifier is a getter for k8s api crash loopback string
 func PodCrashLoopbackStringIdentifier() string {
 	return podCrashLoopbackStringIdentifier
@@ -37,3 +47,39 @@ func BuildEventReceiversList(annotations map[string]string) []string {
 
 	return eventReceivers
 }
+
+// HasReceiversAnnotation returns true when the receivers of the resource are set by its annotations
+func HasReceiversAnnotation(annotations map[string]string) bool {
+	return annotations != nil && annotations[receiversAnnotationName] != ""
+}
+
+// RouteEventReceivers builds the list of receivers for an event. receivers from the resource annotations
+// take precedence, otherwise the receivers of all the configured routes that match the event are used.
+// when no route matches, the default receiver is used
+func RouteEventReceivers(annotations map[string]string, namespace string, kind string, severity string, reason string) []string {
+	if annotations != nil && annotations[receiversAnnotationName] != "" {
+		return BuildEventReceiversList(annotations)
+	}
+
+	eventReceivers := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, route := range config.Routes() {
+		if !route.Matches(namespace, kind, severity, reason) {
+			continue
+		}
+
+		for _, receiver := range route.Receivers {
+			if !seen[receiver] {
+				seen[receiver] = true
+				eventReceivers = append(eventReceivers, receiver)
+			}
+		}
+	}
+
+	if len(eventReceivers) == 0 {
+		return BuildEventReceiversList(annotations)
+	}
+
+	return eventReceivers
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -12,6 +14,13 @@ import (
 
 var mandatoryEnvironmentVariables = []string{"K8S_CLUSTER_NAME", "PORT"}
 
+// optInWatchers run only when they are listed in the watchers configuration. the event watcher
+// reports events that the other watchers don't, so it isn't enabled on upgrades by default
+var optInWatchers = []string{"event"}
+
+// defaultEventReasons are the reasons of k8s Events that usually explain why a workload fails
+var defaultEventReasons = []string{"FailedScheduling", "FailedMount", "FailedAttachVolume", "BackOff", "Unhealthy", "FailedCreate", "Evicted", "OOMKilling"}
+
 var k8sClusterName string
 var kubeConfigFilePath *string
 var logLevel zerolog.Level
@@ -21,28 +30,90 @@ var slackToken string
 var defaultReceiver string
 var watcherThreads int
 var port int
+var webhookURLs []string
+var webhookHeaders map[string]string
+var webhookTimeout time.Duration
+var webhookRetries int
+var webhookSecret string
+var pagerDutyURL string
+var pagerDutyRoutingKey string
+var pagerDutyNamespaceRoutingKeys map[string]string
+var alertmanagerURLs []string
+var teamsWebhookURLs []string
+var emailSMTPHost string
+var emailSMTPPort int
+var emailUsername string
+var emailPassword string
+var emailStartTLS bool
+var emailFrom string
+var emailTo []string
+var emailDigestInterval time.Duration
+var kafkaBrokers []string
+var kafkaTopic string
+var kafkaTLS bool
+var kafkaTLSCAFile string
+var kafkaSASLMechanism string
+var kafkaSASLUsername string
+var kafkaSASLPassword string
+var kafkaBatchSize int
+var kafkaBatchTimeout time.Duration
+var messageTemplatesDir string
+var messageTemplates map[string]string
+var alertmanagerResendInterval time.Duration
+var alertmanagerAlertTTL time.Duration
+var watchers []string
+var includeNamespaces []string
+var excludeNamespaces []string
+var routes []Route
+var eventTypes []string
+var eventReasons []string
+var eventRepeatInterval time.Duration
+var slackMentions []string
+var slackThreadsEnabled bool
+var slackThreadTTL time.Duration
+var slackThreadCacheSize int
+var slackThreadUpdateParent bool
+var slackSigningSecret string
+var slackRestartAllowlist []string
+var slackChannelRoutes []SlackChannelRoute
+var receiverInstances []ReceiverInstance
+var configReloadInterval time.Duration
+var aggregationWindow time.Duration
+var aggregationGroupBy []string
+var aggregationMaxBatchSize int
+var leaderElectionEnabled bool
+var leaderElectionNamespace string
+var leaderElectionLeaseName string
+var leaderElectionLeaseDuration time.Duration
+var leaderElectionRenewDeadline time.Duration
+var leaderElectionRetryPeriod time.Duration
+
+// configLock guards the values that can be changed at runtime by a configuration reload
+var configLock sync.RWMutex
+
+// configFilePath is the path of the YAML configuration file (if any)
+// and configFile holds its content. environment variables take precedence over the file values
+var configFilePath string
+var configFile = &fileConfig{}
 
 func init() {
+	if configFilePath = lookupConfigFilePath(os.Args[1:]); configFilePath != "" {
+		file, err := readConfigFile(configFilePath)
+		if err != nil {
+			panic(fmt.Sprintf("error on reading configuration file %s:[%v]", configFilePath, err))
+		}
+
+		configFile = file
+	}
+
 	setLogLevel()
 	verifyMandatoryVariables()
 	var err error
 
-	k8sClusterName = os.Getenv("K8S_CLUSTER_NAME")
-	slackToken = os.Getenv("SLACK_TOKEN")
+	k8sClusterName = getEnvOrFile("K8S_CLUSTER_NAME", configFile.ClusterName)
+	watchers = getListEnvOrFile("WATCHERS", configFile.Watchers)
 
-	if os.Getenv("EXCLUDE_POD_NAME_PATTERNS") == "" {
-		excludePodNamePatterns = make([]string, 0)
-	} else {
-		excludePodNamePatterns = strings.Split(os.Getenv("EXCLUDE_POD_NAME_PATTERNS"), ",")
-	}
-
-	if os.Getenv("SLACK_CHANNEL_NAMES") == "" {
-		slackChannelNames = make([]string, 0)
-	} else {
-		slackChannelNames = strings.Split(os.Getenv("SLACK_CHANNEL_NAMES"), ",")
-	}
-
-	if confFile := os.Getenv("K8S_CONF_FILE_PATH"); confFile != "" {
+	if confFile := getEnvOrFile("K8S_CONF_FILE_PATH", configFile.KubeConfigFilePath); confFile != "" {
 		kubeConfigFilePath = &confFile
 	} else {
 		home := homeDir()
@@ -50,19 +121,17 @@ func init() {
 		kubeConfigFilePath = &confFile
 	}
 
-	if defaultReceiver = os.Getenv("DEFAULT_RECEIVER"); defaultReceiver == "" {
-		defaultReceiver = "slack"
-	}
-
 	if threads := os.Getenv("WATCHER_THREADS"); threads != "" {
 		if watcherThreads, err = strconv.Atoi(threads); err != nil {
 			panic(fmt.Sprintf("error on parsing WATCHER_THREADS:[%v]", err))
 		}
+	} else if configFile.WatcherThreads > 0 {
+		watcherThreads = configFile.WatcherThreads
 	} else {
 		watcherThreads = 10
 	}
 
-	if p, err := strconv.Atoi(os.Getenv("PORT")); err == nil {
+	if p, err := strconv.Atoi(getEnvOrFile("PORT", strconv.Itoa(configFile.Port))); err == nil {
 		if p < 1 || p > 65535 {
 			panic("PORT env variable must be valid int between 1-65535")
 		}
@@ -72,9 +141,380 @@ func init() {
 		panic("PORT env variable must be valid int between 1-65535")
 	}
 
+	if configReloadInterval, err = getDurationEnvOrFile("CONFIG_RELOAD_INTERVAL", configFile.ReloadInterval, 10*time.Second); err != nil {
+		panic(err.Error())
+	}
+
+	if err = setLeaderElection(configFile.LeaderElection); err != nil {
+		panic(err.Error())
+	}
+
+	if err = setAggregation(configFile.Aggregation); err != nil {
+		panic(err.Error())
+	}
+
+	rc, err := loadReloadableConfig(configFile)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	applyReloadableConfig(rc)
 	outputConfig()
 }
 
+// setLeaderElection sets the leader election values. the lease namespace defaults to
+// the namespace kubeobserver runs in (POD_NAMESPACE) and the timings to the kube-controller-manager defaults
+func setLeaderElection(file leaderElection) error {
+	var err error
+
+	if leaderElectionEnabled, err = getBoolEnvOrFile("LEADER_ELECTION", file.Enabled); err != nil {
+		return err
+	}
+
+	leaderElectionNamespace = getEnvOrFile("LEADER_ELECTION_NAMESPACE", file.Namespace)
+	if leaderElectionNamespace == "" {
+		leaderElectionNamespace = getEnvOrFile("POD_NAMESPACE", "default")
+	}
+
+	leaderElectionLeaseName = getEnvOrFile("LEADER_ELECTION_LEASE_NAME", file.LeaseName)
+	if leaderElectionLeaseName == "" {
+		leaderElectionLeaseName = "kubeobserver"
+	}
+
+	if leaderElectionLeaseDuration, err = getDurationEnvOrFile("LEADER_ELECTION_LEASE_DURATION", file.LeaseDuration, 15*time.Second); err != nil {
+		return err
+	}
+
+	if leaderElectionRenewDeadline, err = getDurationEnvOrFile("LEADER_ELECTION_RENEW_DEADLINE", file.RenewDeadline, 10*time.Second); err != nil {
+		return err
+	}
+
+	if leaderElectionRetryPeriod, err = getDurationEnvOrFile("LEADER_ELECTION_RETRY_PERIOD", file.RetryPeriod, 2*time.Second); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setAggregation sets the events aggregation values. aggregation is disabled when the window is zero
+func setAggregation(file aggregation) error {
+	var err error
+
+	if aggregationWindow, err = getDurationEnvOrFile("AGGREGATION_WINDOW", file.Window, 0); err != nil {
+		return err
+	}
+
+	aggregationGroupBy = getListEnvOrFile("AGGREGATION_GROUP_BY", file.GroupBy)
+	if len(aggregationGroupBy) == 0 {
+		aggregationGroupBy = []string{"owner", "namespace", "reason"}
+	}
+
+	for _, field := range aggregationGroupBy {
+		if !contains(validAggregationGroupBy, field) {
+			return fmt.Errorf("unknown aggregation group by field '%s'. valid values are %s", field, strings.Join(validAggregationGroupBy, ","))
+		}
+	}
+
+	if size := os.Getenv("AGGREGATION_MAX_BATCH_SIZE"); size != "" {
+		if aggregationMaxBatchSize, err = strconv.Atoi(size); err != nil {
+			return fmt.Errorf("error on parsing AGGREGATION_MAX_BATCH_SIZE:[%v]", err)
+		}
+	} else if file.MaxBatchSize > 0 {
+		aggregationMaxBatchSize = file.MaxBatchSize
+	} else {
+		aggregationMaxBatchSize = 50
+	}
+
+	return nil
+}
+
+// reloadableConfig holds all the values that can be changed at runtime by reloading the configuration file
+type reloadableConfig struct {
+	logLevel                zerolog.Level
+	excludePodNamePatterns  []string
+	slackChannelNames       []string
+	slackToken              string
+	slackMentions           []string
+	slackThreadsEnabled     bool
+	slackThreadTTL          time.Duration
+	slackThreadCacheSize    int
+	slackThreadUpdateParent bool
+	slackSigningSecret      string
+	slackRestartAllowlist   []string
+	slackChannelRoutes      []SlackChannelRoute
+	receiverInstances       []ReceiverInstance
+	defaultReceiver         string
+	webhookURLs             []string
+	webhookHeaders          map[string]string
+	webhookTimeout          time.Duration
+	webhookRetries          int
+	webhookSecret           string
+	pagerDutyURL            string
+	pagerDutyRoutingKey     string
+	pagerDutyNamespaceKeys  map[string]string
+	alertmanagerURLs        []string
+	alertmanagerResend      time.Duration
+	alertmanagerAlertTTL    time.Duration
+	teamsWebhookURLs        []string
+	emailSMTPHost           string
+	emailSMTPPort           int
+	emailUsername           string
+	emailPassword           string
+	emailStartTLS           bool
+	emailFrom               string
+	emailTo                 []string
+	emailDigestInterval     time.Duration
+	kafkaBrokers            []string
+	kafkaTopic              string
+	kafkaTLS                bool
+	kafkaTLSCAFile          string
+	kafkaSASLMechanism      string
+	kafkaSASLUsername       string
+	kafkaSASLPassword       string
+	kafkaBatchSize          int
+	kafkaBatchTimeout       time.Duration
+	messageTemplatesDir     string
+	messageTemplates        map[string]string
+	includeNamespaces       []string
+	excludeNamespaces       []string
+	routes                  []Route
+	eventTypes              []string
+	eventReasons            []string
+	eventRepeatInterval     time.Duration
+}
+
+// loadReloadableConfig builds the runtime values from the environment and the given configuration file
+func loadReloadableConfig(file *fileConfig) (*reloadableConfig, error) {
+	var err error
+	rc := &reloadableConfig{
+		logLevel:               parseLogLevel(getEnvOrFile("LOG_LEVEL", file.LogLevel)),
+		excludePodNamePatterns: getListEnvOrFile("EXCLUDE_POD_NAME_PATTERNS", file.ExcludePodNamePatterns),
+		slackChannelNames:      getListEnvOrFile("SLACK_CHANNEL_NAMES", file.Receivers.Slack.Channels),
+		slackToken:             getEnvOrFile("SLACK_TOKEN", file.Receivers.Slack.Token),
+		slackMentions:          getListEnvOrFile("SLACK_MENTIONS", file.Receivers.Slack.Mentions),
+		slackSigningSecret:     getEnvOrFile("SLACK_SIGNING_SECRET", file.Receivers.Slack.SigningSecret),
+		slackRestartAllowlist:  getListEnvOrFile("SLACK_RESTART_ALLOWLIST", file.Receivers.Slack.RestartAllowlist),
+		defaultReceiver:        getEnvOrFile("DEFAULT_RECEIVER", file.DefaultReceiver),
+		webhookURLs:            getListEnvOrFile("WEBHOOK_URLS", file.Receivers.Webhook.URLs),
+		webhookSecret:          getEnvOrFile("WEBHOOK_SECRET", file.Receivers.Webhook.Secret),
+		pagerDutyURL:           getEnvOrFile("PAGERDUTY_URL", file.Receivers.PagerDuty.URL),
+		pagerDutyRoutingKey:    getEnvOrFile("PAGERDUTY_ROUTING_KEY", file.Receivers.PagerDuty.RoutingKey),
+		alertmanagerURLs:       getListEnvOrFile("ALERTMANAGER_URLS", file.Receivers.Alertmanager.URLs),
+		teamsWebhookURLs:       getListEnvOrFile("TEAMS_WEBHOOK_URLS", file.Receivers.Teams.WebhookURLs),
+		emailSMTPHost:          getEnvOrFile("EMAIL_SMTP_HOST", file.Receivers.Email.Host),
+		emailUsername:          getEnvOrFile("EMAIL_USERNAME", file.Receivers.Email.Username),
+		emailPassword:          getEnvOrFile("EMAIL_PASSWORD", file.Receivers.Email.Password),
+		emailFrom:              getEnvOrFile("EMAIL_FROM", file.Receivers.Email.From),
+		emailTo:                getListEnvOrFile("EMAIL_TO", file.Receivers.Email.To),
+		kafkaBrokers:           getListEnvOrFile("KAFKA_BROKERS", file.Receivers.Kafka.Brokers),
+		kafkaTopic:             getEnvOrFile("KAFKA_TOPIC", file.Receivers.Kafka.Topic),
+		kafkaTLSCAFile:         getEnvOrFile("KAFKA_TLS_CA_FILE", file.Receivers.Kafka.TLS.CAFile),
+		kafkaSASLMechanism:     strings.ToUpper(getEnvOrFile("KAFKA_SASL_MECHANISM", file.Receivers.Kafka.SASL.Mechanism)),
+		kafkaSASLUsername:      getEnvOrFile("KAFKA_SASL_USERNAME", file.Receivers.Kafka.SASL.Username),
+		kafkaSASLPassword:      getEnvOrFile("KAFKA_SASL_PASSWORD", file.Receivers.Kafka.SASL.Password),
+		messageTemplatesDir:    getEnvOrFile("MESSAGE_TEMPLATES_DIR", file.TemplatesDir),
+		includeNamespaces:      getListEnvOrFile("INCLUDE_NAMESPACES", file.Namespaces.Include),
+		excludeNamespaces:      getListEnvOrFile("EXCLUDE_NAMESPACES", file.Namespaces.Exclude),
+		routes:                 file.Routes,
+		slackChannelRoutes:     file.Receivers.Slack.Routes,
+		eventTypes:             getListEnvOrFile("EVENT_TYPES", file.Events.Types),
+		eventReasons:           getListEnvOrFile("EVENT_REASONS", file.Events.Reasons),
+	}
+
+	if len(rc.eventTypes) == 0 {
+		rc.eventTypes = []string{"Warning"}
+	}
+
+	if len(rc.eventReasons) == 0 {
+		rc.eventReasons = defaultEventReasons
+	}
+
+	if rc.eventRepeatInterval, err = getDurationEnvOrFile("EVENT_REPEAT_INTERVAL", file.Events.RepeatInterval, 10*time.Minute); err != nil {
+		return nil, err
+	}
+
+	if rc.defaultReceiver == "" {
+		rc.defaultReceiver = "slack"
+	}
+
+	if headers := os.Getenv("WEBHOOK_HEADERS"); headers != "" || file.Receivers.Webhook.Headers == nil {
+		rc.webhookHeaders = parseKeyValueList(headers)
+	} else {
+		rc.webhookHeaders = file.Receivers.Webhook.Headers
+	}
+
+	if rc.pagerDutyURL == "" {
+		rc.pagerDutyURL = "https://events.pagerduty.com"
+	}
+
+	if keys := os.Getenv("PAGERDUTY_NAMESPACE_ROUTING_KEYS"); keys != "" || file.Receivers.PagerDuty.NamespaceRoutingKeys == nil {
+		rc.pagerDutyNamespaceKeys = parseKeyValueList(keys)
+	} else {
+		rc.pagerDutyNamespaceKeys = file.Receivers.PagerDuty.NamespaceRoutingKeys
+	}
+
+	if timeout := getEnvOrFile("WEBHOOK_TIMEOUT", file.Receivers.Webhook.Timeout); timeout != "" {
+		if rc.webhookTimeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, fmt.Errorf("error on parsing WEBHOOK_TIMEOUT:[%v]", err)
+		}
+	} else {
+		rc.webhookTimeout = 5 * time.Second
+	}
+
+	alertmanager := file.Receivers.Alertmanager
+	if rc.alertmanagerResend, err = getDurationEnvOrFile("ALERTMANAGER_RESEND_INTERVAL", alertmanager.ResendInterval, time.Minute); err != nil {
+		return nil, err
+	}
+
+	if rc.alertmanagerAlertTTL, err = getDurationEnvOrFile("ALERTMANAGER_ALERT_TTL", alertmanager.AlertTTL, time.Hour); err != nil {
+		return nil, err
+	}
+
+	if rc.alertmanagerResend <= 0 || rc.alertmanagerAlertTTL <= 0 {
+		return nil, fmt.Errorf("ALERTMANAGER_RESEND_INTERVAL and ALERTMANAGER_ALERT_TTL must be positive durations")
+	}
+
+	email := file.Receivers.Email
+	if port := os.Getenv("EMAIL_SMTP_PORT"); port != "" {
+		if rc.emailSMTPPort, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("error on parsing EMAIL_SMTP_PORT:[%v]", err)
+		}
+	} else if email.Port > 0 {
+		rc.emailSMTPPort = email.Port
+	} else {
+		rc.emailSMTPPort = 587
+	}
+
+	startTLS := email.StartTLS == nil || *email.StartTLS
+	if rc.emailStartTLS, err = getBoolEnvOrFile("EMAIL_STARTTLS", startTLS); err != nil {
+		return nil, err
+	}
+
+	if rc.emailDigestInterval, err = getDurationEnvOrFile("EMAIL_DIGEST_INTERVAL", email.DigestInterval, 0); err != nil {
+		return nil, err
+	}
+
+	kafka := file.Receivers.Kafka
+	if rc.kafkaTLS, err = getBoolEnvOrFile("KAFKA_TLS", kafka.TLS.Enabled); err != nil {
+		return nil, err
+	}
+
+	if size := os.Getenv("KAFKA_BATCH_SIZE"); size != "" {
+		if rc.kafkaBatchSize, err = strconv.Atoi(size); err != nil {
+			return nil, fmt.Errorf("error on parsing KAFKA_BATCH_SIZE:[%v]", err)
+		}
+	} else if kafka.BatchSize > 0 {
+		rc.kafkaBatchSize = kafka.BatchSize
+	} else {
+		rc.kafkaBatchSize = 100
+	}
+
+	if rc.kafkaBatchTimeout, err = getDurationEnvOrFile("KAFKA_BATCH_TIMEOUT", kafka.BatchTimeout, 100*time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	if rc.messageTemplates, err = loadMessageTemplates(file.Templates, rc.messageTemplatesDir); err != nil {
+		return nil, err
+	}
+
+	threads := file.Receivers.Slack.Threads
+	if rc.slackThreadsEnabled, err = getBoolEnvOrFile("SLACK_THREADS", threads.Enabled); err != nil {
+		return nil, err
+	}
+
+	if rc.slackThreadTTL, err = getDurationEnvOrFile("SLACK_THREAD_TTL", threads.TTL, time.Hour); err != nil {
+		return nil, err
+	}
+
+	if size := os.Getenv("SLACK_THREAD_CACHE_SIZE"); size != "" {
+		if rc.slackThreadCacheSize, err = strconv.Atoi(size); err != nil {
+			return nil, fmt.Errorf("error on parsing SLACK_THREAD_CACHE_SIZE:[%v]", err)
+		}
+	} else if threads.MaxSize > 0 {
+		rc.slackThreadCacheSize = threads.MaxSize
+	} else {
+		rc.slackThreadCacheSize = 1000
+	}
+
+	updateParent := threads.UpdateParent == nil || *threads.UpdateParent
+	if rc.slackThreadUpdateParent, err = getBoolEnvOrFile("SLACK_THREAD_UPDATE_PARENT", updateParent); err != nil {
+		return nil, err
+	}
+
+	if retries := os.Getenv("WEBHOOK_RETRIES"); retries != "" {
+		if rc.webhookRetries, err = strconv.Atoi(retries); err != nil {
+			return nil, fmt.Errorf("error on parsing WEBHOOK_RETRIES:[%v]", err)
+		}
+	} else if file.Receivers.Webhook.Retries != nil {
+		rc.webhookRetries = *file.Receivers.Webhook.Retries
+	} else {
+		rc.webhookRetries = 3
+	}
+
+	if rc.receiverInstances, err = buildReceiverInstances(file.Receivers.Instances, rc.webhookTimeout, rc.webhookRetries); err != nil {
+		return nil, err
+	}
+
+	return rc, nil
+}
+
+func applyReloadableConfig(rc *reloadableConfig) {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	logLevel = rc.logLevel
+	excludePodNamePatterns = rc.excludePodNamePatterns
+	slackChannelNames = rc.slackChannelNames
+	slackToken = rc.slackToken
+	slackMentions = rc.slackMentions
+	slackThreadsEnabled = rc.slackThreadsEnabled
+	slackThreadTTL = rc.slackThreadTTL
+	slackThreadCacheSize = rc.slackThreadCacheSize
+	slackThreadUpdateParent = rc.slackThreadUpdateParent
+	slackSigningSecret = rc.slackSigningSecret
+	slackRestartAllowlist = rc.slackRestartAllowlist
+	slackChannelRoutes = rc.slackChannelRoutes
+	receiverInstances = rc.receiverInstances
+	defaultReceiver = rc.defaultReceiver
+	webhookURLs = rc.webhookURLs
+	webhookHeaders = rc.webhookHeaders
+	webhookTimeout = rc.webhookTimeout
+	webhookRetries = rc.webhookRetries
+	webhookSecret = rc.webhookSecret
+	pagerDutyURL = rc.pagerDutyURL
+	pagerDutyRoutingKey = rc.pagerDutyRoutingKey
+	pagerDutyNamespaceRoutingKeys = rc.pagerDutyNamespaceKeys
+	alertmanagerURLs = rc.alertmanagerURLs
+	alertmanagerResendInterval = rc.alertmanagerResend
+	alertmanagerAlertTTL = rc.alertmanagerAlertTTL
+	teamsWebhookURLs = rc.teamsWebhookURLs
+	emailSMTPHost = rc.emailSMTPHost
+	emailSMTPPort = rc.emailSMTPPort
+	emailUsername = rc.emailUsername
+	emailPassword = rc.emailPassword
+	emailStartTLS = rc.emailStartTLS
+	emailFrom = rc.emailFrom
+	emailTo = rc.emailTo
+	emailDigestInterval = rc.emailDigestInterval
+	kafkaBrokers = rc.kafkaBrokers
+	kafkaTopic = rc.kafkaTopic
+	kafkaTLS = rc.kafkaTLS
+	kafkaTLSCAFile = rc.kafkaTLSCAFile
+	kafkaSASLMechanism = rc.kafkaSASLMechanism
+	kafkaSASLUsername = rc.kafkaSASLUsername
+	kafkaSASLPassword = rc.kafkaSASLPassword
+	kafkaBatchSize = rc.kafkaBatchSize
+	kafkaBatchTimeout = rc.kafkaBatchTimeout
+	messageTemplatesDir = rc.messageTemplatesDir
+	messageTemplates = rc.messageTemplates
+	includeNamespaces = rc.includeNamespaces
+	excludeNamespaces = rc.excludeNamespaces
+	routes = rc.routes
+	eventTypes = rc.eventTypes
+	eventReasons = rc.eventReasons
+	eventRepeatInterval = rc.eventRepeatInterval
+}
+
 // Port is a getter for port int variable
 func Port() int {
 	return port
@@ -82,6 +522,9 @@ func Port() int {
 
 // LogLevel is a getter for zerolog log level
 func LogLevel() zerolog.Level {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
 	return logLevel
 }
 
@@ -92,44 +535,55 @@ func ClusterName() string {
 
 // ExcludePodNamePatterns is a getter function for the excludePodNamePatterns slice
 func ExcludePodNamePatterns() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
 	return excludePodNamePatterns
 }
 
 // SlackChannelNames is a getter funcrtion for the ChannelNames slice
 func SlackChannelNames() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
 	return slackChannelNames
 }
 
 // DefaultReceiver is a getter function for DefaultReceiver string
 func DefaultReceiver() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
 	return defaultReceiver
 }
 
 func verifyMandatoryVariables() {
+	fileValues := map[string]bool{
+		"K8S_CLUSTER_NAME": configFile.ClusterName != "",
+		"PORT":             configFile.Port != 0,
+	}
+
 	for _, envVar := range mandatoryEnvironmentVariables {
-		if os.Getenv(envVar) == "" {
+		if os.Getenv(envVar) == "" && !fileValues[envVar] {
 			panic(fmt.Sprintf("missing mandatory environment variable: %s", envVar))
 		}
 	}
 }
 
 func setLogLevel() {
-	logLevelStr := os.Getenv("LOG_LEVEL")
-
-	if logLevelStr == "" {
-		logLevel = zerolog.InfoLevel
-		return
-	}
+	logLevel = parseLogLevel(getEnvOrFile("LOG_LEVEL", configFile.LogLevel))
+}
 
+func parseLogLevel(logLevelStr string) zerolog.Level {
 	switch strings.ToLower(logLevelStr) {
 	case "debug":
-		logLevel = zerolog.DebugLevel
-	case "info":
-		logLevel = zerolog.InfoLevel
+		return zerolog.DebugLevel
 	case "warn":
-		logLevel = zerolog.WarnLevel
+		return zerolog.WarnLevel
 	case "error":
-		logLevel = zerolog.ErrorLevel
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
 	}
 }
 
@@ -148,6 +602,9 @@ func KubeConfFilePath() *string {
 
 // SlackToken is a getter function to get a slack API token from environment
 func SlackToken() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
 	return slackToken
 }
 
@@ -156,14 +613,489 @@ func WatcherThreads() int {
 	return watcherThreads
 }
 
+// WebhookURLs is a getter function for the webhook receiver URLs slice
+func WebhookURLs() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return webhookURLs
+}
+
+// WebhookHeaders is a getter function for the additional headers the webhook receiver sends
+func WebhookHeaders() map[string]string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return webhookHeaders
+}
+
+// WebhookTimeout is a getter function for the webhook receiver request timeout
+func WebhookTimeout() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return webhookTimeout
+}
+
+// WebhookRetries is a getter function for the number of times the webhook receiver retries a failed request
+func WebhookRetries() int {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return webhookRetries
+}
+
+// WebhookSecret is a getter function for the webhook receiver HMAC signing secret
+func WebhookSecret() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return webhookSecret
+}
+
+// PagerDutyURL is a getter function for the base URL of the PagerDuty Events API
+func PagerDutyURL() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return pagerDutyURL
+}
+
+// PagerDutyRoutingKey is a getter function for the default PagerDuty integration routing key
+func PagerDutyRoutingKey() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return pagerDutyRoutingKey
+}
+
+// PagerDutyNamespaceRoutingKeys is a getter function for the PagerDuty routing keys by namespace,
+// which take precedence over the default routing key
+func PagerDutyNamespaceRoutingKeys() map[string]string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return pagerDutyNamespaceRoutingKeys
+}
+
+// AlertmanagerURLs is a getter function for the base URLs of the Alertmanager instances
+func AlertmanagerURLs() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return alertmanagerURLs
+}
+
+// AlertmanagerResendInterval is a getter function for how often the active alerts are sent again to Alertmanager
+func AlertmanagerResendInterval() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return alertmanagerResendInterval
+}
+
+// AlertmanagerAlertTTL is a getter function for how long an alert without a recovery event stays active
+func AlertmanagerAlertTTL() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return alertmanagerAlertTTL
+}
+
+// TeamsWebhookURLs is a getter function for the Microsoft Teams incoming webhook or Workflows URLs
+func TeamsWebhookURLs() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return teamsWebhookURLs
+}
+
+// EmailSMTPHost is a getter function for the host of the SMTP server email receiver sends the mails through
+func EmailSMTPHost() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return emailSMTPHost
+}
+
+// EmailSMTPPort is a getter function for the port of the SMTP server
+func EmailSMTPPort() int {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return emailSMTPPort
+}
+
+// EmailUsername is a getter function for the SMTP username. an empty username disables authentication
+func EmailUsername() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return emailUsername
+}
+
+// EmailPassword is a getter function for the SMTP password
+func EmailPassword() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return emailPassword
+}
+
+// EmailStartTLS is a getter function for whether the SMTP connection must be upgraded with STARTTLS
+func EmailStartTLS() bool {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return emailStartTLS
+}
+
+// EmailFrom is a getter function for the sender address of the mails
+func EmailFrom() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return emailFrom
+}
+
+// EmailTo is a getter function for the default recipients of the mails
+func EmailTo() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return emailTo
+}
+
+// EmailDigestInterval is a getter function for the interval the events are batched into a single mail for.
+// a zero interval sends a mail per event
+func EmailDigestInterval() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return emailDigestInterval
+}
+
+// KafkaBrokers is a getter function for the bootstrap brokers (host:port) of the kafka receiver
+func KafkaBrokers() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaBrokers
+}
+
+// KafkaTopic is a getter function for the topic kafka receiver publishes the events to
+func KafkaTopic() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaTopic
+}
+
+// KafkaTLS is a getter function for whether the connections to the kafka brokers use TLS
+func KafkaTLS() bool {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaTLS
+}
+
+// KafkaTLSCAFile is a getter function for the CA certificates file the kafka brokers certificates are
+// verified with. an empty path uses the system certificates
+func KafkaTLSCAFile() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaTLSCAFile
+}
+
+// KafkaSASLMechanism is a getter function for the SASL mechanism of the kafka connections
+// (PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512). an empty mechanism disables SASL
+func KafkaSASLMechanism() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaSASLMechanism
+}
+
+// KafkaSASLUsername is a getter function for the SASL username of the kafka connections
+func KafkaSASLUsername() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaSASLUsername
+}
+
+// KafkaSASLPassword is a getter function for the SASL password of the kafka connections
+func KafkaSASLPassword() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaSASLPassword
+}
+
+// KafkaBatchSize is a getter function for the number of events that are published together without
+// waiting for the batch timeout
+func KafkaBatchSize() int {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaBatchSize
+}
+
+// KafkaBatchTimeout is a getter function for the time events wait for other events before they are published
+func KafkaBatchTimeout() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return kafkaBatchTimeout
+}
+
+// MessageTemplates is a getter function for the message templates that override the built-in templates, by name.
+// the templates of the templates directory take precedence over the templates of the configuration file
+func MessageTemplates() map[string]string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return messageTemplates
+}
+
+// ConfigReloadInterval is a getter function for the interval the configuration file is checked for changes.
+// a zero interval disables the configuration reload
+func ConfigReloadInterval() time.Duration {
+	return configReloadInterval
+}
+
+// AggregationWindow is a getter function for the time window identical events are deduplicated
+// and bursts of events are aggregated in. a zero window disables the aggregation
+func AggregationWindow() time.Duration {
+	return aggregationWindow
+}
+
+// AggregationGroupBy is a getter function for the event fields (owner, namespace, reason, kind) events are aggregated by
+func AggregationGroupBy() []string {
+	return aggregationGroupBy
+}
+
+// AggregationMaxBatchSize is a getter function for the number of aggregated events that triggers a summary before the window ends
+func AggregationMaxBatchSize() int {
+	return aggregationMaxBatchSize
+}
+
+// LeaderElectionEnabled returns true when only the elected replica should send events to the receivers
+func LeaderElectionEnabled() bool {
+	return leaderElectionEnabled
+}
+
+// LeaderElectionNamespace is a getter function for the namespace of the leader election lease
+func LeaderElectionNamespace() string {
+	return leaderElectionNamespace
+}
+
+// LeaderElectionLeaseName is a getter function for the name of the leader election lease
+func LeaderElectionLeaseName() string {
+	return leaderElectionLeaseName
+}
+
+// LeaderElectionLeaseDuration is a getter function for the time non leader replicas wait before taking over the lease
+func LeaderElectionLeaseDuration() time.Duration {
+	return leaderElectionLeaseDuration
+}
+
+// LeaderElectionRenewDeadline is a getter function for the time the leader keeps trying to renew the lease before giving it up
+func LeaderElectionRenewDeadline() time.Duration {
+	return leaderElectionRenewDeadline
+}
+
+// LeaderElectionRetryPeriod is a getter function for the time between lease acquire and renew attempts
+func LeaderElectionRetryPeriod() time.Duration {
+	return leaderElectionRetryPeriod
+}
+
+// WatcherEnabled returns true if the watcher with the given name (pod, hpa, deployment..) should run.
+// all watchers except the opt-in ones are enabled when no watchers were configured
+func WatcherEnabled(name string) bool {
+	if len(watchers) == 0 {
+		return !contains(optInWatchers, name)
+	}
+
+	return contains(watchers, name)
+}
+
+// ShouldWatchNamespace returns true if events from the given namespace should be handled
+// based on the included and excluded namespaces configuration
+func ShouldWatchNamespace(namespace string) bool {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	if contains(excludeNamespaces, namespace) {
+		return false
+	}
+
+	return len(includeNamespaces) == 0 || contains(includeNamespaces, namespace)
+}
+
+// EventRepeatInterval is a getter function for the minimum time between two reports of a repeating k8s Event
+func EventRepeatInterval() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return eventRepeatInterval
+}
+
+// ShouldForwardKubeEvent returns true if a k8s Event with the given type (Normal, Warning)
+// and reason is in the configured events filter
+func ShouldForwardKubeEvent(eventType string, reason string) bool {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return contains(eventTypes, eventType) && (contains(eventReasons, "*") || contains(eventReasons, reason))
+}
+
+// SlackMentions is a getter function for the slack users IDs that are mentioned on critical events
+// of resources that don't define their own users to mention
+func SlackMentions() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return slackMentions
+}
+
+// SlackThreadsEnabled returns true when follow-up slack messages of the same resource are posted as thread replies
+func SlackThreadsEnabled() bool {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return slackThreadsEnabled
+}
+
+// SlackThreadTTL is a getter function for the time a resource keeps posting to the same slack thread
+func SlackThreadTTL() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return slackThreadTTL
+}
+
+// SlackThreadCacheSize is a getter function for the maximum number of slack threads kept in memory
+func SlackThreadCacheSize() int {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return slackThreadCacheSize
+}
+
+// SlackThreadUpdateParent returns true when the first message of a slack thread should reflect the latest event
+func SlackThreadUpdateParent() bool {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return slackThreadUpdateParent
+}
+
+// SlackSigningSecret is a getter function for the secret slack signs the interactivity requests with.
+// the action buttons of slack messages are enabled only when it is set
+func SlackSigningSecret() string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return slackSigningSecret
+}
+
+// SlackRestartAllowlist is a getter function for the namespace/deployment patterns that can be restarted from slack
+func SlackRestartAllowlist() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return slackRestartAllowlist
+}
+
+// SlackChannelRoutes is a getter function for the slack channel routing rules from the configuration file
+func SlackChannelRoutes() []SlackChannelRoute {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return slackChannelRoutes
+}
+
+// ReceiverInstances is a getter function for the named receivers from the configuration file
+func ReceiverInstances() []ReceiverInstance {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return receiverInstances
+}
+
+// Routes is a getter function for the routing rules from the configuration file
+func Routes() []Route {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return routes
+}
+
+// parseKeyValueList parses a comma separated string of key=value pairs into a map
+func parseKeyValueList(s string) map[string]string {
+	result := make(map[string]string)
+
+	for _, pair := range strings.Split(s, ",") {
+		keyValue := strings.SplitN(pair, "=", 2)
+
+		if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+			continue
+		}
+
+		result[strings.TrimSpace(keyValue[0])] = strings.TrimSpace(keyValue[1])
+	}
+
+	return result
+}
+
 func outputConfig() {
 	log.Info().
+		Str("configFilePath", configFilePath).
 		Str("k8sClusterName", k8sClusterName).
 		Str("logLevel", logLevel.String()).
 		Str("excludePodNamePatterns", strings.Join(excludePodNamePatterns, " ")).
 		Str("defaultReceiver", defaultReceiver).
 		Int("port", port).
 		Str("slackChannelNames", strings.Join(slackChannelNames, ",")).
+		Bool("slackActions", slackSigningSecret != "").
+		Str("slackRestartAllowlist", strings.Join(slackRestartAllowlist, ",")).
 		Int("watcherThreads", watcherThreads).
+		Str("webhookURLs", strings.Join(webhookURLs, ",")).
+		Dur("webhookTimeout", webhookTimeout).
+		Int("webhookRetries", webhookRetries).
+		Str("pagerDutyURL", pagerDutyURL).
+		Str("alertmanagerURLs", strings.Join(alertmanagerURLs, ",")).
+		Dur("alertmanagerResendInterval", alertmanagerResendInterval).
+		Dur("alertmanagerAlertTTL", alertmanagerAlertTTL).
+		Str("emailSMTPHost", emailSMTPHost).
+		Int("emailSMTPPort", emailSMTPPort).
+		Bool("emailStartTLS", emailStartTLS).
+		Str("emailFrom", emailFrom).
+		Str("emailTo", strings.Join(emailTo, ",")).
+		Dur("emailDigestInterval", emailDigestInterval).
+		Str("kafkaBrokers", strings.Join(kafkaBrokers, ",")).
+		Str("kafkaTopic", kafkaTopic).
+		Bool("kafkaTLS", kafkaTLS).
+		Str("kafkaSASLMechanism", kafkaSASLMechanism).
+		Int("kafkaBatchSize", kafkaBatchSize).
+		Dur("kafkaBatchTimeout", kafkaBatchTimeout).
+		Str("messageTemplatesDir", messageTemplatesDir).
+		Int("messageTemplates", len(messageTemplates)).
+		Str("watchers", strings.Join(watchers, ",")).
+		Str("includeNamespaces", strings.Join(includeNamespaces, ",")).
+		Str("excludeNamespaces", strings.Join(excludeNamespaces, ",")).
+		Int("routes", len(routes)).
+		Int("slackChannelRoutes", len(slackChannelRoutes)).
+		Str("receiverInstances", strings.Join(receiverInstanceNames(receiverInstances), ",")).
+		Str("eventTypes", strings.Join(eventTypes, ",")).
+		Str("eventReasons", strings.Join(eventReasons, ",")).
+		Dur("eventRepeatInterval", eventRepeatInterval).
+		Dur("configReloadInterval", configReloadInterval).
+		Bool("leaderElection", leaderElectionEnabled).
+		Dur("aggregationWindow", aggregationWindow).
+		Str("aggregationGroupBy", strings.Join(aggregationGroupBy, ",")).
+		Str("leaderElectionLease", leaderElectionNamespace+"/"+leaderElectionLeaseName).
 		Msg("kubeobserver configurations")
 }
@@ -71,3 +71,25 @@ func TestSlackToken(t *testing.T) {
 		t.Errorf("Can't get slack token")
 	}
 }
+
+func TestParseKeyValueList(t *testing.T) {
+	result := parseKeyValueList("Authorization=Bearer token==, X-Team = sre,invalid,=empty")
+
+	if len(result) != 2 || result["Authorization"] != "Bearer token==" || result["X-Team"] != "sre" {
+		t.Errorf("Can't parse key value list: %v", result)
+	}
+}
+
+func TestWatcherEnabled(t *testing.T) {
+	defer func(configured []string) { watchers = configured }(watchers)
+
+	watchers = nil
+	if !WatcherEnabled("pod") || WatcherEnabled("event") {
+		t.Error("All the watchers except the event watcher should run when no watchers were configured")
+	}
+
+	watchers = []string{"event"}
+	if WatcherEnabled("pod") || !WatcherEnabled("event") {
+		t.Error("Only the configured watchers should run")
+	}
+}
@@ -0,0 +1,384 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const configFileEnvironmentVariable = "KUBEOBSERVER_CONFIG"
+const configFileFlagName = "config"
+
+var validSeverities = []string{"info", "warning", "critical"}
+var validAggregationGroupBy = []string{"owner", "namespace", "reason", "kind"}
+
+// the flag is registered so it shows up in the usage and is accepted by flag.Parse.
+// the value itself is looked up directly in os.Args, since the configuration
+// is loaded in init() before main has a chance to parse the flags
+var _ = flag.String(configFileFlagName, "", fmt.Sprintf("path to kubeobserver YAML configuration file (can also be set with %s)", configFileEnvironmentVariable))
+
+// fileConfig is the structure of the kubeobserver YAML configuration file.
+// every value in the file can be overridden by its environment variable
+type fileConfig struct {
+	ClusterName            string            `yaml:"clusterName"`
+	Port                   int               `yaml:"port"`
+	LogLevel               string            `yaml:"logLevel"`
+	ReloadInterval         string            `yaml:"reloadInterval"`
+	KubeConfigFilePath     string            `yaml:"kubeConfigFilePath"`
+	DefaultReceiver        string            `yaml:"defaultReceiver"`
+	WatcherThreads         int               `yaml:"watcherThreads"`
+	ExcludePodNamePatterns []string          `yaml:"excludePodNamePatterns"`
+	Watchers               []string          `yaml:"watchers"`
+	Namespaces             namespaceFilter   `yaml:"namespaces"`
+	Receivers              receiversConfig   `yaml:"receivers"`
+	Routes                 []Route           `yaml:"routes"`
+	LeaderElection         leaderElection    `yaml:"leaderElection"`
+	Aggregation            aggregation       `yaml:"aggregation"`
+	Events                 eventsFilter      `yaml:"events"`
+	Templates              map[string]string `yaml:"templates"`
+	TemplatesDir           string            `yaml:"templatesDir"`
+}
+
+type eventsFilter struct {
+	Types          []string `yaml:"types"`
+	Reasons        []string `yaml:"reasons"`
+	RepeatInterval string   `yaml:"repeatInterval"`
+}
+
+type aggregation struct {
+	Window       string   `yaml:"window"`
+	GroupBy      []string `yaml:"groupBy"`
+	MaxBatchSize int      `yaml:"maxBatchSize"`
+}
+
+type leaderElection struct {
+	Enabled       bool   `yaml:"enabled"`
+	Namespace     string `yaml:"namespace"`
+	LeaseName     string `yaml:"leaseName"`
+	LeaseDuration string `yaml:"leaseDuration"`
+	RenewDeadline string `yaml:"renewDeadline"`
+	RetryPeriod   string `yaml:"retryPeriod"`
+}
+
+type namespaceFilter struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+type receiversConfig struct {
+	Slack        slackConfig        `yaml:"slack"`
+	Webhook      webhookConfig      `yaml:"webhook"`
+	PagerDuty    pagerDutyConfig    `yaml:"pagerduty"`
+	Alertmanager alertmanagerConfig `yaml:"alertmanager"`
+	Teams        teamsConfig        `yaml:"teams"`
+	Email        emailConfig        `yaml:"email"`
+	Kafka        kafkaConfig        `yaml:"kafka"`
+
+	// Instances are named receivers, for example a second slack workspace or webhook endpoint
+	Instances []receiverInstanceConfig `yaml:"instances"`
+}
+
+type slackConfig struct {
+	Token            string              `yaml:"token"`
+	Channels         []string            `yaml:"channels"`
+	Mentions         []string            `yaml:"mentions"`
+	Threads          slackThreadsConfig  `yaml:"threads"`
+	SigningSecret    string              `yaml:"signingSecret"`
+	RestartAllowlist []string            `yaml:"restartAllowlist"`
+	Routes           []SlackChannelRoute `yaml:"routes"`
+}
+
+type slackThreadsConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	TTL          string `yaml:"ttl"`
+	MaxSize      int    `yaml:"maxSize"`
+	UpdateParent *bool  `yaml:"updateParent"`
+}
+
+type webhookConfig struct {
+	URLs    []string          `yaml:"urls"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout string            `yaml:"timeout"`
+	Retries *int              `yaml:"retries"`
+	Secret  string            `yaml:"secret"`
+}
+
+type pagerDutyConfig struct {
+	URL                  string            `yaml:"url"`
+	RoutingKey           string            `yaml:"routingKey"`
+	NamespaceRoutingKeys map[string]string `yaml:"namespaceRoutingKeys"`
+}
+
+type alertmanagerConfig struct {
+	URLs           []string `yaml:"urls"`
+	ResendInterval string   `yaml:"resendInterval"`
+	AlertTTL       string   `yaml:"alertTTL"`
+}
+
+type teamsConfig struct {
+	WebhookURLs []string `yaml:"webhookURLs"`
+}
+
+type emailConfig struct {
+	Host           string   `yaml:"host"`
+	Port           int      `yaml:"port"`
+	Username       string   `yaml:"username"`
+	Password       string   `yaml:"password"`
+	StartTLS       *bool    `yaml:"startTLS"`
+	From           string   `yaml:"from"`
+	To             []string `yaml:"to"`
+	DigestInterval string   `yaml:"digestInterval"`
+}
+
+type kafkaConfig struct {
+	Brokers      []string        `yaml:"brokers"`
+	Topic        string          `yaml:"topic"`
+	TLS          kafkaTLSConfig  `yaml:"tls"`
+	SASL         kafkaSASLConfig `yaml:"sasl"`
+	BatchSize    int             `yaml:"batchSize"`
+	BatchTimeout string          `yaml:"batchTimeout"`
+}
+
+type kafkaTLSConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	CAFile  string `yaml:"caFile"`
+}
+
+type kafkaSASLConfig struct {
+	Mechanism string `yaml:"mechanism"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+// Route is a routing rule that sends every event that matches all of its
+// conditions to the route receivers. an empty condition matches any value
+type Route struct {
+	Namespaces []string `yaml:"namespaces"`
+	Kinds      []string `yaml:"kinds"`
+	Severities []string `yaml:"severities"`
+	Reasons    []string `yaml:"reasons"`
+	Receivers  []string `yaml:"receivers"`
+}
+
+// Matches returns true when the event properties satisfy all of the route conditions
+func (r Route) Matches(namespace string, kind string, severity string, reason string) bool {
+	return matchesAny(r.Namespaces, namespace) &&
+		matchesAny(r.Kinds, kind) &&
+		matchesAny(r.Severities, severity) &&
+		matchesAny(r.Reasons, reason)
+}
+
+func (r Route) validate() error {
+	if len(r.Receivers) == 0 {
+		return fmt.Errorf("route %+v has no receivers", r)
+	}
+
+	for _, severity := range r.Severities {
+		if !contains(validSeverities, severity) {
+			return fmt.Errorf("route %+v has unknown severity '%s'. valid values are %s", r, severity, strings.Join(validSeverities, ","))
+		}
+	}
+
+	return nil
+}
+
+// SlackChannelRoute is a routing rule that posts the slack messages of every event that matches
+// all of its conditions to the route channels, instead of the default slack channels
+type SlackChannelRoute struct {
+	Namespaces []string `yaml:"namespaces"`
+	Kinds      []string `yaml:"kinds"`
+	Severities []string `yaml:"severities"`
+	Reasons    []string `yaml:"reasons"`
+	Channels   []string `yaml:"channels"`
+}
+
+// Matches returns true when the event properties satisfy all of the route conditions
+func (r SlackChannelRoute) Matches(namespace string, kind string, severity string, reason string) bool {
+	return matchesAny(r.Namespaces, namespace) &&
+		matchesAny(r.Kinds, kind) &&
+		matchesAny(r.Severities, severity) &&
+		matchesAny(r.Reasons, reason)
+}
+
+func (r SlackChannelRoute) validate() error {
+	if len(r.Channels) == 0 {
+		return fmt.Errorf("slack route %+v has no channels", r)
+	}
+
+	for _, severity := range r.Severities {
+		if !contains(validSeverities, severity) {
+			return fmt.Errorf("slack route %+v has unknown severity '%s'. valid values are %s", r, severity, strings.Join(validSeverities, ","))
+		}
+	}
+
+	return nil
+}
+
+func matchesAny(values []string, value string) bool {
+	return len(values) == 0 || contains(values, value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readConfigFile reads and validates the YAML configuration file in the given path.
+// unknown fields are treated as errors so typos won't be silently ignored
+func readConfigFile(path string) (*fileConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &fileConfig{}
+	if err := yaml.UnmarshalStrict(content, file); err != nil {
+		return nil, err
+	}
+
+	for _, route := range file.Routes {
+		if err := route.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, route := range file.Receivers.Slack.Routes {
+		if err := route.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateReceiverInstances(file.Receivers.Instances); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// lookupConfigFilePath returns the configuration file path from the command line
+// arguments (-config / --config) or from the KUBEOBSERVER_CONFIG environment variable
+func lookupConfigFilePath(args []string) string {
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+
+		if name == configFileFlagName && i+1 < len(args) {
+			return args[i+1]
+		}
+
+		if strings.HasPrefix(name, configFileFlagName+"=") {
+			return strings.TrimPrefix(name, configFileFlagName+"=")
+		}
+	}
+
+	return os.Getenv(configFileEnvironmentVariable)
+}
+
+// getEnvOrFile returns the value of the environment variable when it is set,
+// otherwise the value from the configuration file
+func getEnvOrFile(envName string, fileValue string) string {
+	if value := os.Getenv(envName); value != "" {
+		return value
+	}
+
+	return fileValue
+}
+
+// getListEnvOrFile returns the comma separated environment variable as a slice when it is set,
+// otherwise the slice from the configuration file
+func getListEnvOrFile(envName string, fileValue []string) []string {
+	if value := os.Getenv(envName); value != "" {
+		return strings.Split(value, ",")
+	}
+
+	if fileValue == nil {
+		return make([]string, 0)
+	}
+
+	return fileValue
+}
+
+// getBoolEnvOrFile parses the boolean environment variable when it is set,
+// otherwise the value from the configuration file is returned
+func getBoolEnvOrFile(envName string, fileValue bool) (bool, error) {
+	value := os.Getenv(envName)
+	if value == "" {
+		return fileValue, nil
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("error on parsing %s:[%v]", envName, err)
+	}
+
+	return result, nil
+}
+
+// getDurationEnvOrFile parses the duration from the environment variable or the configuration file.
+// the default value is returned when both are empty
+func getDurationEnvOrFile(envName string, fileValue string, defaultValue time.Duration) (time.Duration, error) {
+	value := getEnvOrFile(envName, fileValue)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("error on parsing %s:[%v]", envName, err)
+	}
+
+	return duration, nil
+}
+
+// loadMessageTemplates merges the templates of the configuration file with the templates directory,
+// which is usually a config map mounted as a volume. every file in the directory is a template named
+// after the file, without the optional .tmpl extension. hidden files, like the ..data link kubelet
+// creates in config map volumes, are skipped
+func loadMessageTemplates(fileTemplates map[string]string, dir string) (map[string]string, error) {
+	templates := make(map[string]string)
+
+	for name, text := range fileTemplates {
+		templates[name] = text
+	}
+
+	if dir == "" {
+		return templates, nil
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error on reading message templates directory %s:[%v]", dir, err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		// config map keys are symbolic links, so the target is checked instead of the entry
+		if info, err := os.Stat(path); err != nil || info.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error on reading message template %s:[%v]", path, err)
+		}
+
+		templates[strings.TrimSuffix(entry.Name(), ".tmpl")] = string(content)
+	}
+
+	return templates, nil
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var mockConfigFileContent = `
+clusterName: mock-cluster
+port: 9090
+watchers: ["pod", "deployment"]
+namespaces:
+  exclude: ["kube-system"]
+receivers:
+  slack:
+    token: mock-token
+    channels: ["#general"]
+    routes:
+      - namespaces: ["payments"]
+        channels: ["#payments-alerts"]
+  webhook:
+    urls: ["http://localhost:8080/events"]
+    timeout: 2s
+    retries: 1
+routes:
+  - namespaces: ["payments"]
+    severities: ["critical"]
+    receivers: ["pagerduty", "slack"]
+`
+
+func writeMockConfigFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "kubeobserver-config-*.yaml")
+	if err != nil {
+		t.Fatalf("couldn't create a temporary config file: %v", err)
+	}
+	defer file.Close()
+
+	file.WriteString(content)
+
+	return file.Name()
+}
+
+func TestReadConfigFile(t *testing.T) {
+	path := writeMockConfigFile(t, mockConfigFileContent)
+	defer os.Remove(path)
+
+	file, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("Can't read config file: %v", err)
+	}
+
+	if file.ClusterName != "mock-cluster" || file.Port != 9090 || len(file.Watchers) != 2 {
+		t.Errorf("Config file general values weren't parsed properly: %+v", file)
+	}
+
+	if file.Receivers.Slack.Token != "mock-token" || *file.Receivers.Webhook.Retries != 1 {
+		t.Errorf("Config file receivers weren't parsed properly: %+v", file.Receivers)
+	}
+
+	if len(file.Routes) != 1 || len(file.Routes[0].Receivers) != 2 {
+		t.Errorf("Config file routes weren't parsed properly: %+v", file.Routes)
+	}
+
+	if len(file.Receivers.Slack.Routes) != 1 || file.Receivers.Slack.Routes[0].Channels[0] != "#payments-alerts" {
+		t.Errorf("Config file slack routes weren't parsed properly: %+v", file.Receivers.Slack.Routes)
+	}
+}
+
+func TestReadInvalidConfigFile(t *testing.T) {
+	invalidContents := []string{
+		"clusterName: [",
+		"unknownField: true",
+		"routes:\n  - namespaces: [\"payments\"]\n",
+		"routes:\n  - severities: [\"urgent\"]\n    receivers: [\"slack\"]\n",
+		"receivers:\n  slack:\n    routes:\n      - kinds: [\"HorizontalPodAutoscaler\"]\n",
+		"receivers:\n  slack:\n    routes:\n      - severities: [\"urgent\"]\n        channels: [\"#capacity\"]\n",
+	}
+
+	for _, content := range invalidContents {
+		path := writeMockConfigFile(t, content)
+
+		if _, err := readConfigFile(path); err == nil {
+			t.Errorf("Invalid config file should fail: %s", content)
+		}
+
+		os.Remove(path)
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	route := Route{Namespaces: []string{"payments"}, Severities: []string{"critical"}, Receivers: []string{"slack"}}
+
+	if !route.Matches("payments", "Pod", "critical", "CrashLoopBackOff") {
+		t.Error("Route should match critical events from payments namespace")
+	}
+
+	if route.Matches("payments", "Pod", "info", "Created") || route.Matches("checkout", "Pod", "critical", "CrashLoopBackOff") {
+		t.Error("Route shouldn't match events with other severity or namespace")
+	}
+}
+
+func TestLookupConfigFilePath(t *testing.T) {
+	if path := lookupConfigFilePath([]string{"-config", "/etc/kubeobserver.yaml"}); path != "/etc/kubeobserver.yaml" {
+		t.Errorf("Can't get config file path from flag, got '%s'", path)
+	}
+
+	if path := lookupConfigFilePath([]string{"--config=/etc/kubeobserver.yaml"}); path != "/etc/kubeobserver.yaml" {
+		t.Errorf("Can't get config file path from flag with value, got '%s'", path)
+	}
+
+	os.Setenv(configFileEnvironmentVariable, "/tmp/kubeobserver.yaml")
+	defer os.Unsetenv(configFileEnvironmentVariable)
+
+	if path := lookupConfigFilePath([]string{"-test.v"}); path != "/tmp/kubeobserver.yaml" {
+		t.Errorf("Can't get config file path from environment, got '%s'", path)
+	}
+}
+
+func TestGetEnvOrFile(t *testing.T) {
+	os.Setenv("KUBEOBSERVER_MOCK_VALUE", "fromEnv")
+	defer os.Unsetenv("KUBEOBSERVER_MOCK_VALUE")
+
+	if value := getEnvOrFile("KUBEOBSERVER_MOCK_VALUE", "fromFile"); value != "fromEnv" {
+		t.Errorf("Environment variable should override file value, got '%s'", value)
+	}
+
+	if value := getEnvOrFile("KUBEOBSERVER_MISSING_VALUE", "fromFile"); value != "fromFile" {
+		t.Errorf("File value should be used when environment variable is missing, got '%s'", value)
+	}
+}
+
+func TestLoadMessageTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeobserver-templates")
+	if err != nil {
+		t.Fatalf("couldn't create a temporary templates directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(dir+"/pod.tmpl", []byte("pod from dir"), 0644)
+	ioutil.WriteFile(dir+"/slack.hpa", []byte("hpa for slack"), 0644)
+	ioutil.WriteFile(dir+"/.hidden", []byte("hidden"), 0644)
+	os.Mkdir(dir+"/..data", 0755)
+
+	templates, err := loadMessageTemplates(map[string]string{"pod": "pod from file", "hpa": "hpa from file"}, dir)
+	if err != nil {
+		t.Fatalf("Can't load message templates: %v", err)
+	}
+
+	if len(templates) != 3 || templates["pod"] != "pod from dir" || templates["hpa"] != "hpa from file" || templates["slack.hpa"] != "hpa for slack" {
+		t.Errorf("Message templates weren't loaded properly: %v", templates)
+	}
+
+	if _, err := loadMessageTemplates(nil, dir+"/missing"); err == nil {
+		t.Error("should receive an error for a missing templates directory")
+	}
+}
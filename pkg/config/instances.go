@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// validReceiverInstanceTypes are the receiver types that can have named instances
+var validReceiverInstanceTypes = []string{"slack", "webhook", "teams"}
+
+// reservedReceiverNames are the names of the default receivers, which the receiver instances can't use
+var reservedReceiverNames = []string{"slack", "log", "webhook", "teams", "pagerduty", "alertmanager", "alert-manager", "email", "kafka"}
+
+// receiverInstanceConfig is a named receiver of the configuration file, with the settings of its type
+type receiverInstanceConfig struct {
+	Name    string              `yaml:"name"`
+	Type    string              `yaml:"type"`
+	Slack   slackInstanceConfig `yaml:"slack"`
+	Webhook webhookConfig       `yaml:"webhook"`
+	Teams   teamsInstanceConfig `yaml:"teams"`
+}
+
+type slackInstanceConfig struct {
+	Token    string              `yaml:"token"`
+	Channels []string            `yaml:"channels"`
+	Mentions []string            `yaml:"mentions"`
+	Routes   []SlackChannelRoute `yaml:"routes"`
+}
+
+type teamsInstanceConfig struct {
+	WebhookURLs []string `yaml:"webhookURLs"`
+	Timeout     string   `yaml:"timeout"`
+	Retries     *int     `yaml:"retries"`
+}
+
+// ReceiverInstance is a named receiver built from the configuration file, next to the default receiver of each type.
+// it is addressed by its name in the kubeobserver.io/receivers annotation and in the routes
+type ReceiverInstance struct {
+	Name    string
+	Type    string
+	Slack   SlackSettings
+	Webhook WebhookSettings
+	Teams   TeamsSettings
+}
+
+// SlackSettings are the settings of a slack receiver instance
+type SlackSettings struct {
+	Token    string
+	Channels []string
+	Mentions []string
+	Routes   []SlackChannelRoute
+}
+
+// WebhookSettings are the settings of a webhook receiver instance
+type WebhookSettings struct {
+	URLs    []string
+	Headers map[string]string
+	Secret  string
+	Timeout time.Duration
+	Retries int
+}
+
+// TeamsSettings are the settings of a teams receiver instance
+type TeamsSettings struct {
+	WebhookURLs []string
+	Timeout     time.Duration
+	Retries     int
+}
+
+// validateReceiverInstances checks the names and types of the receiver instances of the configuration file
+func validateReceiverInstances(instances []receiverInstanceConfig) error {
+	names := make(map[string]bool)
+
+	for _, instance := range instances {
+		if instance.Name == "" {
+			return fmt.Errorf("receiver instance of type '%s' has no name", instance.Type)
+		}
+
+		if names[instance.Name] {
+			return fmt.Errorf("receiver instance name '%s' is used more than once", instance.Name)
+		}
+
+		names[instance.Name] = true
+
+		if contains(reservedReceiverNames, instance.Name) {
+			return fmt.Errorf("receiver instance name '%s' is used by a default receiver", instance.Name)
+		}
+
+		if !contains(validReceiverInstanceTypes, strings.ToLower(instance.Type)) {
+			return fmt.Errorf("receiver instance '%s' has unknown type '%s'. valid values are %s", instance.Name, instance.Type, strings.Join(validReceiverInstanceTypes, ","))
+		}
+
+		for _, route := range instance.Slack.Routes {
+			if err := route.validate(); err != nil {
+				return fmt.Errorf("receiver instance '%s': %s", instance.Name, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildReceiverInstances parses the settings of the receiver instances of the configuration file.
+// webhook and teams instances without a timeout or retries use the ones of the default webhook receiver
+func buildReceiverInstances(instances []receiverInstanceConfig, defaultTimeout time.Duration, defaultRetries int) ([]ReceiverInstance, error) {
+	receiverInstances := make([]ReceiverInstance, 0, len(instances))
+
+	for _, instance := range instances {
+		receiverInstance := ReceiverInstance{
+			Name: instance.Name,
+			Type: strings.ToLower(instance.Type),
+			Slack: SlackSettings{
+				Token:    instance.Slack.Token,
+				Channels: instance.Slack.Channels,
+				Mentions: instance.Slack.Mentions,
+				Routes:   instance.Slack.Routes,
+			},
+			Webhook: WebhookSettings{
+				URLs:    instance.Webhook.URLs,
+				Headers: instance.Webhook.Headers,
+				Secret:  instance.Webhook.Secret,
+				Retries: defaultRetries,
+			},
+			Teams: TeamsSettings{
+				WebhookURLs: instance.Teams.WebhookURLs,
+				Retries:     defaultRetries,
+			},
+		}
+
+		var err error
+		if receiverInstance.Webhook.Timeout, err = parseInstanceDuration(instance.Name, instance.Webhook.Timeout, defaultTimeout); err != nil {
+			return nil, err
+		}
+
+		if receiverInstance.Teams.Timeout, err = parseInstanceDuration(instance.Name, instance.Teams.Timeout, defaultTimeout); err != nil {
+			return nil, err
+		}
+
+		if instance.Webhook.Retries != nil {
+			receiverInstance.Webhook.Retries = *instance.Webhook.Retries
+		}
+
+		if instance.Teams.Retries != nil {
+			receiverInstance.Teams.Retries = *instance.Teams.Retries
+		}
+
+		receiverInstances = append(receiverInstances, receiverInstance)
+	}
+
+	return receiverInstances, nil
+}
+
+func parseInstanceDuration(name string, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("error on parsing the timeout of receiver instance '%s':[%v]", name, err)
+	}
+
+	return duration, nil
+}
+
+func receiverInstanceNames(instances []ReceiverInstance) []string {
+	names := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		names = append(names, instance.Type+":"+instance.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+var mockInstancesConfigFileContent = `
+receivers:
+  instances:
+    - name: slack-payments
+      type: slack
+      slack:
+        token: payments-token
+        channels: ["C0PAYMENTS"]
+        routes:
+          - severities: ["critical"]
+            channels: ["C0ONCALL"]
+    - name: webhook-audit
+      type: webhook
+      webhook:
+        urls: ["https://audit.internal/events"]
+        timeout: 1s
+    - name: teams-platform
+      type: Teams
+      teams:
+        webhookURLs: ["https://teams.internal/hook"]
+        retries: 0
+`
+
+func TestReceiverInstances(t *testing.T) {
+	path := writeMockConfigFile(t, mockInstancesConfigFileContent)
+	defer os.Remove(path)
+
+	file, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("Can't read config file: %v", err)
+	}
+
+	instances, err := buildReceiverInstances(file.Receivers.Instances, 5*time.Second, 3)
+	if err != nil {
+		t.Fatalf("Can't build receiver instances: %v", err)
+	}
+
+	if len(instances) != 3 {
+		t.Fatalf("Expected 3 receiver instances, got %+v", instances)
+	}
+
+	slack := instances[0]
+	if slack.Name != "slack-payments" || slack.Type != "slack" || slack.Slack.Token != "payments-token" || len(slack.Slack.Routes) != 1 {
+		t.Errorf("Slack instance wasn't parsed properly: %+v", slack)
+	}
+
+	webhook := instances[1].Webhook
+	if webhook.Timeout != time.Second || webhook.Retries != 3 || webhook.URLs[0] != "https://audit.internal/events" {
+		t.Errorf("Webhook instance should have its own timeout and the default retries: %+v", webhook)
+	}
+
+	teams := instances[2]
+	if teams.Type != "teams" || teams.Teams.Timeout != 5*time.Second || teams.Teams.Retries != 0 {
+		t.Errorf("Teams instance should have the default timeout and its own retries: %+v", teams)
+	}
+}
+
+func TestReadInvalidReceiverInstances(t *testing.T) {
+	invalidContents := []string{
+		"receivers:\n  instances:\n    - type: slack\n",
+		"receivers:\n  instances:\n    - name: kafka-audit\n      type: kafka\n",
+		"receivers:\n  instances:\n    - name: slack-a\n      type: slack\n    - name: slack-a\n      type: slack\n",
+		"receivers:\n  instances:\n    - name: webhook\n      type: webhook\n",
+		"receivers:\n  instances:\n    - name: alert-manager\n      type: Webhook\n",
+		"receivers:\n  instances:\n    - name: slack-a\n      type: slack\n      slack:\n        threads:\n          enabled: true\n",
+		"receivers:\n  instances:\n    - name: slack-a\n      type: slack\n      slack:\n        routes:\n          - namespaces: [\"payments\"]\n",
+	}
+
+	for _, content := range invalidContents {
+		path := writeMockConfigFile(t, content)
+
+		if _, err := readConfigFile(path); err == nil {
+			t.Errorf("Invalid receiver instances should fail: %s", content)
+		}
+
+		os.Remove(path)
+	}
+
+	instances := []receiverInstanceConfig{{Name: "webhook-a", Type: "webhook", Webhook: webhookConfig{Timeout: "soon"}}}
+	if _, err := buildReceiverInstances(instances, time.Second, 3); err == nil {
+		t.Error("Receiver instance with an invalid timeout should fail")
+	}
+}
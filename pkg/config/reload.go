@@ -0,0 +1,129 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// ConfigFilePath is a getter function for the path of the YAML configuration file.
+// an empty string is returned when kubeobserver runs with environment variables only
+func ConfigFilePath() string {
+	return configFilePath
+}
+
+// Reload reads the configuration file again and applies the values that can change at runtime:
+// log level, receivers configuration, routing rules, namespace filters, exclude patterns and mentions.
+// an invalid file is rejected as a whole and the previous configuration stays active
+func Reload() error {
+	if configFilePath == "" {
+		return fmt.Errorf("no configuration file to reload")
+	}
+
+	file, err := readConfigFile(configFilePath)
+	if err == nil {
+		var rc *reloadableConfig
+		if rc, err = loadReloadableConfig(file); err == nil {
+			applyReloadableConfig(rc)
+		}
+	}
+
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues("failure").Inc()
+		metrics.ConfigLastReloadSuccessful.Set(0)
+		return err
+	}
+
+	metrics.ConfigReloads.WithLabelValues("success").Inc()
+	metrics.ConfigLastReloadSuccessful.Set(1)
+	outputConfig()
+
+	return nil
+}
+
+// WatchConfigFile polls the configuration file and the message templates directory every interval
+// and reloads the configuration whenever their content changes. polling (instead of file system notifications) also catches
+// config maps mounted as a volume, which kubelet updates by swapping a symlink.
+// onReload is called after every successful reload so other packages can rebuild their state
+func WatchConfigFile(stopCh <-chan struct{}, interval time.Duration, onReload func()) {
+	if configFilePath == "" {
+		return
+	}
+
+	lastContent, _ := watchedContent()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Info().Msg(fmt.Sprintf("watching configuration file %s for changes every %v", configFilePath, interval))
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			content, err := watchedContent()
+			if err != nil {
+				log.Error().Msg(fmt.Sprintf("couldn't read configuration file %s: %s", configFilePath, err))
+				continue
+			}
+
+			if bytes.Equal(content, lastContent) {
+				continue
+			}
+
+			lastContent = content
+			log.Info().Msg(fmt.Sprintf("configuration file %s has changed. reloading configuration", configFilePath))
+
+			if err := Reload(); err != nil {
+				log.Error().Msg(fmt.Sprintf("configuration reload was rejected, keeping the previous configuration: %s", err))
+				continue
+			}
+
+			if onReload != nil {
+				onReload()
+			}
+
+			log.Info().Msg("configuration reloaded successfully")
+		}
+	}
+}
+
+// watchedContent returns the content of the configuration file followed by the content
+// of the message templates directory, so a change in any of them is detected
+func watchedContent() ([]byte, error) {
+	content, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	configLock.RLock()
+	dir := messageTemplatesDir
+	configLock.RUnlock()
+
+	if dir == "" {
+		return content, nil
+	}
+
+	templates, err := loadMessageTemplates(nil, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		content = append(content, fmt.Sprintf("\n%s:%s", name, templates[name])...)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func resetReloadableConfig(t *testing.T) {
+	configFilePath = ""
+	rc, err := loadReloadableConfig(&fileConfig{})
+	if err != nil {
+		t.Fatalf("Can't reset reloadable configuration: %v", err)
+	}
+
+	applyReloadableConfig(rc)
+}
+
+func TestReload(t *testing.T) {
+	configFilePath = writeMockConfigFile(t, "excludePodNamePatterns: [\"runner\"]\nnamespaces:\n  exclude: [\"kube-system\"]\n")
+	defer os.Remove(configFilePath)
+	defer resetReloadableConfig(t)
+
+	if err := Reload(); err != nil {
+		t.Fatalf("Valid configuration reload failed: %v", err)
+	}
+
+	if patterns := ExcludePodNamePatterns(); len(patterns) != 1 || patterns[0] != "runner" {
+		t.Errorf("Exclude pod name patterns weren't reloaded: %v", patterns)
+	}
+
+	if ShouldWatchNamespace("kube-system") {
+		t.Error("Excluded namespaces weren't reloaded")
+	}
+
+	ioutil.WriteFile(configFilePath, []byte("excludePodNamePatterns: [\"job\"]\nwebhook: {}\n"), 0644)
+
+	if err := Reload(); err == nil {
+		t.Error("Invalid configuration reload should be rejected")
+	}
+
+	if patterns := ExcludePodNamePatterns(); len(patterns) != 1 || patterns[0] != "runner" {
+		t.Errorf("Previous configuration should stay active after a rejected reload: %v", patterns)
+	}
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	configFilePath = writeMockConfigFile(t, "defaultReceiver: slack\n")
+	defer os.Remove(configFilePath)
+	defer resetReloadableConfig(t)
+
+	reloaded := make(chan bool, 1)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	go WatchConfigFile(stopCh, 10*time.Millisecond, func() {
+		reloaded <- true
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	ioutil.WriteFile(configFilePath, []byte("defaultReceiver: log\n"), 0644)
+
+	select {
+	case <-reloaded:
+		if DefaultReceiver() != "log" {
+			t.Errorf("Default receiver wasn't reloaded, got '%s'", DefaultReceiver())
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Configuration file change wasn't detected")
+	}
+}
@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+)
+
+// maxSummaryResources is the number of resource names listed in an aggregated summary message
+const maxSummaryResources = 20
+
+// eventAggregator is the pipeline stage between the controllers and the receivers.
+// identical events of a resource are sent once per window, and bursts of similar events
+// (same owner, namespace and reason by default) are aggregated: the first event of a group
+// is sent right away and the events that follow it are sent as one summary when the window
+// ends or when the batch is full
+type eventAggregator struct {
+	sync.Mutex
+	window       time.Duration
+	groupBy      []string
+	maxBatchSize int
+	send         func(receivers.ReceiverEvent, []string)
+	sent         map[string]time.Time
+	groups       map[string]*eventGroup
+}
+
+// eventGroup holds the events of a single aggregation group
+type eventGroup struct {
+	start     time.Time
+	receivers []string
+	first     receivers.ReceiverEvent
+	events    []receivers.ReceiverEvent
+	timer     *time.Timer
+}
+
+var aggregator = newEventAggregator(config.AggregationWindow(), config.AggregationGroupBy(), config.AggregationMaxBatchSize(), sendEventToReceivers)
+
+func newEventAggregator(window time.Duration, groupBy []string, maxBatchSize int, send func(receivers.ReceiverEvent, []string)) *eventAggregator {
+	return &eventAggregator{
+		window:       window,
+		groupBy:      groupBy,
+		maxBatchSize: maxBatchSize,
+		send:         send,
+		sent:         make(map[string]time.Time),
+		groups:       make(map[string]*eventGroup),
+	}
+}
+
+// dispatchEvent passes the event of a controller onward to its receivers,
+// through the aggregator when an aggregation window is configured. events of silenced workloads are dropped
+func dispatchEvent(receiverEvent receivers.ReceiverEvent, eventReceivers []string) {
+	if isSilenced(receiverEvent) {
+		metrics.EventsSilenced.WithLabelValues(receiverEvent.Kind).Inc()
+		log.Debug().Msg(fmt.Sprintf("dropping %s event of silenced %s %s/%s", receiverEvent.EventName, receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name))
+		return
+	}
+
+	if aggregator.window <= 0 {
+		sendEventToReceivers(receiverEvent, eventReceivers)
+		return
+	}
+
+	aggregator.add(receiverEvent, eventReceivers)
+}
+
+func (a *eventAggregator) add(receiverEvent receivers.ReceiverEvent, eventReceivers []string) {
+	now := time.Now()
+	deduplicationKey := eventDeduplicationKey(receiverEvent)
+	groupKey := a.groupKey(receiverEvent, eventReceivers)
+
+	a.Lock()
+
+	if expiration, ok := a.sent[deduplicationKey]; ok && now.Before(expiration) {
+		a.Unlock()
+
+		metrics.EventsDeduplicated.WithLabelValues(receiverEvent.Kind).Inc()
+		log.Debug().Msg(fmt.Sprintf("dropping duplicate %s event of %s %s/%s", receiverEvent.EventName, receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name))
+		return
+	}
+
+	a.sent[deduplicationKey] = now.Add(a.window)
+	group, ok := a.groups[groupKey]
+
+	if !ok {
+		group = &eventGroup{start: now, receivers: eventReceivers, first: receiverEvent}
+		group.timer = time.AfterFunc(a.window, func() { a.flush(groupKey, group) })
+		a.groups[groupKey] = group
+		a.Unlock()
+
+		a.send(receiverEvent, eventReceivers)
+		return
+	}
+
+	group.events = append(group.events, receiverEvent)
+	full := len(group.events) >= a.maxBatchSize
+
+	if full {
+		group.timer.Stop()
+		delete(a.groups, groupKey)
+	}
+
+	a.Unlock()
+
+	if full {
+		a.sendSummary(group)
+	}
+}
+
+// flush sends the summary of the group when its window has ended
+func (a *eventAggregator) flush(groupKey string, group *eventGroup) {
+	a.Lock()
+
+	// the group was already sent since its batch was full
+	if a.groups[groupKey] != group {
+		a.Unlock()
+		return
+	}
+
+	delete(a.groups, groupKey)
+
+	now := time.Now()
+	for key, expiration := range a.sent {
+		if now.After(expiration) {
+			delete(a.sent, key)
+		}
+	}
+
+	a.Unlock()
+
+	if len(group.events) > 0 {
+		a.sendSummary(group)
+	}
+}
+
+func (a *eventAggregator) sendSummary(group *eventGroup) {
+	metrics.EventsAggregated.WithLabelValues(group.first.Kind).Add(float64(len(group.events)))
+	a.send(a.summaryEvent(group), group.receivers)
+}
+
+// summaryEvent builds a single event out of the aggregated events of the group
+func (a *eventAggregator) summaryEvent(group *eventGroup) receivers.ReceiverEvent {
+	summary := group.first
+	ownerKind, ownerName := eventOwner(group.first)
+	resources := make(map[string]int)
+
+	for _, receiverEvent := range group.events {
+		resources[receiverEvent.Name]++
+
+		if severityRank(receiverEvent.Severity) > severityRank(summary.Severity) {
+			summary.Severity = receiverEvent.Severity
+		}
+	}
+
+	// the summary describes a group of resources, not a single one
+	if ownerName != "" && contains(a.groupBy, "owner") {
+		summary.Name = ownerName
+	}
+
+	summary.LastTermination = nil
+	summary.ContainerLogs = ""
+	summary.Timestamp = time.Now()
+
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	// the names let the receivers that keep track of incidents resolve the ones of every resource
+	summary.AdditionalInfo = map[string]interface{}{
+		"aggregated_events":    len(group.events),
+		"aggregated_resources": len(resources),
+		"aggregated_names":     names,
+	}
+
+	var msgBuilder strings.Builder
+	msgBuilder.WriteString(fmt.Sprintf("`%d` more %s events were received in the last `%v` (the first one was sent separately)\n",
+		len(group.events), group.first.Kind, time.Since(group.start).Round(time.Second)))
+
+	if contains(a.groupBy, "reason") && group.first.Reason != "" {
+		msgBuilder.WriteString(fmt.Sprintf("Reason:`%s`. ", group.first.Reason))
+	}
+
+	if contains(a.groupBy, "namespace") && group.first.Namespace != "" {
+		msgBuilder.WriteString(fmt.Sprintf("Namespace:`%s`. ", group.first.Namespace))
+	}
+
+	if contains(a.groupBy, "owner") && ownerName != "" {
+		msgBuilder.WriteString(fmt.Sprintf("Controller kind:`%s`. Controller name:`%s`. ", ownerKind, ownerName))
+	}
+
+	msgBuilder.WriteString(fmt.Sprintf("Environment:`%s`\n", config.ClusterName()))
+
+	for i, name := range names {
+		if i == maxSummaryResources {
+			msgBuilder.WriteString(fmt.Sprintf("- and %d more\n", len(names)-maxSummaryResources))
+			break
+		}
+
+		msgBuilder.WriteString(fmt.Sprintf("- %s (%d)\n", name, resources[name]))
+	}
+
+	summary.Message = msgBuilder.String()
+
+	return summary
+}
+
+// groupKey returns the aggregation group of the event. events that go to
+// different receivers are never aggregated together
+func (a *eventAggregator) groupKey(receiverEvent receivers.ReceiverEvent, eventReceivers []string) string {
+	key := []string{string(receiverEvent.EventName), strings.Join(eventReceivers, ",")}
+
+	for _, field := range a.groupBy {
+		switch field {
+		case "owner":
+			ownerKind, ownerName := eventOwner(receiverEvent)
+			key = append(key, ownerKind, ownerName)
+		case "namespace":
+			key = append(key, receiverEvent.Namespace)
+		case "reason":
+			key = append(key, receiverEvent.Reason)
+		case "kind":
+			key = append(key, receiverEvent.Kind)
+		}
+	}
+
+	return strings.Join(key, "/")
+}
+
+// eventDeduplicationKey identifies repeating events of the same resource. the message is not part of the
+// key since it changes on every repeat, for example with the restart count or the time of a crash loop
+func eventDeduplicationKey(receiverEvent receivers.ReceiverEvent) string {
+	return strings.Join([]string{
+		receiverEvent.Kind,
+		receiverEvent.Namespace,
+		receiverEvent.Name,
+		string(receiverEvent.EventName),
+		receiverEvent.Reason,
+		receiverEvent.Container,
+	}, "/")
+}
+
+// eventOwner returns the kind and name of the controller of the event resource
+func eventOwner(receiverEvent receivers.ReceiverEvent) (string, string) {
+	workload := receiverEvent.Workload()
+
+	return workload.Kind, workload.Name
+}
+
+func severityRank(severity receivers.Severity) int {
+	switch severity {
+	case receivers.CriticalSeverity:
+		return 2
+	case receivers.WarningSeverity:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+)
+
+type sentEvents struct {
+	sync.Mutex
+	events []receivers.ReceiverEvent
+}
+
+func (s *sentEvents) send(receiverEvent receivers.ReceiverEvent, eventReceivers []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.events = append(s.events, receiverEvent)
+}
+
+func (s *sentEvents) get() []receivers.ReceiverEvent {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]receivers.ReceiverEvent{}, s.events...)
+}
+
+func mockCrashLoopEvent(podName string, severity receivers.Severity) receivers.ReceiverEvent {
+	return receivers.ReceiverEvent{
+		EventName: receivers.UpdateEvent,
+		Kind:      "Pod",
+		Namespace: "payments",
+		Name:      podName,
+		Reason:    "CrashLoopBackOff",
+		Severity:  severity,
+		Owner:     receivers.Owner{Kind: "ReplicaSet", Name: "checkout-5d8f7"},
+		Labels:    map[string]string{"pod-template-hash": "5d8f7"},
+		Message:   "crash loop of " + podName,
+	}
+}
+
+func TestAggregatorDeduplication(t *testing.T) {
+	sent := &sentEvents{}
+	a := newEventAggregator(time.Hour, []string{"owner", "namespace", "reason"}, 50, sent.send)
+
+	a.add(mockCrashLoopEvent("checkout-1", receivers.CriticalSeverity), []string{"slack"})
+
+	// a repeating crash loop has a new restart count in its message
+	repeat := mockCrashLoopEvent("checkout-1", receivers.CriticalSeverity)
+	repeat.Message += " (restart count 2)"
+	a.add(repeat, []string{"slack"})
+
+	if len(sent.get()) != 1 {
+		t.Errorf("Identical events should be sent once per window, got %d events", len(sent.get()))
+	}
+
+	if len(a.groups) != 1 || len(a.groups[a.groupKey(mockCrashLoopEvent("checkout-1", ""), []string{"slack"})].events) != 0 {
+		t.Error("Duplicate event shouldn't be aggregated")
+	}
+}
+
+func TestAggregatorSummary(t *testing.T) {
+	sent := &sentEvents{}
+	a := newEventAggregator(50*time.Millisecond, []string{"owner", "namespace", "reason"}, 50, sent.send)
+
+	a.add(mockCrashLoopEvent("checkout-1", receivers.WarningSeverity), []string{"slack"})
+	a.add(mockCrashLoopEvent("checkout-2", receivers.CriticalSeverity), []string{"slack"})
+	a.add(mockCrashLoopEvent("checkout-3", receivers.WarningSeverity), []string{"slack"})
+
+	// events to other receivers are not part of the group
+	a.add(mockCrashLoopEvent("checkout-4", receivers.WarningSeverity), []string{"webhook"})
+
+	if len(sent.get()) != 2 {
+		t.Fatalf("Only the first event of every group should be sent right away, got %d events", len(sent.get()))
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	events := sent.get()
+	if len(events) != 3 {
+		t.Fatalf("A single summary should be sent when the window ends, got %d events", len(events))
+	}
+
+	summary := events[2]
+	if summary.Name != "checkout" || summary.Severity != receivers.CriticalSeverity || summary.AdditionalInfo["aggregated_events"] != 2 {
+		t.Errorf("Summary event wasn't built properly: %+v", summary)
+	}
+
+	if !strings.Contains(summary.Message, "checkout-2 (1)") || !strings.Contains(summary.Message, "Controller kind:`Deployment`") {
+		t.Errorf("Summary message should list the aggregated resources and their deployment: %s", summary.Message)
+	}
+
+	if names := summary.AdditionalInfo["aggregated_names"]; !reflect.DeepEqual(names, []string{"checkout-2", "checkout-3"}) {
+		t.Errorf("Summary should list the names of the aggregated resources, got %v", names)
+	}
+}
+
+func TestAggregatorMaxBatchSize(t *testing.T) {
+	sent := &sentEvents{}
+	a := newEventAggregator(time.Hour, []string{"owner", "namespace", "reason"}, 2, sent.send)
+
+	for _, podName := range []string{"checkout-1", "checkout-2", "checkout-3"} {
+		a.add(mockCrashLoopEvent(podName, receivers.CriticalSeverity), []string{"slack"})
+	}
+
+	if events := sent.get(); len(events) != 2 || events[1].AdditionalInfo["aggregated_events"] != 2 {
+		t.Errorf("A full batch should be sent without waiting for the window to end, got %d events", len(events))
+	}
+
+	if len(a.groups) != 0 {
+		t.Error("A full batch should close its group")
+	}
+}
+
+func TestEventOwner(t *testing.T) {
+	if kind, name := eventOwner(mockCrashLoopEvent("checkout-1", "")); kind != "Deployment" || name != "checkout" {
+		t.Errorf("Pod of a deployment should be owned by the deployment, got %s %s", kind, name)
+	}
+
+	statefulSetPod := receivers.ReceiverEvent{Owner: receivers.Owner{Kind: "StatefulSet", Name: "db"}}
+	if kind, name := eventOwner(statefulSetPod); kind != "StatefulSet" || name != "db" {
+		t.Errorf("Pod owner should be returned as is, got %s %s", kind, name)
+	}
+}
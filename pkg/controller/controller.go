@@ -8,12 +8,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/PayU/kubeobserver/pkg/common"
 	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/metrics"
 	"github.com/PayU/kubeobserver/pkg/receivers"
 
 	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -22,7 +26,8 @@ import (
 )
 
 type k8sClientStruct struct {
-	Clientset kubernetes.Interface
+	Clientset     kubernetes.Interface
+	DynamicClient dynamic.Interface
 }
 
 var k8sClient k8sClientStruct
@@ -61,6 +66,11 @@ func initClientOutOfCluster() *kubernetes.Clientset {
 		panic(err.Error())
 	}
 
+	// the dynamic client is used for the kubeobserver custom resources
+	if k8sClient.DynamicClient, err = dynamic.NewForConfig(config); err != nil {
+		panic(err.Error())
+	}
+
 	return clientset
 }
 
@@ -100,6 +110,13 @@ func (c *controller) processNextItem() bool {
 
 	// Invoke the method containing the business logic
 	err := c.eventHandler(key.(string), c.indexer)
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	metrics.EventsProcessed.WithLabelValues(c.resourceType, queuedEventName(key.(string)), result).Inc()
 	// Handle the error if something went wrong during the execution of the business logic
 	c.handleErr(err, key)
 	return true
@@ -126,26 +143,50 @@ func (c *controller) handleErr(err error, key interface{}) {
 	}
 
 	c.queue.Forget(key)
+	metrics.EventsDropped.WithLabelValues(c.resourceType, queuedEventName(key.(string))).Inc()
+
 	// Report to an external entity that, even after several retries, we could not successfully process this key
 	runtime.HandleError(err)
 	log.Info().Msg(fmt.Sprintf("Dropping pod %q out of the queue: %v", key, err))
 }
 
+// enqueueEvent adds the marshaled event to the controller queue
+func enqueueEvent(queue workqueue.Interface, resourceType string, eventName receivers.EventName, key string) {
+	queue.Add(key)
+	metrics.EventsEnqueued.WithLabelValues(resourceType, string(eventName)).Inc()
+}
+
+// queuedEventName returns the event name of a queue key. all the controllers
+// queue a marshaled event with an EventName field
+func queuedEventName(key string) string {
+	event := struct {
+		EventName receivers.EventName
+	}{}
+
+	json.Unmarshal([]byte(key), &event)
+
+	return string(event.EventName)
+}
+
 func (c *controller) Run(threadiness int, stopCh chan struct{}) {
 	defer runtime.HandleCrash()
 
 	// Let the workers stop when we are done
 	defer c.queue.ShutDown()
 
-	go c.informer.Run(stopCh)
+	// controllers without an informer, like the delivery controller, get their items from other controllers
+	// or from a shared cache that was started before them
+	if c.informer != nil {
+		go c.informer.Run(stopCh)
 
-	log.Info().
-		Msg(fmt.Sprintf("waiting for %s controller cache to by sync", c.resourceType))
+		log.Info().
+			Msg(fmt.Sprintf("waiting for %s controller cache to by sync", c.resourceType))
 
-	// Wait for all involved caches to be synced, before processing items from the queue is started
-	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
-		runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
-		return
+		// Wait for all involved caches to be synced, before processing items from the queue is started
+		if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
+			runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
+			return
+		}
 	}
 
 	log.Info().
@@ -166,6 +207,49 @@ func (c *controller) runWorker() {
 	}
 }
 
+// newReceiverEvent builds a receiver event with the identity of the given k8s resource.
+// the caller is responsible for the event specific fields (message, severity, reason and so on..)
+func newReceiverEvent(eventName receivers.EventName, kind string, obj metav1.Object) receivers.ReceiverEvent {
+	receiverEvent := receivers.ReceiverEvent{
+		EventName:         eventName,
+		AdditionalInfo:    make(map[string]interface{}),
+		Cluster:           config.ClusterName(),
+		Kind:              kind,
+		Namespace:         obj.GetNamespace(),
+		Name:              obj.GetName(),
+		UID:               string(obj.GetUID()),
+		Severity:          receivers.InfoSeverity,
+		Labels:            obj.GetLabels(),
+		Annotations:       withNamespaceAnnotations(obj.GetNamespace(), obj.GetAnnotations()),
+		Mentions:          make([]string, 0),
+		CreationTimestamp: obj.GetCreationTimestamp().Time,
+		Timestamp:         time.Now(),
+	}
+
+	// this value can be any valid controller like StatefulSet, DaemonSet, ReplicaSet, Job and so on..
+	if ownerReferences := obj.GetOwnerReferences(); len(ownerReferences) > 0 {
+		receiverEvent.Owner = receivers.Owner{
+			Kind: ownerReferences[0].Kind,
+			Name: ownerReferences[0].Name,
+		}
+	}
+
+	return receiverEvent
+}
+
+// buildEventReceivers returns the receivers of an event based on the resource annotations, the KubeObserverRoute
+// resources, the configured routing rules and the default receiver. the matched KubeObserverRoute resources
+// may also change the mentions, severity and template of the event
+func buildEventReceivers(receiverEvent *receivers.ReceiverEvent) []string {
+	routeReceivers := applyResourceRoutes(receiverEvent)
+
+	if len(routeReceivers) > 0 && !common.HasReceiversAnnotation(receiverEvent.Annotations) {
+		return routeReceivers
+	}
+
+	return common.RouteEventReceivers(receiverEvent.Annotations, receiverEvent.Namespace, receiverEvent.Kind, string(receiverEvent.Severity), receiverEvent.Reason)
+}
+
 // this function should be used by all receivers in order to to send
 // the updated events in parallel
 //  * receiverEvent: is the new event we want to notify the receivers about
@@ -173,12 +257,18 @@ func (c *controller) runWorker() {
 func sendEventToReceivers(receiverEvent receivers.ReceiverEvent, receiversSlice []string) {
 	var channelList []chan error
 
+	// all replicas handle the events, only the leader notifies about them
+	if !IsLeader() {
+		log.Debug().Msg(fmt.Sprintf("not the leader, skipping %s event of %s %s/%s", receiverEvent.EventName, receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name))
+		return
+	}
+
 	for _, receiverName := range receiversSlice {
-		channel := make(chan error)
-		channelList = append(channelList, channel)
+		if receiver := receivers.GetReceiver(receiverName); receiver != nil {
+			channel := make(chan error)
+			channelList = append(channelList, channel)
 
-		if receivers.ReceiverMap[receiverName] != nil {
-			go receivers.ReceiverMap[receiverName].HandleEvent(receiverEvent, channel)
+			go deliverEvent(receiverName, receiver, receiverEvent, channel)
 		} else {
 			log.Warn().Msg(fmt.Sprintf("an event was requested to be send to unknown receiver: %s", receiverName))
 		}
@@ -192,6 +282,27 @@ func sendEventToReceivers(receiverEvent receivers.ReceiverEvent, receiversSlice
 	log.Debug().Msg(string(reStr))
 }
 
+// sendEventToReceiver passes the event to the receiver and reports the receiver result
+// and latency. the receiver result is passed onward to the given channel
+func sendEventToReceiver(receiverName string, receiver receivers.Receiver, receiverEvent receivers.ReceiverEvent, c chan error) {
+	start := time.Now()
+	receiverChannel := make(chan error)
+
+	go receiver.HandleEvent(receiverEvent, receiverChannel)
+
+	// receivers send a single error or close the channel on success
+	err := <-receiverChannel
+	metrics.ReceiverSendDuration.WithLabelValues(receiverName).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		metrics.ReceiverEvents.WithLabelValues(receiverName, "failure").Inc()
+	} else {
+		metrics.ReceiverEvents.WithLabelValues(receiverName, "success").Inc()
+	}
+
+	c <- err
+}
+
 func waitForChannelsToClose(chans ...chan error) {
 	t := time.Now()
 	for _, v := range chans {
@@ -223,6 +334,10 @@ func init() {
 			panic(err.Error())
 		}
 
+		if k8sClient.DynamicClient, err = dynamic.NewForConfig(config); err != nil {
+			panic(err.Error())
+		}
+
 		k8sClient.Clientset = clientset
 		log.Info().Msg("k8s 'in cluster' client is initialized")
 	}
@@ -232,15 +347,72 @@ func init() {
 func StartWatch(initTime time.Time) {
 	applicationInitTime = initTime
 
-	podController := newPodController() // pod watcher
-	hpaController := newHPAController() // Horizontal Pod Autoscaler watcher
-
 	stopCh := make(chan struct{})
 	defer close(stopCh)
 
+	// failed deliveries are retried regardless of the enabled watchers
+	deliveryController := newDeliveryController()
+	go deliveryController.Run(config.WatcherThreads(), stopCh)
+
+	// namespace annotations are the defaults of the resources in the namespace,
+	// so the namespaces cache should be ready before the watchers handle events
+	startNamespaceInformer(stopCh)
+
+	// silences are taken on any replica, and the leader drops the events of the silenced workloads
+	startSilencesInformer(stopCh)
+
+	// the job and the cron job watchers share the jobs cache, and the pods of jobs get the slack channels of their job from it
+	if config.WatcherEnabled("job") || config.WatcherEnabled("cronjob") {
+		startJobsInformer(stopCh)
+		workloadIndexers["Job"] = jobsIndexer
+	}
+
 	// run controllers
-	go podController.Run(config.WatcherThreads(), stopCh)
-	go hpaController.Run(config.WatcherThreads(), stopCh)
+	if config.WatcherEnabled("deployment") {
+		deploymentController := newDeploymentController() // deployment rollout watcher
+		workloadIndexers["Deployment"] = deploymentController.indexer
+		go deploymentController.Run(config.WatcherThreads(), stopCh)
+	}
+
+	if config.WatcherEnabled("pod") {
+		podController := newPodController() // pod watcher
+		go podController.Run(config.WatcherThreads(), stopCh)
+	}
+
+	if config.WatcherEnabled("hpa") {
+		hpaController := newHPAController() // Horizontal Pod Autoscaler watcher
+		go hpaController.Run(config.WatcherThreads(), stopCh)
+	}
+
+	if config.WatcherEnabled("node") {
+		nodeController := newNodeController() // node watcher
+		go nodeController.Run(config.WatcherThreads(), stopCh)
+	}
+
+	if config.WatcherEnabled("job") {
+		jobController := newJobController() // job watcher
+		go jobController.Run(config.WatcherThreads(), stopCh)
+	}
+
+	if config.WatcherEnabled("cronjob") {
+		cronJobController := newCronJobController() // cron job watcher
+		go cronJobController.Run(config.WatcherThreads(), stopCh)
+	}
+
+	if config.WatcherEnabled("route") {
+		if routeResourcesInstalled() {
+			routeController := newRouteController() // KubeObserverRoute resources watcher
+			go routeController.Run(config.WatcherThreads(), stopCh)
+			go syncRoutesStatus(stopCh)
+		} else {
+			log.Info().Msg("KubeObserverRoute resource definition is not installed, route resources won't be watched")
+		}
+	}
+
+	if config.WatcherEnabled("event") {
+		eventController := newEventController() // k8s Events watcher
+		go eventController.Run(config.WatcherThreads(), stopCh)
+	}
 
 	// wait forever
 	select {}
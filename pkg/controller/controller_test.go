@@ -8,7 +8,11 @@ import (
 	"testing"
 
 	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/metrics"
 	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
@@ -176,6 +180,29 @@ func TestSendEventToReceivers(t *testing.T) {
 	}()
 }
 
+func TestNewReceiverEvent(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            "mockPod",
+		Namespace:       "mockNamespace",
+		Labels:          map[string]string{"app": "mock"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "mockReplicaSet"}},
+	}}
+
+	event := newReceiverEvent(receivers.AddEvent, "Pod", pod)
+
+	if event.Name != "mockPod" || event.Namespace != "mockNamespace" || event.Kind != "Pod" || event.Labels["app"] != "mock" {
+		t.Errorf("TestNewReceiverEvent: resource identity wasn't set properly: %+v", event)
+	}
+
+	if event.Owner.Kind != "ReplicaSet" || event.Owner.Name != "mockReplicaSet" {
+		t.Errorf("TestNewReceiverEvent: owner wasn't set properly: %+v", event.Owner)
+	}
+
+	if event.Cluster != config.ClusterName() || event.Severity != receivers.InfoSeverity {
+		t.Errorf("TestNewReceiverEvent: default values weren't set properly: %+v", event)
+	}
+}
+
 func TestWaitForChannelsToClose(t *testing.T) {
 	var channelList []chan error
 	channel := make(chan error)
@@ -193,3 +220,34 @@ func TestWaitForChannelsToClose(t *testing.T) {
 		}
 	}
 }
+
+func TestEventMetrics(t *testing.T) {
+	c := mockNewController()
+	key := `{"EventName":"Add","PodName":"default/mockPod"}`
+
+	enqueueEvent(c.queue, c.resourceType, receivers.AddEvent, key)
+	if count := testutil.ToFloat64(metrics.EventsEnqueued.WithLabelValues(c.resourceType, "Add")); count != 1 {
+		t.Errorf("Enqueued events metric should be 1, got %v", count)
+	}
+
+	// the mock controller logic always fails
+	c.processNextItem()
+	if count := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues(c.resourceType, "Add", "error")); count != 1 {
+		t.Errorf("Processed events metric should be 1, got %v", count)
+	}
+}
+
+func TestReceiverMetrics(t *testing.T) {
+	receivers.ReceiverMap["mockReceiver"] = mockReceiver{}
+
+	sendEventToReceivers(receivers.ReceiverEvent{EventName: receivers.AddEvent}, []string{"mockReceiver"})
+	sendEventToReceivers(receivers.ReceiverEvent{EventName: receivers.DeleteEvent}, []string{"mockReceiver"})
+
+	if count := testutil.ToFloat64(metrics.ReceiverEvents.WithLabelValues("mockReceiver", "success")); count < 1 {
+		t.Errorf("Receiver success metric should be counted, got %v", count)
+	}
+
+	if count := testutil.ToFloat64(metrics.ReceiverEvents.WithLabelValues("mockReceiver", "failure")); count < 1 {
+		t.Errorf("Receiver failure metric should be counted, got %v", count)
+	}
+}
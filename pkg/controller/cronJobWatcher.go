@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/common"
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	watchCronJobAnnotationName               = "cronjob-kubeobserver.io/watch"
+	cronJobMissedScheduleGraceAnnotationName = "cronjob-watch-kubeobserver.io/missed_schedule_grace"
+	cronJobSuccessIntervalAnnotationName     = "cronjob-watch-kubeobserver.io/success_interval"
+	cronJobSlackUserIdsAnnotationName        = "cronjob-watch-kubeobserver.io/slack_users_id"
+
+	// the time a scheduled run may be late before it is reported as missed
+	defaultMissedScheduleGrace = 5 * time.Minute
+)
+
+type cronJobEvent struct {
+	EventName      receivers.EventName
+	CronJobName    string
+	NewCronJobData *batchv1beta1.CronJob
+}
+
+// reportedCronJobs holds the last reported missed schedule and the last success time that was
+// reported as too old for each cron job (by namespace/name key), so each one is reported once
+var reportedCronJobs = struct {
+	sync.Mutex
+	missedSchedules map[string]time.Time
+	lastSuccesses   map[string]time.Time
+}{missedSchedules: make(map[string]time.Time), lastSuccesses: make(map[string]time.Time)}
+
+func newCronJobController() *controller {
+	// create the cron job watcher
+	cronJobListWatcher := cache.NewListWatchFromClient(k8sClient.Clientset.BatchV1beta1().RESTClient(), "cronjobs", v1.NamespaceAll, fields.Everything())
+
+	// create the workqueue
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "cronjob")
+
+	// Bind the workqueue to a cache with the help of an informer. This way we make sure that
+	// whenever the cache is updated, the cron job key is added to the workqueue.
+	// missed schedules are the lack of an update, so the cron jobs are checked on every resync
+	indexer, informer := cache.NewIndexerInformer(cronJobListWatcher, &batchv1beta1.CronJob{}, jobsResyncPeriod, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old interface{}, new interface{}) {
+			key, err := cache.MetaNamespaceKeyFunc(new)
+			newCronJob := new.(*batchv1beta1.CronJob)
+			if err == nil && shouldWatchCronJob(key, newCronJob) {
+				out, err := json.Marshal(cronJobEvent{
+					EventName:      receivers.UpdateEvent,
+					CronJobName:    key,
+					NewCronJobData: newCronJob,
+				})
+
+				if err == nil {
+					enqueueEvent(queue, "cronjob", receivers.UpdateEvent, string(out))
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err == nil {
+				reportedCronJobs.Lock()
+				delete(reportedCronJobs.missedSchedules, key)
+				delete(reportedCronJobs.lastSuccesses, key)
+				reportedCronJobs.Unlock()
+			}
+		},
+	}, cache.Indexers{})
+
+	return newController(queue, indexer, informer, cronJobEventsHandler, "cronjob")
+}
+
+// cronJobEventsHandler is the business logic of the cron job controller.
+// In case an error happened, it has to simply return the error.
+func cronJobEventsHandler(key string, indexer cache.Indexer) error {
+	log.Debug().Msg("running cronJobEventsHandler func")
+	event := cronJobEvent{}
+	json.Unmarshal([]byte(key), &event)
+
+	cronJob := event.NewCronJobData
+	if cronJob == nil || (cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend) {
+		return nil
+	}
+
+	schedule, err := cron.ParseStandard(cronJob.Spec.Schedule)
+	if err != nil {
+		log.Warn().Msg(fmt.Sprintf("CronJob [%s] schedule can't be parsed: %s", event.CronJobName, err))
+		return nil
+	}
+
+	now := time.Now()
+	annotations := cronJob.GetAnnotations()
+
+	if missedSchedule := getMissedSchedule(event.CronJobName, cronJob, schedule, now); !missedSchedule.IsZero() {
+		message := fmt.Sprintf("CronJob [`%s`] has `Missed` its scheduled run of `%s` in `%s` cluster. Schedule:`%s`\n",
+			event.CronJobName, missedSchedule.Format(time.RFC3339), config.ClusterName(), cronJob.Spec.Schedule)
+
+		sendCronJobEvent(event, "CronJobMissedSchedule", receivers.WarningSeverity, message)
+	}
+
+	successInterval := getDurationAnnotation(annotations, cronJobSuccessIntervalAnnotationName, 0)
+	if successInterval <= 0 {
+		return nil
+	}
+
+	lastSuccess, err := getLastSuccessfulRun(cronJob)
+	if err != nil {
+		return err
+	}
+
+	if isSuccessOverdue(event.CronJobName, cronJob, lastSuccess, successInterval, now) {
+		lastSuccessStr := "never"
+		if !lastSuccess.IsZero() {
+			lastSuccessStr = lastSuccess.Format(time.RFC3339)
+		}
+
+		message := fmt.Sprintf("CronJob [`%s`] has `Not Succeeded` within `%v` in `%s` cluster. Last success:`%s`. Schedule:`%s`\n",
+			event.CronJobName, successInterval, config.ClusterName(), lastSuccessStr, cronJob.Spec.Schedule)
+
+		sendCronJobEvent(event, "CronJobNotSucceeded", receivers.CriticalSeverity, message)
+	}
+
+	return nil
+}
+
+func sendCronJobEvent(event cronJobEvent, reason string, severity receivers.Severity, message string) {
+	cronJob := event.NewCronJobData
+
+	cronJobWatchSlackUsersID := make([]string, 0)
+	if slackUsersID := cronJob.GetAnnotations()[cronJobSlackUserIdsAnnotationName]; slackUsersID != "" {
+		cronJobWatchSlackUsersID = strings.Split(slackUsersID, ",")
+	}
+
+	receiverEvent := newReceiverEvent(event.EventName, common.CronJobKind, cronJob)
+	receiverEvent.Message = message
+	receiverEvent.Reason = reason
+	receiverEvent.Severity = severity
+	receiverEvent.Mentions = cronJobWatchSlackUsersID
+	receiverEvent.AdditionalInfo["schedule"] = cronJob.Spec.Schedule
+	receiverEvent.AdditionalInfo["active_jobs"] = len(cronJob.Status.Active)
+
+	eventReceivers := buildEventReceivers(&receiverEvent)
+	log.Debug().Msg(fmt.Sprintf("found %d event receivers for CronJob[%s]. receivers[%s]. reason: %s.",
+		len(eventReceivers), event.CronJobName, strings.Join(eventReceivers, ","), reason))
+
+	dispatchEvent(receiverEvent, eventReceivers)
+}
+
+// getMissedSchedule returns the first scheduled run after the last run of the cron job when it is later than
+// the grace period (a run that is not started while its previous run is active is missed as well).
+// a zero time is returned when no run was missed or when the missed run was already reported
+func getMissedSchedule(cronJobKey string, cronJob *batchv1beta1.CronJob, schedule cron.Schedule, now time.Time) time.Time {
+	lastRun := cronJob.GetCreationTimestamp().Time
+	if cronJob.Status.LastScheduleTime != nil {
+		lastRun = cronJob.Status.LastScheduleTime.Time
+	}
+
+	scheduled := schedule.Next(lastRun)
+	grace := getDurationAnnotation(cronJob.GetAnnotations(), cronJobMissedScheduleGraceAnnotationName, defaultMissedScheduleGrace)
+
+	if scheduled.IsZero() || now.Before(scheduled.Add(grace)) {
+		return time.Time{}
+	}
+
+	reportedCronJobs.Lock()
+	defer reportedCronJobs.Unlock()
+
+	if reportedCronJobs.missedSchedules[cronJobKey].Equal(scheduled) {
+		return time.Time{}
+	}
+
+	reportedCronJobs.missedSchedules[cronJobKey] = scheduled
+
+	return scheduled
+}
+
+// isSuccessOverdue returns true when the cron job has not succeeded within the interval, counting from
+// its creation when it has never succeeded. each last success is reported once
+func isSuccessOverdue(cronJobKey string, cronJob *batchv1beta1.CronJob, lastSuccess time.Time, interval time.Duration, now time.Time) bool {
+	since := lastSuccess
+	if since.IsZero() {
+		since = cronJob.GetCreationTimestamp().Time
+	}
+
+	if now.Sub(since) <= interval {
+		return false
+	}
+
+	reportedCronJobs.Lock()
+	defer reportedCronJobs.Unlock()
+
+	if reported, ok := reportedCronJobs.lastSuccesses[cronJobKey]; ok && reported.Equal(lastSuccess) {
+		return false
+	}
+
+	reportedCronJobs.lastSuccesses[cronJobKey] = lastSuccess
+
+	return true
+}
+
+// getLastSuccessfulRun returns the completion time of the latest successful job of the cron job from the jobs cache.
+// the cron job keeps the history of its jobs by its successfulJobsHistoryLimit (3 by default)
+func getLastSuccessfulRun(cronJob *batchv1beta1.CronJob) (time.Time, error) {
+	var lastSuccess time.Time
+
+	// a cron job isn't reported as not succeeded before its jobs were listed
+	if jobsIndexer == nil || jobsCacheSynced == nil || !jobsCacheSynced() {
+		return lastSuccess, fmt.Errorf("jobs cache isn't synced")
+	}
+
+	jobs, err := jobsIndexer.ByIndex(jobControllerUIDIndex, string(cronJob.GetUID()))
+	if err != nil {
+		return lastSuccess, err
+	}
+
+	for _, obj := range jobs {
+		job := obj.(*batchv1.Job)
+
+		if getJobCondition(job, batchv1.JobComplete) == nil || job.Status.CompletionTime == nil {
+			continue
+		}
+
+		if job.Status.CompletionTime.After(lastSuccess) {
+			lastSuccess = job.Status.CompletionTime.Time
+		}
+	}
+
+	return lastSuccess, nil
+}
+
+// cron jobs are opt-in, so only cron jobs with the watch annotation are watched
+func shouldWatchCronJob(cronJobNamespaceKey string, cronJob *batchv1beta1.CronJob) bool {
+	shouldWatch := cronJob.Annotations != nil && cronJob.Annotations[watchCronJobAnnotationName] == "true" &&
+		config.ShouldWatchNamespace(cronJob.GetNamespace())
+
+	if !shouldWatch {
+		log.Debug().Msg(fmt.Sprintf("cronjob-watcher: ignoring cron job [%s] event", cronJobNamespaceKey))
+	}
+
+	return shouldWatch
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func mockCronJob(name string, lastSchedule time.Time) *batchv1beta1.CronJob {
+	lastScheduleTime := metav1.NewTime(lastSchedule)
+
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			UID:               "cronjob-uid",
+			CreationTimestamp: metav1.NewTime(lastSchedule.Add(-48 * time.Hour)),
+			Annotations:       map[string]string{watchCronJobAnnotationName: "true"},
+		},
+		Spec:   batchv1beta1.CronJobSpec{Schedule: "0 * * * *"},
+		Status: batchv1beta1.CronJobStatus{LastScheduleTime: &lastScheduleTime},
+	}
+}
+
+func TestNewCronJobController(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset()
+	cronJobController := newCronJobController()
+
+	if cronJobController == nil {
+		t.Error("TestNewCronJobController: couldn't create a new cron job controller")
+	}
+}
+
+func TestGetMissedSchedule(t *testing.T) {
+	lastRun := time.Date(2021, time.March, 17, 10, 0, 0, 0, time.UTC)
+	cronJob := mockCronJob("hourly", lastRun)
+	schedule, _ := cron.ParseStandard(cronJob.Spec.Schedule)
+
+	if missed := getMissedSchedule("default/hourly", cronJob, schedule, lastRun.Add(time.Hour+time.Minute)); !missed.IsZero() {
+		t.Errorf("Run within the grace period shouldn't be missed, got %v", missed)
+	}
+
+	now := lastRun.Add(time.Hour + 10*time.Minute)
+	if missed := getMissedSchedule("default/hourly", cronJob, schedule, now); !missed.Equal(lastRun.Add(time.Hour)) {
+		t.Errorf("Expected the 11:00 run to be missed, got %v", missed)
+	}
+
+	if missed := getMissedSchedule("default/hourly", cronJob, schedule, now.Add(time.Minute)); !missed.IsZero() {
+		t.Errorf("Missed run should be reported once, got %v", missed)
+	}
+}
+
+func TestGetLastSuccessfulRun(t *testing.T) {
+	cronJob := mockCronJob("nightly", time.Now())
+	isController := true
+	completionTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	ownerReferences := []metav1.OwnerReference{{Kind: "CronJob", Name: "nightly", UID: cronJob.GetUID(), Controller: &isController}}
+
+	jobsIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{jobControllerUIDIndex: jobControllerUIDIndexFunc})
+	jobsCacheSynced = func() bool { return false }
+	defer func() { jobsIndexer, jobsCacheSynced = nil, nil }()
+
+	jobsIndexer.Add(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly-1", Namespace: "default", OwnerReferences: ownerReferences},
+		Status: batchv1.JobStatus{
+			CompletionTime: &completionTime,
+			Conditions:     []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}},
+		},
+	})
+	jobsIndexer.Add(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly-2", Namespace: "default", OwnerReferences: ownerReferences},
+		Status:     batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: v1.ConditionTrue}}},
+	})
+
+	// a successful job of another cron job
+	otherCompletionTime := metav1.NewTime(time.Now())
+	otherController := []metav1.OwnerReference{{Kind: "CronJob", Name: "other", UID: "other-uid", Controller: &isController}}
+	jobsIndexer.Add(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-1", Namespace: "default", OwnerReferences: otherController},
+		Status: batchv1.JobStatus{
+			CompletionTime: &otherCompletionTime,
+			Conditions:     []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}},
+		},
+	})
+
+	if _, err := getLastSuccessfulRun(cronJob); err == nil {
+		t.Error("Last success shouldn't be checked before the jobs cache is synced")
+	}
+
+	jobsCacheSynced = func() bool { return true }
+
+	lastSuccess, err := getLastSuccessfulRun(cronJob)
+	if err != nil || !lastSuccess.Equal(completionTime.Time) {
+		t.Errorf("Expected last success at %v, got %v (%v)", completionTime.Time, lastSuccess, err)
+	}
+
+	if !isSuccessOverdue("default/nightly", cronJob, lastSuccess, 30*time.Minute, time.Now()) {
+		t.Error("Cron job that hasn't succeeded within the interval should be reported")
+	}
+
+	if isSuccessOverdue("default/nightly", cronJob, lastSuccess, 30*time.Minute, time.Now()) {
+		t.Error("Overdue success should be reported once")
+	}
+
+	if isSuccessOverdue("default/nightly", cronJob, lastSuccess, 2*time.Hour, time.Now()) {
+		t.Error("Cron job that has succeeded within the interval shouldn't be reported")
+	}
+}
@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var deliveryResourceType = "delivery"
+
+// maxDeliveryRetries is the number of times a failed delivery is retried before it is dropped,
+// the same number of retries the controllers give their events
+const maxDeliveryRetries = 5
+
+// deliveryQueue holds the keys of the receivers and resources that have failed deliveries. they are retried
+// by the delivery controller with the same rate limited retries the watchers use for their events
+var deliveryQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), deliveryResourceType)
+
+// pendingDeliveries holds the events of a receiver and resource, by delivery key, from the first one that failed
+// with a retryable error. the events that follow it wait behind it instead of being sent right away, so
+// a failed delivery never lets a later event of its resource overtake it
+var pendingDeliveries = struct {
+	sync.Mutex
+	events map[string][]receivers.ReceiverEvent
+	locks  map[string]*deliveryLock
+}{events: make(map[string][]receivers.ReceiverEvent), locks: make(map[string]*deliveryLock)}
+
+// deliveryLock is held while an event of the key is delivered, refs counts the events that wait for it
+type deliveryLock struct {
+	sync.Mutex
+	refs int
+}
+
+// deliveryKey is the queue key of the failed deliveries of a receiver and a resource. the queue
+// handles a key by a single worker at a time, which keeps the order of the resource events
+type deliveryKey struct {
+	ReceiverName string
+	Resource     string
+}
+
+// newDeliveryController returns a controller of the delivery queue. it has no informer
+// since the deliveries are queued by the other controllers
+func newDeliveryController() *controller {
+	return newController(deliveryQueue, nil, nil, deliveryHandler, deliveryResourceType)
+}
+
+func newDeliveryKey(receiverName string, receiverEvent receivers.ReceiverEvent) string {
+	key, _ := json.Marshal(deliveryKey{
+		ReceiverName: receiverName,
+		Resource:     strings.Join([]string{receiverEvent.Cluster, receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name}, "/"),
+	})
+
+	return string(key)
+}
+
+// lockDelivery waits for the delivery of the key that is in progress and returns the function that releases the key
+func lockDelivery(key string) func() {
+	pendingDeliveries.Lock()
+	lock, ok := pendingDeliveries.locks[key]
+	if !ok {
+		lock = &deliveryLock{}
+		pendingDeliveries.locks[key] = lock
+	}
+	lock.refs++
+	pendingDeliveries.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		pendingDeliveries.Lock()
+		defer pendingDeliveries.Unlock()
+
+		if lock.refs--; lock.refs == 0 {
+			delete(pendingDeliveries.locks, key)
+		}
+	}
+}
+
+// addPendingDelivery puts the event behind the pending events of the key, and
+// returns true when it is the first one, so the key should be queued
+func addPendingDelivery(key string, receiverEvent receivers.ReceiverEvent) bool {
+	pendingDeliveries.Lock()
+	defer pendingDeliveries.Unlock()
+
+	pendingDeliveries.events[key] = append(pendingDeliveries.events[key], receiverEvent)
+
+	return len(pendingDeliveries.events[key]) == 1
+}
+
+func hasPendingDeliveries(key string) bool {
+	pendingDeliveries.Lock()
+	defer pendingDeliveries.Unlock()
+
+	return len(pendingDeliveries.events[key]) > 0
+}
+
+// firstPendingDelivery returns the oldest pending event of the key
+func firstPendingDelivery(key string) (receivers.ReceiverEvent, bool) {
+	pendingDeliveries.Lock()
+	defer pendingDeliveries.Unlock()
+
+	if events := pendingDeliveries.events[key]; len(events) > 0 {
+		return events[0], true
+	}
+
+	return receivers.ReceiverEvent{}, false
+}
+
+// removeFirstPendingDelivery removes the oldest pending event of the key and returns the number of events left
+func removeFirstPendingDelivery(key string) int {
+	pendingDeliveries.Lock()
+	defer pendingDeliveries.Unlock()
+
+	events := pendingDeliveries.events[key]
+	if len(events) <= 1 {
+		delete(pendingDeliveries.events, key)
+		return 0
+	}
+
+	pendingDeliveries.events[key] = events[1:]
+
+	return len(events) - 1
+}
+
+// dropPendingDeliveries removes all of the pending events of the key and counts them as dropped
+func dropPendingDeliveries(key string) {
+	pendingDeliveries.Lock()
+	events := pendingDeliveries.events[key]
+	delete(pendingDeliveries.events, key)
+	pendingDeliveries.Unlock()
+
+	for _, receiverEvent := range events {
+		metrics.EventsDropped.WithLabelValues(deliveryResourceType, string(receiverEvent.EventName)).Inc()
+	}
+}
+
+// deliverEvent sends the event to the receiver, unless earlier events of its resource wait for a retry,
+// in which case it waits behind them. a delivery that fails with a retryable error is queued to the
+// delivery controller. the receiver result is passed onward to the given channel
+func deliverEvent(receiverName string, receiver receivers.Receiver, receiverEvent receivers.ReceiverEvent, c chan error) {
+	key := newDeliveryKey(receiverName, receiverEvent)
+	unlock := lockDelivery(key)
+	defer unlock()
+
+	if hasPendingDeliveries(key) {
+		addPendingDelivery(key, receiverEvent)
+		metrics.EventsEnqueued.WithLabelValues(deliveryResourceType, string(receiverEvent.EventName)).Inc()
+		log.Debug().Msg(fmt.Sprintf("%s event of %s %s/%s waits for the failed deliveries of the resource to %s receiver",
+			receiverEvent.EventName, receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name, receiverName))
+
+		c <- nil
+		return
+	}
+
+	result := make(chan error)
+	go sendEventToReceiver(receiverName, receiver, receiverEvent, result)
+
+	err := <-result
+	if receivers.IsRetryable(err) && addPendingDelivery(key, receiverEvent) {
+		deliveryQueue.AddRateLimited(key)
+		metrics.EventsEnqueued.WithLabelValues(deliveryResourceType, string(receiverEvent.EventName)).Inc()
+	}
+
+	c <- err
+}
+
+// deliveryHandler sends the oldest pending event of the key to its receiver again. the error is returned
+// only when the delivery should be retried once more, otherwise the next pending event is queued
+func deliveryHandler(key string, indexer cache.Indexer) error {
+	var d deliveryKey
+	if err := json.Unmarshal([]byte(key), &d); err != nil {
+		log.Error().Msg(fmt.Sprintf("couldn't unmarshal delivery %s: %s", key, err.Error()))
+		return nil
+	}
+
+	unlock := lockDelivery(key)
+	defer unlock()
+
+	receiverEvent, ok := firstPendingDelivery(key)
+	if !ok {
+		return nil
+	}
+
+	// the new leader is responsible for the events from now on
+	if !IsLeader() {
+		dropPendingDeliveries(key)
+		return nil
+	}
+
+	receiver := receivers.GetReceiver(d.ReceiverName)
+	if receiver == nil {
+		log.Warn().Msg(fmt.Sprintf("a delivery was requested to unknown receiver: %s", d.ReceiverName))
+		dropPendingDeliveries(key)
+		return nil
+	}
+
+	result := make(chan error)
+	go sendEventToReceiver(d.ReceiverName, receiver, receiverEvent, result)
+
+	err := <-result
+	if receivers.IsRetryable(err) && deliveryQueue.NumRequeues(key) < maxDeliveryRetries {
+		return err
+	}
+
+	if err != nil {
+		metrics.EventsDropped.WithLabelValues(deliveryResourceType, string(receiverEvent.EventName)).Inc()
+		log.Error().Msg(fmt.Sprintf("dropping %s event of %s %s/%s after the deliveries to %s receiver failed: %s",
+			receiverEvent.EventName, receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name, d.ReceiverName, err))
+	}
+
+	// the next event of the resource is sent right away, it has waited for this one
+	if removeFirstPendingDelivery(key) > 0 {
+		deliveryQueue.Add(key)
+	}
+
+	return nil
+}
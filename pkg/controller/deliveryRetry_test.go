@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// flakyReceiver records the reasons of the events it got and fails with a retryable error the first failures times
+type flakyReceiver struct {
+	sync.Mutex
+	failures int
+	reasons  []string
+}
+
+func (fr *flakyReceiver) HandleEvent(r receivers.ReceiverEvent, c chan error) {
+	defer close(c)
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if fr.failures > 0 {
+		fr.failures--
+		c <- &receivers.RetryableError{Err: errors.New("mock broker is down")}
+		return
+	}
+
+	fr.reasons = append(fr.reasons, r.Reason)
+}
+
+func (fr *flakyReceiver) received() string {
+	fr.Lock()
+	defer fr.Unlock()
+
+	return strings.Join(fr.reasons, ",")
+}
+
+// newMockDeliveryController returns a delivery controller with a queue that retries without a delay
+func newMockDeliveryController(t *testing.T) *controller {
+	previous := deliveryQueue
+	deliveryQueue = workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(0, 0))
+
+	t.Cleanup(func() { deliveryQueue = previous })
+
+	return newDeliveryController()
+}
+
+func deliverMockEvent(receiverName string, receiver receivers.Receiver, reason string) error {
+	c := make(chan error, 1)
+	deliverEvent(receiverName, receiver, receivers.ReceiverEvent{EventName: receivers.UpdateEvent, Kind: "Pod", Namespace: "payments", Name: "mockPod", Reason: reason}, c)
+
+	return <-c
+}
+
+func TestDeliveryRetryKeepsOrder(t *testing.T) {
+	c := newMockDeliveryController(t)
+	receiver := &flakyReceiver{failures: 2}
+
+	if err := deliverMockEvent("flakyReceiver", receiver, "first"); !receivers.IsRetryable(err) {
+		t.Fatalf("TestDeliveryRetryKeepsOrder: expected the retryable error of the receiver, got %v", err)
+	}
+
+	// the next event of the resource waits for the failed one instead of overtaking it
+	if err := deliverMockEvent("flakyReceiver", receiver, "second"); err != nil || receiver.received() != "" {
+		t.Fatalf("TestDeliveryRetryKeepsOrder: the second event should wait for the first one, got %s. err: %v", receiver.received(), err)
+	}
+
+	receivers.ReceiverMap["flakyReceiver"] = receiver
+	defer delete(receivers.ReceiverMap, "flakyReceiver")
+
+	for c.queue.Len() > 0 {
+		c.processNextItem()
+	}
+
+	if received := receiver.received(); received != "first,second" {
+		t.Errorf("TestDeliveryRetryKeepsOrder: expected the events in order, got %s", received)
+	}
+
+	if err := deliverMockEvent("flakyReceiver", receiver, "third"); err != nil || receiver.received() != "first,second,third" {
+		t.Errorf("TestDeliveryRetryKeepsOrder: events should be sent right away once the pending ones were delivered, got %s", receiver.received())
+	}
+}
+
+func TestDeliveryDropped(t *testing.T) {
+	c := newMockDeliveryController(t)
+	receiver := &flakyReceiver{failures: 100}
+
+	receivers.ReceiverMap["brokenReceiver"] = receiver
+	defer delete(receivers.ReceiverMap, "brokenReceiver")
+
+	dropped := testutil.ToFloat64(metrics.EventsDropped.WithLabelValues(deliveryResourceType, "Update"))
+
+	deliverMockEvent("brokenReceiver", receiver, "first")
+	deliverMockEvent("brokenReceiver", receiver, "second")
+
+	for c.queue.Len() > 0 {
+		c.processNextItem()
+	}
+
+	// both events get all of the retries, the second one after the first was dropped
+	if receiver.failures != 100-2-2*maxDeliveryRetries {
+		t.Errorf("TestDeliveryDropped: expected %d deliveries, got %d", 2+2*maxDeliveryRetries, 100-receiver.failures)
+	}
+
+	if count := testutil.ToFloat64(metrics.EventsDropped.WithLabelValues(deliveryResourceType, "Update")); count != dropped+2 {
+		t.Errorf("TestDeliveryDropped: expected the deliveries to be counted as dropped, got %v", count-dropped)
+	}
+}
+
+func TestDeliveryPermanentErrorIsNotRetried(t *testing.T) {
+	c := newMockDeliveryController(t)
+
+	if err := deliverMockEvent("mockReceiver", mockReceiver{}, "first"); err == nil {
+		t.Fatal("TestDeliveryPermanentErrorIsNotRetried: expected the error of the receiver")
+	}
+
+	if c.queue.Len() != 0 {
+		t.Error("TestDeliveryPermanentErrorIsNotRetried: a delivery with a permanent error shouldn't be retried")
+	}
+}
+
+func TestDeliveryDroppedByFollower(t *testing.T) {
+	c := newMockDeliveryController(t)
+	receiver := &flakyReceiver{failures: 1}
+
+	receivers.ReceiverMap["flakyReceiver"] = receiver
+	defer delete(receivers.ReceiverMap, "flakyReceiver")
+
+	deliverMockEvent("flakyReceiver", receiver, "first")
+
+	defer setLeader(IsLeader())
+	setLeader(false)
+
+	for c.queue.Len() > 0 {
+		c.processNextItem()
+	}
+
+	if receiver.received() != "" || hasPendingDeliveries(newDeliveryKey("flakyReceiver", receivers.ReceiverEvent{Kind: "Pod", Namespace: "payments", Name: "mockPod"})) {
+		t.Error("TestDeliveryDroppedByFollower: a replica that isn't the leader should drop its pending deliveries")
+	}
+}
@@ -0,0 +1,351 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/PayU/kubeobserver/pkg/common"
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	ignoreAllDeploymentEventsAnnotationName = "deployment-kubeobserver.io/ignore"
+	watchDeploymentProgressAnnotationName   = "deployment-progress-kubeobserver.io/watch"
+	deploymentSlackUserIdsAnnotationName    = "deployment-watch-kubeobserver.io/slack_users_id"
+
+	// annotations and reasons set by the k8s deployment controller
+	deploymentRevisionAnnotationName        = "deployment.kubernetes.io/revision"
+	deploymentRevisionHistoryAnnotationName = "deployment.kubernetes.io/revision-history"
+	deploymentProgressDeadlineReason        = "ProgressDeadlineExceeded"
+)
+
+type rolloutState string
+
+const (
+	rolloutStarted     rolloutState = "Started"
+	rolloutProgressing rolloutState = "Progressing"
+	rolloutCompleted   rolloutState = "Completed"
+	rolloutStalled     rolloutState = "Stalled"
+	rolloutRolledBack  rolloutState = "RolledBack"
+)
+
+type deploymentEvent struct {
+	EventName         receivers.EventName
+	DeploymentName    string
+	NewDeploymentData *appsv1.Deployment
+	OldDeploymentData *appsv1.Deployment
+}
+
+// activeRollouts holds the deployments (by namespace/name key) that have a rollout in progress.
+// it is used to tell a real rollout apart from a simple scale of the deployment
+var activeRollouts = struct {
+	sync.Mutex
+	revisions map[string]string
+}{revisions: make(map[string]string)}
+
+func newDeploymentController() *controller {
+	// create the deployment watcher
+	deploymentListWatcher := cache.NewListWatchFromClient(k8sClient.Clientset.AppsV1().RESTClient(), "deployments", v1.NamespaceAll, fields.Everything())
+
+	// create the workqueue
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "deployment")
+
+	// Bind the workqueue to a cache with the help of an informer. This way we make sure that
+	// whenever the cache is updated, the deployment key is added to the workqueue.
+	// a rollout is always reflected as an update of the deployment status, so add events
+	// (including the initial list) are not queued
+	indexer, informer := cache.NewIndexerInformer(deploymentListWatcher, &appsv1.Deployment{}, 0, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old interface{}, new interface{}) {
+			key, err := cache.MetaNamespaceKeyFunc(new)
+			newDeployment := new.(*appsv1.Deployment)
+			if err == nil && shouldWatchDeployment(key, newDeployment) {
+				out, err := json.Marshal(deploymentEvent{
+					EventName:         receivers.UpdateEvent,
+					DeploymentName:    key,
+					NewDeploymentData: newDeployment,
+					OldDeploymentData: old.(*appsv1.Deployment),
+				})
+
+				if err == nil {
+					enqueueEvent(queue, "deployment", receivers.UpdateEvent, string(out))
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err == nil {
+				activeRollouts.Lock()
+				delete(activeRollouts.revisions, key)
+				activeRollouts.Unlock()
+			}
+		},
+	}, cache.Indexers{})
+
+	return newController(queue, indexer, informer, deploymentEventsHandler, "deployment")
+}
+
+// deploymentEventsHandler is the business logic of the deployment controller.
+// In case an error happened, it has to simply return the error.
+func deploymentEventsHandler(key string, indexer cache.Indexer) error {
+	log.Debug().Msg("running deploymentEventsHandler func")
+	event := deploymentEvent{}
+	json.Unmarshal([]byte(key), &event)
+
+	newDeployment := event.NewDeploymentData
+	oldDeployment := event.OldDeploymentData
+
+	if newDeployment == nil || oldDeployment == nil {
+		log.Warn().Msg(fmt.Sprintf("Deployment [%s] old and/or new data is nil. unable to handle rollout", event.DeploymentName))
+		return nil
+	}
+
+	state := getRolloutState(event.DeploymentName, oldDeployment, newDeployment)
+	if state == "" {
+		return nil
+	}
+
+	// only the leader notifies about the rollout, so there is no need for the others to look up its replica sets
+	if state == rolloutStarted && IsLeader() {
+		rolledBack, err := isRolledBack(newDeployment)
+		if err != nil {
+			return err
+		}
+
+		if rolledBack {
+			state = rolloutRolledBack
+		}
+	}
+
+	deploymentAnnotations := getDeploymentAnnotations(newDeployment)
+
+	if state == rolloutProgressing && deploymentAnnotations[watchDeploymentProgressAnnotationName] != "true" {
+		return nil
+	}
+
+	deploymentWatchSlackUsersID := make([]string, 0)
+	if state == rolloutStalled || state == rolloutRolledBack {
+		if deploymentAnnotations[deploymentSlackUserIdsAnnotationName] != "" {
+			deploymentWatchSlackUsersID = strings.Split(deploymentAnnotations[deploymentSlackUserIdsAnnotationName], ",")
+		}
+	}
+
+	receiverEvent := newReceiverEvent(event.EventName, common.DeploymentKind, newDeployment)
+	receiverEvent.Message = buildRolloutMessage(event.DeploymentName, state, newDeployment)
+	receiverEvent.Reason = getRolloutReason(state)
+	receiverEvent.Severity = getRolloutSeverity(state)
+	receiverEvent.Annotations = deploymentAnnotations
+	receiverEvent.Mentions = deploymentWatchSlackUsersID
+
+	eventReceivers := buildEventReceivers(&receiverEvent)
+	log.Debug().Msg(fmt.Sprintf("found %d event receivers for Deployment[%s]. receivers[%s]. rollout-state: %s.",
+		len(eventReceivers), event.DeploymentName, strings.Join(eventReceivers, ","), state))
+
+	dispatchEvent(receiverEvent, eventReceivers)
+
+	return nil
+}
+
+// getRolloutState compares the old and the new deployment and returns the rollout state
+// the deployment has moved to. an empty state is returned when there is nothing to report.
+// a rollout is tracked from the moment the deployment revision changes (i.e. a new replica set
+// is created or an old one is reused) until the rollout is completed
+func getRolloutState(deploymentKey string, oldDeployment *appsv1.Deployment, newDeployment *appsv1.Deployment) rolloutState {
+	oldRevision := oldDeployment.GetAnnotations()[deploymentRevisionAnnotationName]
+	newRevision := newDeployment.GetAnnotations()[deploymentRevisionAnnotationName]
+
+	activeRollouts.Lock()
+	defer activeRollouts.Unlock()
+
+	if newRevision != "" && oldRevision != newRevision {
+		activeRollouts.revisions[deploymentKey] = newRevision
+		return rolloutStarted
+	}
+
+	_, isActive := activeRollouts.revisions[deploymentKey]
+
+	// a rollout that started before kubeobserver did still has pods of the old replica set
+	if !isActive && newDeployment.Status.Replicas > newDeployment.Status.UpdatedReplicas {
+		activeRollouts.revisions[deploymentKey] = newRevision
+		isActive = true
+	}
+
+	if !isActive {
+		return ""
+	}
+
+	if isRolloutStalled(newDeployment) {
+		if isRolloutStalled(oldDeployment) {
+			return ""
+		}
+
+		return rolloutStalled
+	}
+
+	if isRolloutComplete(newDeployment) {
+		delete(activeRollouts.revisions, deploymentKey)
+		return rolloutCompleted
+	}
+
+	if oldDeployment.Status.UpdatedReplicas != newDeployment.Status.UpdatedReplicas ||
+		oldDeployment.Status.AvailableReplicas != newDeployment.Status.AvailableReplicas {
+		return rolloutProgressing
+	}
+
+	return ""
+}
+
+// getRolloutReason maps a rollout state to the reason of the receiver event.
+// a stalled rollout uses the same reason as the deployment progressing condition
+func getRolloutReason(state rolloutState) string {
+	if state == rolloutStalled {
+		return deploymentProgressDeadlineReason
+	}
+
+	return "Rollout" + string(state)
+}
+
+func getRolloutSeverity(state rolloutState) receivers.Severity {
+	switch state {
+	case rolloutStalled:
+		return receivers.CriticalSeverity
+	case rolloutRolledBack:
+		return receivers.WarningSeverity
+	default:
+		return receivers.InfoSeverity
+	}
+}
+
+// isRolloutComplete uses the same logic as 'kubectl rollout status'
+func isRolloutComplete(deployment *appsv1.Deployment) bool {
+	var desiredReplicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		desiredReplicas = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == desiredReplicas &&
+		status.Replicas == status.UpdatedReplicas &&
+		status.AvailableReplicas == status.UpdatedReplicas
+}
+
+func isRolloutStalled(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing {
+			return condition.Reason == deploymentProgressDeadlineReason
+		}
+	}
+
+	return false
+}
+
+// isRolledBack checks whether the replica set of the current deployment revision was reused.
+// k8s keeps the previous revisions of a reused replica set in the revision-history annotation,
+// which happens when a deployment is rolled back (or its template is set back to an older one)
+func isRolledBack(deployment *appsv1.Deployment) (bool, error) {
+	revision := deployment.GetAnnotations()[deploymentRevisionAnnotationName]
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+
+	replicaSets, err := k8sClient.Clientset.AppsV1().ReplicaSets(deployment.GetNamespace()).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, err
+	}
+
+	for _, replicaSet := range replicaSets.Items {
+		if !metav1.IsControlledBy(&replicaSet, deployment) {
+			continue
+		}
+
+		if replicaSet.GetAnnotations()[deploymentRevisionAnnotationName] == revision {
+			return replicaSet.GetAnnotations()[deploymentRevisionHistoryAnnotationName] != "", nil
+		}
+	}
+
+	return false, nil
+}
+
+// getDeploymentAnnotations merges the pod template annotations with the deployment annotations,
+// so teams can use the same annotations they already set for the pod watcher.
+// deployment annotations take precedence, namespace annotations are the defaults
+func getDeploymentAnnotations(deployment *appsv1.Deployment) map[string]string {
+	annotations := make(map[string]string)
+
+	for k, v := range withNamespaceAnnotations(deployment.GetNamespace(), nil) {
+		annotations[k] = v
+	}
+
+	for k, v := range deployment.Spec.Template.GetAnnotations() {
+		annotations[k] = v
+	}
+
+	for k, v := range deployment.GetAnnotations() {
+		annotations[k] = v
+	}
+
+	return annotations
+}
+
+func buildRolloutMessage(deploymentName string, state rolloutState, deployment *appsv1.Deployment) string {
+	var eventMessage strings.Builder
+	revision := deployment.GetAnnotations()[deploymentRevisionAnnotationName]
+	status := deployment.Status
+
+	switch state {
+	case rolloutStarted:
+		eventMessage.WriteString(fmt.Sprintf("Deployment [`%s`] rollout of revision `%s` has `Started` in `%s` cluster\n", deploymentName, revision, config.ClusterName()))
+		eventMessage.WriteString(fmt.Sprintf("Images:`%s`\n", strings.Join(getDeploymentImages(deployment), ",")))
+	case rolloutRolledBack:
+		eventMessage.WriteString(fmt.Sprintf("Deployment [`%s`] has been `Rolled Back` to revision `%s` in `%s` cluster\n", deploymentName, revision, config.ClusterName()))
+		eventMessage.WriteString(fmt.Sprintf("Images:`%s`\n", strings.Join(getDeploymentImages(deployment), ",")))
+	case rolloutProgressing:
+		eventMessage.WriteString(fmt.Sprintf("Deployment [`%s`] rollout of revision `%s` is `Progressing` in `%s` cluster\n", deploymentName, revision, config.ClusterName()))
+	case rolloutCompleted:
+		eventMessage.WriteString(fmt.Sprintf("Deployment [`%s`] rollout of revision `%s` has `Completed` in `%s` cluster\n", deploymentName, revision, config.ClusterName()))
+	case rolloutStalled:
+		eventMessage.WriteString(fmt.Sprintf("Deployment [`%s`] rollout of revision `%s` has `Stalled` in `%s` cluster. progress deadline exceeded\n", deploymentName, revision, config.ClusterName()))
+	}
+
+	eventMessage.WriteString(fmt.Sprintf("updated-replicas:`%d` available-replicas:`%d` total-replicas:`%d`\n", status.UpdatedReplicas, status.AvailableReplicas, status.Replicas))
+
+	return eventMessage.String()
+}
+
+func getDeploymentImages(deployment *appsv1.Deployment) []string {
+	images := make([]string, 0)
+
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+
+	return images
+}
+
+// check if the specific deployment is mark as ignore (in annotations)
+// if so, return false. otherwise return true.
+func shouldWatchDeployment(deploymentNamespaceKey string, deployment *appsv1.Deployment) bool {
+	deploymentAnnotations := withNamespaceAnnotations(deployment.GetNamespace(), deployment.GetAnnotations())
+
+	shouldWatch := (deploymentAnnotations == nil || deploymentAnnotations[ignoreAllDeploymentEventsAnnotationName] != "true") &&
+		config.ShouldWatchNamespace(deployment.GetNamespace())
+
+	if !shouldWatch {
+		log.Debug().Msg(fmt.Sprintf("deployment-watcher: ignoring deployment [%s] event", deploymentNamespaceKey))
+	}
+
+	return shouldWatch
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func mockDeployment(revision string, replicas int32, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "mockDeployment",
+			Namespace:   "default",
+			UID:         "mockDeploymentUID",
+			Annotations: map[string]string{deploymentRevisionAnnotationName: revision},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "mock"}},
+		},
+		Status: status,
+	}
+}
+
+func TestNewDeploymentController(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset()
+	deploymentController := newDeploymentController()
+
+	if deploymentController == nil {
+		t.Error("TestNewDeploymentController: couldn't create a new deployment controller")
+	}
+}
+
+func TestGetRolloutState(t *testing.T) {
+	key := "default/mockRolloutDeployment"
+	completed := appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}
+	started := appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2}
+	progressing := appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2}
+	stalled := appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2, Conditions: []appsv1.DeploymentCondition{
+		{Type: appsv1.DeploymentProgressing, Status: v1.ConditionFalse, Reason: deploymentProgressDeadlineReason},
+	}}
+
+	steps := []struct {
+		old      *appsv1.Deployment
+		new      *appsv1.Deployment
+		expected rolloutState
+	}{
+		{mockDeployment("1", 2, completed), mockDeployment("2", 2, started), rolloutStarted},
+		{mockDeployment("2", 2, started), mockDeployment("2", 2, progressing), rolloutProgressing},
+		{mockDeployment("2", 2, progressing), mockDeployment("2", 2, stalled), rolloutStalled},
+		{mockDeployment("2", 2, stalled), mockDeployment("2", 2, stalled), ""},
+		{mockDeployment("2", 2, stalled), mockDeployment("2", 2, completed), rolloutCompleted},
+		// scaling a deployment without a rollout should not be reported
+		{mockDeployment("2", 2, completed), mockDeployment("2", 3, completed), ""},
+		{mockDeployment("2", 3, completed), mockDeployment("2", 3, appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}), ""},
+	}
+
+	for i, step := range steps {
+		if state := getRolloutState(key, step.old, step.new); state != step.expected {
+			t.Errorf("TestGetRolloutState: step %d expected state [%s] but got [%s]", i, step.expected, state)
+		}
+	}
+}
+
+func TestIsRolledBack(t *testing.T) {
+	deployment := mockDeployment("3", 1, appsv1.DeploymentStatus{})
+	isController := true
+	ownerReferences := []metav1.OwnerReference{{Kind: "Deployment", Name: deployment.Name, UID: deployment.UID, Controller: &isController}}
+
+	newReplicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:            "mockDeployment-new",
+		Namespace:       "default",
+		Labels:          map[string]string{"app": "mock"},
+		Annotations:     map[string]string{deploymentRevisionAnnotationName: "3"},
+		OwnerReferences: ownerReferences,
+	}}
+
+	k8sClient.Clientset = fake.NewSimpleClientset(newReplicaSet)
+	if rolledBack, err := isRolledBack(deployment); err != nil || rolledBack {
+		t.Errorf("TestIsRolledBack: a new replica set shouldn't be considered as rollback. err: %v", err)
+	}
+
+	reusedReplicaSet := newReplicaSet.DeepCopy()
+	reusedReplicaSet.Annotations[deploymentRevisionHistoryAnnotationName] = "1"
+
+	k8sClient.Clientset = fake.NewSimpleClientset(reusedReplicaSet)
+	if rolledBack, err := isRolledBack(deployment); err != nil || !rolledBack {
+		t.Errorf("TestIsRolledBack: a reused replica set should be considered as rollback. err: %v", err)
+	}
+}
+
+func TestShouldIgnoreDeployment(t *testing.T) {
+	deployment := mockDeployment("1", 1, appsv1.DeploymentStatus{})
+	deployment.Annotations[ignoreAllDeploymentEventsAnnotationName] = "true"
+
+	if shouldWatchDeployment("default/mockDeployment", deployment) {
+		t.Error("TestShouldIgnoreDeployment: mockDeployment should be ignored")
+	}
+
+	namespaceIndexer = mockNamespaceIndexer(map[string]string{ignoreAllDeploymentEventsAnnotationName: "true"})
+	defer func() { namespaceIndexer = nil }()
+
+	namespaceDeployment := mockDeployment("1", 1, appsv1.DeploymentStatus{})
+	namespaceDeployment.Namespace = "payments"
+
+	if shouldWatchDeployment("payments/mockDeployment", namespaceDeployment) {
+		t.Error("TestShouldIgnoreDeployment: deployment in an ignored namespace should be ignored")
+	}
+
+	namespaceDeployment.Annotations[ignoreAllDeploymentEventsAnnotationName] = "false"
+	if !shouldWatchDeployment("payments/mockDeployment", namespaceDeployment) {
+		t.Error("TestShouldIgnoreDeployment: deployment annotation should override the namespace ignore annotation")
+	}
+}
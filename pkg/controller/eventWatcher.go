@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type kubeEvent struct {
+	EventName receivers.EventName
+	EventKey  string
+	EventData *v1.Event
+}
+
+// reportedKubeEvents holds the last time every event was reported, so an event that
+// repeats (its count increases) is reported at most once every EVENT_REPEAT_INTERVAL
+var reportedKubeEvents = struct {
+	sync.Mutex
+	times map[string]time.Time
+}{times: make(map[string]time.Time)}
+
+// newEventController watches the core/v1 Events. the events.k8s.io API serves the same
+// objects from the same storage, so events reported through it are watched as well
+func newEventController() *controller {
+	// create the event watcher
+	eventListWatcher := cache.NewListWatchFromClient(k8sClient.Clientset.CoreV1().RESTClient(), "events", v1.NamespaceAll, fields.Everything())
+
+	// create the workqueue
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "event")
+
+	// Bind the workqueue to a cache with the help of an informer. This way we make sure that
+	// whenever the cache is updated, the event key is added to the workqueue.
+	// a repeating event updates its count, which is queued when the event wasn't reported recently
+	indexer, informer := cache.NewIndexerInformer(eventListWatcher, &v1.Event{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			key, err := cache.MetaNamespaceKeyFunc(obj)
+			k8sEvent := obj.(*v1.Event)
+			if err == nil && shouldWatchKubeEvent(key, k8sEvent) && shouldReportKubeEvent(key, time.Now()) {
+				out, err := json.Marshal(kubeEvent{
+					EventName: receivers.AddEvent,
+					EventKey:  key,
+					EventData: k8sEvent,
+				})
+
+				if err == nil {
+					enqueueEvent(queue, "event", receivers.AddEvent, string(out))
+				}
+			}
+		},
+		UpdateFunc: func(old interface{}, new interface{}) {
+			key, err := cache.MetaNamespaceKeyFunc(new)
+			k8sEvent := new.(*v1.Event)
+			if err == nil && k8sEvent.Count > old.(*v1.Event).Count &&
+				shouldWatchKubeEvent(key, k8sEvent) && shouldReportKubeEvent(key, time.Now()) {
+				out, err := json.Marshal(kubeEvent{
+					EventName: receivers.UpdateEvent,
+					EventKey:  key,
+					EventData: k8sEvent,
+				})
+
+				if err == nil {
+					enqueueEvent(queue, "event", receivers.UpdateEvent, string(out))
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			// events are deleted by their ttl, they won't repeat anymore
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+				reportedKubeEvents.Lock()
+				delete(reportedKubeEvents.times, key)
+				reportedKubeEvents.Unlock()
+			}
+		},
+	}, cache.Indexers{})
+
+	return newController(queue, indexer, informer, kubeEventsHandler, "event")
+}
+
+// kubeEventsHandler is the business logic of the event controller.
+// In case an error happened, it has to simply return the error.
+func kubeEventsHandler(key string, indexer cache.Indexer) error {
+	log.Debug().Msg("running kubeEventsHandler func")
+	event := kubeEvent{}
+	json.Unmarshal([]byte(key), &event)
+
+	k8sEvent := event.EventData
+	if k8sEvent == nil {
+		return nil
+	}
+
+	// the annotations of the involved object decide on the receivers, as they do for the other watchers
+	involvedObject, err := getInvolvedObject(k8sEvent.InvolvedObject)
+	if err != nil {
+		return err
+	}
+
+	receiverEvent := newReceiverEvent(event.EventName, k8sEvent.InvolvedObject.Kind, involvedObject)
+	receiverEvent.Message = buildKubeEventMessage(k8sEvent)
+	receiverEvent.Reason = k8sEvent.Reason
+	receiverEvent.Container = getInvolvedContainer(k8sEvent.InvolvedObject.FieldPath)
+	receiverEvent.AdditionalInfo["event_type"] = k8sEvent.Type
+	receiverEvent.AdditionalInfo["event_count"] = k8sEvent.Count
+	receiverEvent.AdditionalInfo["event_source"] = k8sEvent.Source.Component
+
+	if k8sEvent.Type == v1.EventTypeWarning {
+		receiverEvent.Severity = receivers.WarningSeverity
+	}
+
+	eventReceivers := buildEventReceivers(&receiverEvent)
+	log.Debug().Msg(fmt.Sprintf("found %d event receivers for Event[%s] of %s[%s/%s]. receivers[%s]. reason: %s.",
+		len(eventReceivers), event.EventKey, k8sEvent.InvolvedObject.Kind, k8sEvent.InvolvedObject.Namespace, k8sEvent.InvolvedObject.Name,
+		strings.Join(eventReceivers, ","), k8sEvent.Reason))
+
+	dispatchEvent(receiverEvent, eventReceivers)
+
+	return nil
+}
+
+// getInvolvedObject returns the object the event is about. objects of unsupported kinds and objects
+// that no longer exist (for example an evicted pod) are described by the event reference only
+func getInvolvedObject(reference v1.ObjectReference) (metav1.Object, error) {
+	var object metav1.Object
+	var err error
+
+	getOptions := metav1.GetOptions{}
+	clientset := k8sClient.Clientset
+
+	switch reference.Kind {
+	case "Pod":
+		object, err = clientset.CoreV1().Pods(reference.Namespace).Get(reference.Name, getOptions)
+	case "Node":
+		object, err = clientset.CoreV1().Nodes().Get(reference.Name, getOptions)
+	case "PersistentVolumeClaim":
+		object, err = clientset.CoreV1().PersistentVolumeClaims(reference.Namespace).Get(reference.Name, getOptions)
+	case "Deployment":
+		object, err = clientset.AppsV1().Deployments(reference.Namespace).Get(reference.Name, getOptions)
+	case "ReplicaSet":
+		object, err = clientset.AppsV1().ReplicaSets(reference.Namespace).Get(reference.Name, getOptions)
+	case "StatefulSet":
+		object, err = clientset.AppsV1().StatefulSets(reference.Namespace).Get(reference.Name, getOptions)
+	case "DaemonSet":
+		object, err = clientset.AppsV1().DaemonSets(reference.Namespace).Get(reference.Name, getOptions)
+	case "Job":
+		object, err = clientset.BatchV1().Jobs(reference.Namespace).Get(reference.Name, getOptions)
+	case "HorizontalPodAutoscaler":
+		object, err = clientset.AutoscalingV1().HorizontalPodAutoscalers(reference.Namespace).Get(reference.Name, getOptions)
+	}
+
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if err != nil || object == nil {
+		return &metav1.ObjectMeta{Name: reference.Name, Namespace: reference.Namespace, UID: reference.UID}, nil
+	}
+
+	return object, nil
+}
+
+// getInvolvedContainer returns the container name from a field path like 'spec.containers{app}'
+func getInvolvedContainer(fieldPath string) string {
+	start := strings.Index(fieldPath, "{")
+	end := strings.LastIndex(fieldPath, "}")
+
+	if start == -1 || end <= start {
+		return ""
+	}
+
+	return fieldPath[start+1 : end]
+}
+
+func buildKubeEventMessage(k8sEvent *v1.Event) string {
+	var eventMessage strings.Builder
+	involvedObject := k8sEvent.InvolvedObject
+
+	eventMessage.WriteString(fmt.Sprintf("`%s` event of %s `%s`", k8sEvent.Reason, involvedObject.Kind, involvedObject.Name))
+
+	if involvedObject.Namespace != "" {
+		eventMessage.WriteString(fmt.Sprintf(" in namespace `%s`", involvedObject.Namespace))
+	}
+
+	eventMessage.WriteString(fmt.Sprintf(". Environment:`%s`\n", config.ClusterName()))
+	eventMessage.WriteString(fmt.Sprintf("%s\n", k8sEvent.Message))
+
+	if k8sEvent.Source.Component != "" {
+		eventMessage.WriteString(fmt.Sprintf("Source:`%s`", k8sEvent.Source.Component))
+
+		if k8sEvent.Source.Host != "" {
+			eventMessage.WriteString(fmt.Sprintf(" on `%s`", k8sEvent.Source.Host))
+		}
+
+		eventMessage.WriteString(". ")
+	}
+
+	if k8sEvent.Count > 1 {
+		eventMessage.WriteString(fmt.Sprintf("Count:`%d`", k8sEvent.Count))
+	}
+
+	return strings.TrimSpace(eventMessage.String()) + "\n"
+}
+
+// kubeEventTime returns the last time the event occurred
+func kubeEventTime(k8sEvent *v1.Event) time.Time {
+	if !k8sEvent.LastTimestamp.IsZero() {
+		return k8sEvent.LastTimestamp.Time
+	}
+
+	if !k8sEvent.EventTime.IsZero() {
+		return k8sEvent.EventTime.Time
+	}
+
+	return k8sEvent.CreationTimestamp.Time
+}
+
+// shouldReportKubeEvent returns true when the event wasn't reported within the repeat interval, and records the report
+func shouldReportKubeEvent(eventKey string, now time.Time) bool {
+	reportedKubeEvents.Lock()
+	defer reportedKubeEvents.Unlock()
+
+	if reported, ok := reportedKubeEvents.times[eventKey]; ok && now.Sub(reported) < config.EventRepeatInterval() {
+		log.Debug().Msg(fmt.Sprintf("event-watcher: event [%s] was already reported at %v", eventKey, reported))
+		return false
+	}
+
+	reportedKubeEvents.times[eventKey] = now
+
+	return true
+}
+
+func shouldWatchKubeEvent(eventKey string, k8sEvent *v1.Event) bool {
+	// the initial list contains the events that happened before kubeobserver has started
+	shouldWatch := applicationInitTime.Before(kubeEventTime(k8sEvent)) &&
+		config.ShouldForwardKubeEvent(k8sEvent.Type, k8sEvent.Reason) &&
+		(k8sEvent.InvolvedObject.Namespace == "" || config.ShouldWatchNamespace(k8sEvent.InvolvedObject.Namespace))
+
+	if !shouldWatch {
+		log.Debug().Msg(fmt.Sprintf("event-watcher: ignoring event [%s] with reason %s", eventKey, k8sEvent.Reason))
+	}
+
+	return shouldWatch
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func mockKubeEvent(eventType string, reason string) *v1.Event {
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: "mockPod.16a", Namespace: "default"},
+		InvolvedObject: v1.ObjectReference{
+			Kind:      "Pod",
+			Namespace: "default",
+			Name:      "mockPod",
+			FieldPath: "spec.containers{app}",
+		},
+		Type:          eventType,
+		Reason:        reason,
+		Message:       "0/3 nodes are available: 3 Insufficient cpu.",
+		Source:        v1.EventSource{Component: "default-scheduler"},
+		LastTimestamp: metav1.NewTime(time.Now()),
+	}
+}
+
+func TestNewEventController(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset()
+	eventController := newEventController()
+
+	if eventController == nil {
+		t.Error("TestNewEventController: couldn't create a new event controller")
+	}
+}
+
+func TestShouldWatchKubeEvent(t *testing.T) {
+	if !shouldWatchKubeEvent("default/mockPod.16a", mockKubeEvent(v1.EventTypeWarning, "FailedScheduling")) {
+		t.Error("Warning event with an allowed reason should be watched")
+	}
+
+	if shouldWatchKubeEvent("default/mockPod.16a", mockKubeEvent(v1.EventTypeNormal, "Scheduled")) {
+		t.Error("Normal event shouldn't be watched")
+	}
+
+	if shouldWatchKubeEvent("default/mockPod.16a", mockKubeEvent(v1.EventTypeWarning, "SomeOtherReason")) {
+		t.Error("Warning event with a reason outside of the allowlist shouldn't be watched")
+	}
+
+	oldEvent := mockKubeEvent(v1.EventTypeWarning, "FailedScheduling")
+	oldEvent.LastTimestamp = metav1.NewTime(applicationInitTime.Add(-time.Hour))
+
+	if shouldWatchKubeEvent("default/mockPod.16a", oldEvent) {
+		t.Error("Event from before kubeobserver has started shouldn't be watched")
+	}
+}
+
+func TestShouldReportKubeEvent(t *testing.T) {
+	now := time.Now()
+
+	if !shouldReportKubeEvent("default/mockPod.17b", now) {
+		t.Error("A new event should be reported")
+	}
+
+	if shouldReportKubeEvent("default/mockPod.17b", now.Add(time.Minute)) {
+		t.Error("A repeating event shouldn't be reported again within the repeat interval")
+	}
+
+	if !shouldReportKubeEvent("default/mockPod.17b", now.Add(time.Hour)) {
+		t.Error("A repeating event should be reported again after the repeat interval")
+	}
+}
+
+func TestKubeEventsHandler(t *testing.T) {
+	var count int32
+	receivers.ReceiverMap["countingReceiver"] = countingReceiver{count: &count}
+	defer delete(receivers.ReceiverMap, "countingReceiver")
+
+	// the receivers are resolved from the annotations of the involved pod
+	k8sClient.Clientset = fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "mockPod",
+			Namespace:   "default",
+			Annotations: map[string]string{"kubeobserver.io/receivers": "countingReceiver"},
+		},
+	})
+
+	key, _ := json.Marshal(kubeEvent{EventName: receivers.AddEvent, EventKey: "default/mockPod.16a", EventData: mockKubeEvent(v1.EventTypeWarning, "FailedScheduling")})
+
+	if err := kubeEventsHandler(string(key), nil); err != nil {
+		t.Fatalf("Event handler failed: %v", err)
+	}
+
+	if atomic.LoadInt32(&count) != 1 {
+		t.Error("Event should be sent to the receivers of the involved object")
+	}
+}
+
+func TestGetInvolvedObject(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset()
+
+	object, err := getInvolvedObject(v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "evictedPod"})
+	if err != nil || object.GetName() != "evictedPod" {
+		t.Errorf("Missing involved object should be described by its reference, got %v (%v)", object, err)
+	}
+
+	if container := getInvolvedContainer("spec.containers{app}"); container != "app" {
+		t.Errorf("Expected container 'app', got '%s'", container)
+	}
+}
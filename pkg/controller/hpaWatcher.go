@@ -31,7 +31,7 @@ func newHPAController() *controller {
 
 	// create the workqueue
 	// queue := workqueue.NewDelayingQueue()
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "hpa")
 
 	// Bind the workqueue to a cache with the help of an informer. This way we make sure that
 	// whenever the cache is updated, the hpa key is added to the workqueue.
@@ -47,7 +47,7 @@ func newHPAController() *controller {
 				})
 
 				if err == nil {
-					queue.Add(string(out))
+					enqueueEvent(queue, "hpa", receivers.AddEvent, string(out))
 				}
 			}
 		},
@@ -62,28 +62,41 @@ func newHPAController() *controller {
 				})
 
 				if err == nil {
-					queue.Add(string(out))
+					enqueueEvent(queue, "hpa", receivers.UpdateEvent, string(out))
 				}
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
-			key, err := cache.MetaNamespaceKeyFunc(obj)
+			hpa, ok := obj.(*v2beta1.HorizontalPodAutoscaler)
+			if !ok {
+				// the hpa was deleted while the watch was disconnected
+				tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown)
+				if !isTombstone {
+					return
+				}
+
+				if hpa, ok = tombstone.Obj.(*v2beta1.HorizontalPodAutoscaler); !ok {
+					return
+				}
+			}
+
+			key, err := cache.MetaNamespaceKeyFunc(hpa)
 			if err == nil {
 				out, err := json.Marshal(hpaEvent{
 					EventName:  receivers.DeleteEvent,
 					HpaName:    key,
 					NewHpaData: nil,
-					OldHpaData: obj.(*v2beta1.HorizontalPodAutoscaler),
+					OldHpaData: hpa,
 				})
 
 				if err == nil {
-					queue.Add(string(out))
+					enqueueEvent(queue, "hpa", receivers.DeleteEvent, string(out))
 				}
 			}
 		},
 	}, cache.Indexers{})
 
-	return newController(queue, indexer, informer, hpaEventsHandler, "HorizontalPodAutoscaler")
+	return newController(queue, indexer, informer, hpaEventsHandler, "hpa")
 }
 
 // hpaEventsHandler is the business logic of the hpa controller.
@@ -93,35 +106,42 @@ func hpaEventsHandler(key string, indexer cache.Indexer) error {
 	event := hpaEvent{}
 	json.Unmarshal([]byte(key), &event)
 
-	var eventMessage string
+	var eventReason string
+	additionalInfo := make(map[string]interface{})
+	var hpa *v2beta1.HorizontalPodAutoscaler
 	var hpaAnnotations map[string]string
 	hpaWatchSlackUsersID := make([]string, 0)
 
+	// on delete events only the old hpa data exists
 	if event.NewHpaData != nil {
-		hpaAnnotations = event.NewHpaData.GetObjectMeta().GetAnnotations()
+		hpa = event.NewHpaData
+		hpaAnnotations = withNamespaceAnnotations(hpa.GetNamespace(), hpa.GetObjectMeta().GetAnnotations())
+	} else if event.OldHpaData != nil {
+		hpa = event.OldHpaData
 	}
 
-	eventReceivers := common.BuildEventReceiversList(hpaAnnotations)
-	log.Debug().Msg(fmt.Sprintf("found %d event receivers for HorizontalPodAutoscaler[%s]. receivers[%s]", len(eventReceivers), event.HpaName, strings.Join(eventReceivers, ",")))
+	if hpa != nil && !config.ShouldWatchNamespace(hpa.GetNamespace()) {
+		log.Debug().Msg(fmt.Sprintf("hpa-watcher: ignoring HorizontalPodAutoscaler [%s] event", event.HpaName))
+		return nil
+	}
 
 	switch event.EventName {
-	case "Add":
+	case receivers.AddEvent:
 		if (applicationInitTime).Before(event.NewHpaData.ObjectMeta.CreationTimestamp.Time) {
 			log.Debug().Msg(fmt.Sprintf("handling 'Add' event for HorizontalPodAutoscaler[%s]", event.HpaName))
-			eventMessage = fmt.Sprintf("New HorizontalPodAutoscaler resource [`%s`] added to `%s` cluster", event.HpaName, config.ClusterName())
-			log.Debug().Msg(eventMessage)
+			eventReason = "Created"
 		}
 
-	case "Delete":
+	case receivers.DeleteEvent:
 		log.Debug().Msg(fmt.Sprintf("handling 'Delete' event for HorizontalPodAutoscaler[%s]", event.HpaName))
-		eventMessage = fmt.Sprintf("HorizontalPodAutoscaler resource [`%s`] has deleted from `%s` cluster", event.HpaName, config.ClusterName())
-		log.Debug().Msg(eventMessage)
+		eventReason = "Deleted"
 
 	default:
 		// update hpa event
 		log.Debug().Msg(fmt.Sprintf("handling 'Update' event for HorizontalPodAutoscaler[%s]", event.HpaName))
 
 		var oldHPAStatus, newHPAStatus v2beta1.HorizontalPodAutoscalerStatus
+		var scaleDirection, scalePhase string
 
 		if event.OldHpaData != nil && event.NewHpaData != nil {
 			oldHPAStatus = event.OldHpaData.Status
@@ -137,65 +157,71 @@ func hpaEventsHandler(key string, indexer cache.Indexer) error {
 		// Scale Up Flow
 		if oldHPAStatus.CurrentReplicas < oldHPAStatus.DesiredReplicas {
 			if newHPAStatus.CurrentReplicas < newHPAStatus.DesiredReplicas {
-				eventMessage = fmt.Sprintf("HorizontalPodAutoscaler[`%s`] scale `UP` event progress has updated in `%s` cluster. current-replicas:`%d` desired-replicas:`%d`",
-					event.HpaName, config.ClusterName(), newHPAStatus.CurrentReplicas, newHPAStatus.DesiredReplicas)
-				log.Debug().Msg(eventMessage)
+				scaleDirection, scalePhase = "UP", "progress"
 			}
 
 			if newHPAStatus.CurrentReplicas == newHPAStatus.DesiredReplicas {
-				eventMessage = fmt.Sprintf("HorizontalPodAutoscaler[`%s`] scale `UP` event has finished in `%s` cluster. current-replicas:`%d` desired-replicas:`%d`",
-					event.HpaName, config.ClusterName(), newHPAStatus.CurrentReplicas, newHPAStatus.DesiredReplicas)
-				log.Debug().Msg(eventMessage)
+				scaleDirection, scalePhase = "UP", "finished"
 			}
 		}
 
 		// Scale Down Flow
 		if oldHPAStatus.CurrentReplicas > oldHPAStatus.DesiredReplicas {
 			if newHPAStatus.CurrentReplicas > newHPAStatus.DesiredReplicas {
-				eventMessage = fmt.Sprintf("HorizontalPodAutoscaler[`%s`] scale `DOWN` event progress has updated in `%s` cluster. current-replicas:`%d` desired-replicas:`%d`",
-					event.HpaName, config.ClusterName(), newHPAStatus.CurrentReplicas, newHPAStatus.DesiredReplicas)
-				log.Debug().Msg(eventMessage)
+				scaleDirection, scalePhase = "DOWN", "progress"
 			}
 
 			if newHPAStatus.CurrentReplicas == newHPAStatus.DesiredReplicas {
-				eventMessage = fmt.Sprintf("HorizontalPodAutoscaler[`%s`] scale `DOWN` event has finished in `%s` cluster. current-replicas:`%d` desired-replicas:`%d`",
-					event.HpaName, config.ClusterName(), newHPAStatus.CurrentReplicas, newHPAStatus.DesiredReplicas)
-				log.Debug().Msg(eventMessage)
+				scaleDirection, scalePhase = "DOWN", "finished"
 			}
 		}
 
 		// new HPA event detected, checking both cases - scale UP or sacale DOWN event
-		if eventMessage == "" {
+		if scalePhase == "" {
 			if newHPAStatus.CurrentReplicas > newHPAStatus.DesiredReplicas {
-				eventMessage = fmt.Sprintf("scale `DOWN` event has detected by HorizontalPodAutoscaler [`%s`] in `%s` cluster. starting to `decrease` pod number. current-replicas:`%d` desired-replicas:`%d`",
-					event.HpaName, config.ClusterName(), newHPAStatus.CurrentReplicas, newHPAStatus.DesiredReplicas)
-				log.Debug().Msg(eventMessage)
+				scaleDirection, scalePhase = "DOWN", "detected"
 			}
 
 			if newHPAStatus.CurrentReplicas < newHPAStatus.DesiredReplicas {
-				eventMessage = fmt.Sprintf("scale `UP` event has detected by HorizontalPodAutoscaler[`%s`] in `%s` cluster. starting to `increase` pod number.  current-replicas:`%d` desired-replicas:`%d`",
-					event.HpaName, config.ClusterName(), newHPAStatus.CurrentReplicas, newHPAStatus.DesiredReplicas)
-				log.Debug().Msg(eventMessage)
+				scaleDirection, scalePhase = "UP", "detected"
 			}
 		}
 
+		// there is nothing to notify about when the hpa isn't scaling
+		if scalePhase == "" {
+			return nil
+		}
+
+		log.Debug().Msg(fmt.Sprintf("HorizontalPodAutoscaler[%s] scale %s event %s", event.HpaName, scaleDirection, scalePhase))
+
+		additionalInfo["scale_direction"] = scaleDirection
+		additionalInfo["scale_phase"] = scalePhase
+		additionalInfo["current_replicas"] = newHPAStatus.CurrentReplicas
+		additionalInfo["desired_replicas"] = newHPAStatus.DesiredReplicas
+
+		if newHPAStatus.CurrentReplicas < newHPAStatus.DesiredReplicas || oldHPAStatus.CurrentReplicas < oldHPAStatus.DesiredReplicas {
+			eventReason = "ScaleUp"
+		} else {
+			eventReason = "ScaleDown"
+		}
+
 		if hpaAnnotations != nil && hpaAnnotations[hpaSlackUserIdsAnnotationName] != "" {
 			hpaWatchSlackUsersID = strings.Split(hpaAnnotations[hpaSlackUserIdsAnnotationName], ",")
 		}
 	}
 
-	if eventMessage != "" {
-		additionalInfo := make(map[string]interface{})
-		additionalInfo["pod_watcher_users_ids"] = hpaWatchSlackUsersID
-		additionalInfo[common.PodHpaStringIdentifier()] = true
+	if eventReason != "" {
+		receiverEvent := newReceiverEvent(event.EventName, common.HorizontalPodAutoscalerKind, hpa)
+		receiverEvent.AdditionalInfo = additionalInfo
+		receiverEvent.Reason = eventReason
+		receiverEvent.Mentions = hpaWatchSlackUsersID
+		receiverEvent.Message = receivers.RenderMessage(receiverEvent)
+		log.Debug().Msg(receiverEvent.Message)
 
-		receiverEvent := receivers.ReceiverEvent{
-			EventName:      event.EventName,
-			Message:        eventMessage,
-			AdditionalInfo: additionalInfo,
-		}
+		eventReceivers := buildEventReceivers(&receiverEvent)
+		log.Debug().Msg(fmt.Sprintf("found %d event receivers for HorizontalPodAutoscaler[%s]. receivers[%s]", len(eventReceivers), event.HpaName, strings.Join(eventReceivers, ",")))
 
-		sendEventToReceivers(receiverEvent, eventReceivers)
+		dispatchEvent(receiverEvent, eventReceivers)
 	}
 
 	return nil
@@ -0,0 +1,323 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/common"
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	watchJobAnnotationName            = "job-kubeobserver.io/watch"
+	jobExpectedDurationAnnotationName = "job-watch-kubeobserver.io/expected_duration"
+	jobSlackUserIdsAnnotationName     = "job-watch-kubeobserver.io/slack_users_id"
+
+	// jobControllerUIDIndex indexes the jobs by the UID of the cron job that created them
+	jobControllerUIDIndex = "controllerUID"
+)
+
+// jobsResyncPeriod is how often the watched jobs and cron jobs are checked even when they
+// didn't change, so long runs and missed schedules are detected without waiting for an update
+const jobsResyncPeriod = time.Minute
+
+type jobEvent struct {
+	EventName  receivers.EventName
+	JobName    string
+	NewJobData *batchv1.Job
+	OldJobData *batchv1.Job
+}
+
+// longRunningJobs holds the jobs that were reported for running longer than expected,
+// so a long run is reported once and its completion is reported as well
+var longRunningJobs = struct {
+	sync.Mutex
+	uids map[types.UID]bool
+}{uids: make(map[types.UID]bool)}
+
+// jobsInformer is the jobs cache shared by the job watcher, the cron job watcher and the workloads of the pods,
+// so the jobs of the cluster are listed and watched once. the jobs are indexed by the cron job that created them
+var jobsInformer cache.SharedIndexInformer
+
+// jobsIndexer and jobsCacheSynced are set once the jobs cache is started
+var jobsIndexer cache.Indexer
+var jobsCacheSynced cache.InformerSynced
+
+// getJobsInformer returns the shared jobs informer, which is created on first use
+func getJobsInformer() cache.SharedIndexInformer {
+	if jobsInformer == nil {
+		jobListWatcher := cache.NewListWatchFromClient(k8sClient.Clientset.BatchV1().RESTClient(), "jobs", v1.NamespaceAll, fields.Everything())
+		jobsInformer = cache.NewSharedIndexInformer(jobListWatcher, &batchv1.Job{}, jobsResyncPeriod,
+			cache.Indexers{jobControllerUIDIndex: jobControllerUIDIndexFunc})
+	}
+
+	return jobsInformer
+}
+
+// startJobsInformer runs the shared jobs cache and waits for it to sync
+func startJobsInformer(stopCh chan struct{}) {
+	informer := getJobsInformer()
+
+	jobsIndexer = informer.GetIndexer()
+	jobsCacheSynced = informer.HasSynced
+	go informer.Run(stopCh)
+
+	timeoutCh := make(chan struct{})
+	timer := time.AfterFunc(namespaceCacheSyncTimeout, func() { close(timeoutCh) })
+	defer timer.Stop()
+
+	if !cache.WaitForCacheSync(timeoutCh, informer.HasSynced) {
+		log.Warn().Msg(fmt.Sprintf("jobs cache didn't sync within %v. cron jobs success won't be checked until it does", namespaceCacheSyncTimeout))
+	}
+}
+
+func jobControllerUIDIndexFunc(obj interface{}) ([]string, error) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return nil, nil
+	}
+
+	if owner := metav1.GetControllerOf(job); owner != nil {
+		return []string{string(owner.UID)}, nil
+	}
+
+	return nil, nil
+}
+
+func newJobController() *controller {
+	// create the workqueue
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "job")
+
+	// Bind the workqueue to the shared jobs cache. This way we make sure that
+	// whenever the cache is updated, the job key is added to the workqueue.
+	// the informer resync passes the unchanged jobs as updates every jobsResyncPeriod,
+	// which is what detects the jobs that are running for too long
+	informer := getJobsInformer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old interface{}, new interface{}) {
+			key, err := cache.MetaNamespaceKeyFunc(new)
+			newJob := new.(*batchv1.Job)
+			if err == nil && shouldWatchJob(key, newJob) {
+				out, err := json.Marshal(jobEvent{
+					EventName:  receivers.UpdateEvent,
+					JobName:    key,
+					NewJobData: newJob,
+					OldJobData: old.(*batchv1.Job),
+				})
+
+				if err == nil {
+					enqueueEvent(queue, "job", receivers.UpdateEvent, string(out))
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			job, ok := obj.(*batchv1.Job)
+			if !ok {
+				tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown)
+				if !isTombstone {
+					return
+				}
+
+				if job, ok = tombstone.Obj.(*batchv1.Job); !ok {
+					return
+				}
+			}
+
+			longRunningJobs.Lock()
+			delete(longRunningJobs.uids, job.GetUID())
+			longRunningJobs.Unlock()
+		},
+	})
+
+	// the shared jobs cache is run by startJobsInformer
+	return newController(queue, informer.GetIndexer(), nil, jobEventsHandler, "job")
+}
+
+// jobEventsHandler is the business logic of the job controller.
+// In case an error happened, it has to simply return the error.
+func jobEventsHandler(key string, indexer cache.Indexer) error {
+	log.Debug().Msg("running jobEventsHandler func")
+	event := jobEvent{}
+	json.Unmarshal([]byte(key), &event)
+
+	newJob := event.NewJobData
+	oldJob := event.OldJobData
+
+	if newJob == nil || oldJob == nil {
+		log.Warn().Msg(fmt.Sprintf("Job [%s] old and/or new data is nil. unable to handle 'Update' event", event.JobName))
+		return nil
+	}
+
+	reason, severity := getJobState(oldJob, newJob, time.Now())
+	if reason == "" {
+		return nil
+	}
+
+	jobWatchSlackUsersID := make([]string, 0)
+	if severity != receivers.InfoSeverity && newJob.GetAnnotations()[jobSlackUserIdsAnnotationName] != "" {
+		jobWatchSlackUsersID = strings.Split(newJob.GetAnnotations()[jobSlackUserIdsAnnotationName], ",")
+	}
+
+	receiverEvent := newReceiverEvent(event.EventName, common.JobKind, newJob)
+	receiverEvent.Message = buildJobMessage(event.JobName, reason, newJob)
+	receiverEvent.Reason = reason
+	receiverEvent.Severity = severity
+	receiverEvent.Mentions = jobWatchSlackUsersID
+	receiverEvent.AdditionalInfo["active_pods"] = newJob.Status.Active
+	receiverEvent.AdditionalInfo["succeeded_pods"] = newJob.Status.Succeeded
+	receiverEvent.AdditionalInfo["failed_pods"] = newJob.Status.Failed
+
+	if condition := getJobCondition(newJob, batchv1.JobFailed); condition != nil {
+		receiverEvent.AdditionalInfo["failure_reason"] = condition.Reason
+	}
+
+	eventReceivers := buildEventReceivers(&receiverEvent)
+	log.Debug().Msg(fmt.Sprintf("found %d event receivers for Job[%s]. receivers[%s]. reason: %s.",
+		len(eventReceivers), event.JobName, strings.Join(eventReceivers, ","), reason))
+
+	dispatchEvent(receiverEvent, eventReceivers)
+
+	return nil
+}
+
+// getJobState compares the old and the new job and returns the reason and the severity
+// of the change worth reporting. an empty reason is returned when there is nothing to report
+func getJobState(oldJob *batchv1.Job, newJob *batchv1.Job, now time.Time) (string, receivers.Severity) {
+	longRunningJobs.Lock()
+	defer longRunningJobs.Unlock()
+
+	uid := newJob.GetUID()
+	reportedLongRun := longRunningJobs.uids[uid]
+
+	if isJobFinished(newJob) {
+		delete(longRunningJobs.uids, uid)
+
+		if isJobFinished(oldJob) {
+			return "", receivers.InfoSeverity
+		}
+
+		if getJobCondition(newJob, batchv1.JobFailed) != nil {
+			return "JobFailed", receivers.CriticalSeverity
+		}
+
+		// a completed job is worth a message only when it was reported for running too long
+		if reportedLongRun {
+			return "JobCompleted", receivers.InfoSeverity
+		}
+
+		return "", receivers.InfoSeverity
+	}
+
+	expectedDuration := getDurationAnnotation(newJob.GetAnnotations(), jobExpectedDurationAnnotationName, 0)
+	if reportedLongRun || expectedDuration <= 0 || newJob.Status.StartTime == nil {
+		return "", receivers.InfoSeverity
+	}
+
+	if now.Sub(newJob.Status.StartTime.Time) > expectedDuration {
+		longRunningJobs.uids[uid] = true
+		return "JobRunningTooLong", receivers.WarningSeverity
+	}
+
+	return "", receivers.InfoSeverity
+}
+
+func getJobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		if job.Status.Conditions[i].Type == conditionType && job.Status.Conditions[i].Status == v1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+func isJobFinished(job *batchv1.Job) bool {
+	return getJobCondition(job, batchv1.JobComplete) != nil || getJobCondition(job, batchv1.JobFailed) != nil
+}
+
+// getJobDuration returns the time the job has been running for, or the time it took when it has finished
+func getJobDuration(job *batchv1.Job) time.Duration {
+	if job.Status.StartTime == nil {
+		return 0
+	}
+
+	end := time.Now()
+	if job.Status.CompletionTime != nil {
+		end = job.Status.CompletionTime.Time
+	} else if condition := getJobCondition(job, batchv1.JobFailed); condition != nil {
+		end = condition.LastTransitionTime.Time
+	}
+
+	return end.Sub(job.Status.StartTime.Time).Round(time.Second)
+}
+
+// getDurationAnnotation returns the positive duration value of the annotation (go duration format),
+// or the default value when the annotation is missing or invalid
+func getDurationAnnotation(annotations map[string]string, name string, defaultValue time.Duration) time.Duration {
+	value, ok := annotations[name]
+	if !ok {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Warn().Msg(fmt.Sprintf("invalid value '%s' for annotation %s, using %v", value, name, defaultValue))
+		return defaultValue
+	}
+
+	return duration
+}
+
+func buildJobMessage(jobName string, reason string, job *batchv1.Job) string {
+	var eventMessage strings.Builder
+	duration := getJobDuration(job)
+
+	switch reason {
+	case "JobFailed":
+		condition := getJobCondition(job, batchv1.JobFailed)
+		eventMessage.WriteString(fmt.Sprintf("Job [`%s`] has `Failed` after `%v` in `%s` cluster. Reason:`%s`\n", jobName, duration, config.ClusterName(), condition.Reason))
+
+		if condition.Message != "" {
+			eventMessage.WriteString(fmt.Sprintf("%s\n", condition.Message))
+		}
+	case "JobRunningTooLong":
+		expectedDuration := getDurationAnnotation(job.GetAnnotations(), jobExpectedDurationAnnotationName, 0)
+		eventMessage.WriteString(fmt.Sprintf("Job [`%s`] is running for `%v`, longer than the expected `%v`, in `%s` cluster\n", jobName, duration, expectedDuration, config.ClusterName()))
+	case "JobCompleted":
+		eventMessage.WriteString(fmt.Sprintf("Job [`%s`] has `Completed` after `%v` in `%s` cluster\n", jobName, duration, config.ClusterName()))
+	}
+
+	backoffLimit := int32(6)
+	if job.Spec.BackoffLimit != nil {
+		backoffLimit = *job.Spec.BackoffLimit
+	}
+
+	eventMessage.WriteString(fmt.Sprintf("active-pods:`%d` succeeded-pods:`%d` failed-pods:`%d` backoff-limit:`%d`\n", job.Status.Active, job.Status.Succeeded, job.Status.Failed, backoffLimit))
+
+	return eventMessage.String()
+}
+
+// jobs are opt-in, so only jobs with the watch annotation are watched.
+// jobs of a cron job get the annotations of the cron job template
+func shouldWatchJob(jobNamespaceKey string, job *batchv1.Job) bool {
+	shouldWatch := job.Annotations != nil && job.Annotations[watchJobAnnotationName] == "true" &&
+		config.ShouldWatchNamespace(job.GetNamespace())
+
+	if !shouldWatch {
+		log.Debug().Msg(fmt.Sprintf("job-watcher: ignoring job [%s] event", jobNamespaceKey))
+	}
+
+	return shouldWatch
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func mockJob(uid string, startedAgo time.Duration, conditions ...batchv1.JobCondition) *batchv1.Job {
+	startTime := metav1.NewTime(time.Now().Add(-startedAgo))
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "settlement",
+			Namespace: "default",
+			UID:       types.UID(uid),
+			Annotations: map[string]string{
+				watchJobAnnotationName:            "true",
+				jobExpectedDurationAnnotationName: "30m",
+			},
+		},
+		Status: batchv1.JobStatus{StartTime: &startTime, Conditions: conditions},
+	}
+}
+
+func TestNewJobController(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset()
+	jobController := newJobController()
+
+	if jobController == nil {
+		t.Error("TestNewJobController: couldn't create a new job controller")
+	}
+}
+
+func TestGetJobState(t *testing.T) {
+	failed := batchv1.JobCondition{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "BackoffLimitExceeded"}
+	complete := batchv1.JobCondition{Type: batchv1.JobComplete, Status: v1.ConditionTrue}
+
+	running := mockJob("failing-job", time.Minute)
+	if reason, severity := getJobState(running, mockJob("failing-job", time.Minute, failed), time.Now()); reason != "JobFailed" || severity != receivers.CriticalSeverity {
+		t.Errorf("Failed job wasn't detected, got %s (%s)", reason, severity)
+	}
+
+	if reason, _ := getJobState(mockJob("failing-job", time.Minute, failed), mockJob("failing-job", time.Minute, failed), time.Now()); reason != "" {
+		t.Errorf("Failed job should be reported once, got %s", reason)
+	}
+
+	longRun := mockJob("long-job", time.Hour)
+	if reason, severity := getJobState(longRun, longRun, time.Now()); reason != "JobRunningTooLong" || severity != receivers.WarningSeverity {
+		t.Errorf("Long running job wasn't detected, got %s (%s)", reason, severity)
+	}
+
+	if reason, _ := getJobState(longRun, longRun, time.Now()); reason != "" {
+		t.Errorf("Long running job should be reported once, got %s", reason)
+	}
+
+	if reason, _ := getJobState(longRun, mockJob("long-job", time.Hour, complete), time.Now()); reason != "JobCompleted" {
+		t.Errorf("Completion of a long running job wasn't detected, got %s", reason)
+	}
+
+	if reason, _ := getJobState(running, mockJob("short-job", time.Minute, complete), time.Now()); reason != "" {
+		t.Errorf("Completion of a job that didn't run too long shouldn't be reported, got %s", reason)
+	}
+}
+
+func TestShouldWatchJob(t *testing.T) {
+	job := mockJob("watched-job", time.Minute)
+
+	if !shouldWatchJob("default/settlement", job) {
+		t.Error("Job with the watch annotation should be watched")
+	}
+
+	delete(job.Annotations, watchJobAnnotationName)
+
+	if shouldWatchJob("default/settlement", job) {
+		t.Error("Job without the watch annotation shouldn't be watched")
+	}
+}
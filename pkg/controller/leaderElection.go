@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// isLeader is 1 while this replica should send events to the receivers
+var isLeader int32
+
+// with leader election every replica starts as a follower, since the watchers
+// start handling events before the election acquired the lease
+func init() {
+	setLeader(!config.LeaderElectionEnabled())
+}
+
+// IsLeader returns true when this replica holds the leader election lease or when leader election is disabled
+func IsLeader() bool {
+	return atomic.LoadInt32(&isLeader) == 1
+}
+
+func setLeader(leader bool) {
+	if leader {
+		wasLeader := atomic.SwapInt32(&isLeader, 1) == 1
+		metrics.IsLeader.Set(1)
+
+		// the followers don't write the routes status, so the new leader writes the status of the routes
+		// that were loaded or changed while another replica was the leader
+		if !wasLeader {
+			RevalidateRoutes()
+			receivers.SetLeader(true)
+		}
+	} else {
+		wasLeader := atomic.SwapInt32(&isLeader, 0) == 1
+		metrics.IsLeader.Set(0)
+
+		if wasLeader {
+			receivers.SetLeader(false)
+		}
+	}
+}
+
+// leaderElectionIdentity returns the identity of this replica in the lease.
+// the pod name is taken from POD_NAME (downward api) and falls back to the hostname
+func leaderElectionIdentity() string {
+	if podName := os.Getenv("POD_NAME"); podName != "" {
+		return podName
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		panic(fmt.Sprintf("couldn't get hostname for leader election identity:[%v]", err))
+	}
+
+	return hostname
+}
+
+// newLeaderElector builds a leader elector on top of a coordination.k8s.io Lease.
+// onLeaderChange is called with true when the lease is acquired and with false when it is lost
+func newLeaderElector(clientset kubernetes.Interface, identity string, onLeaderChange func(bool)) (*leaderelection.LeaderElector, error) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: config.LeaderElectionNamespace(),
+			Name:      config.LeaderElectionLeaseName(),
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: config.LeaderElectionLeaseDuration(),
+		RenewDeadline: config.LeaderElectionRenewDeadline(),
+		RetryPeriod:   config.LeaderElectionRetryPeriod(),
+		// give the lease up on shutdown so another replica takes over
+		// right away instead of waiting for the lease to expire
+		ReleaseOnCancel: true,
+		Name:            identity,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Info().Msg(fmt.Sprintf("%s acquired the leader election lease and starts sending events", identity))
+				onLeaderChange(true)
+			},
+			OnStoppedLeading: func() {
+				log.Info().Msg(fmt.Sprintf("%s is not the leader and stops sending events", identity))
+				onLeaderChange(false)
+			},
+			OnNewLeader: func(leaderIdentity string) {
+				log.Info().Msg(fmt.Sprintf("current kubeobserver leader is %s", leaderIdentity))
+			},
+		},
+	})
+}
+
+// RunLeaderElection runs the leader election until the context is done. all replicas keep their
+// informers running so a new leader has synced caches, but only the leader sends events to the receivers.
+// when leader election is disabled this replica is always the leader and the function returns immediately
+func RunLeaderElection(ctx context.Context) {
+	if !config.LeaderElectionEnabled() {
+		setLeader(true)
+		return
+	}
+
+	setLeader(false)
+
+	elector, err := newLeaderElector(k8sClient.Clientset, leaderElectionIdentity(), setLeader)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// a replica that lost the lease goes back to be a candidate
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func waitForLeader(leader *int32, expected int32, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(leader) == expected {
+			return true
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return false
+}
+
+func TestLeaderElection(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	var firstIsLeader, secondIsLeader int32
+
+	newCandidate := func(identity string, leader *int32) context.CancelFunc {
+		elector, err := newLeaderElector(clientset, identity, func(l bool) {
+			if l {
+				atomic.StoreInt32(leader, 1)
+			} else {
+				atomic.StoreInt32(leader, 0)
+			}
+		})
+		if err != nil {
+			t.Fatalf("Can't create leader elector: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go elector.Run(ctx)
+
+		return cancel
+	}
+
+	cancelFirst := newCandidate("kubeobserver-0", &firstIsLeader)
+	if !waitForLeader(&firstIsLeader, 1, 5*time.Second) {
+		t.Fatal("First candidate should acquire the lease")
+	}
+
+	cancelSecond := newCandidate("kubeobserver-1", &secondIsLeader)
+	defer cancelSecond()
+
+	if waitForLeader(&secondIsLeader, 1, 500*time.Millisecond) {
+		t.Fatal("Second candidate shouldn't be the leader while the lease is held")
+	}
+
+	// the lease is released on cancel so the second candidate takes over
+	cancelFirst()
+
+	if !waitForLeader(&firstIsLeader, 0, 5*time.Second) {
+		t.Error("First candidate should stop leading after cancel")
+	}
+
+	if !waitForLeader(&secondIsLeader, 1, 10*time.Second) {
+		t.Error("Second candidate should acquire the released lease")
+	}
+}
+
+type countingReceiver struct {
+	count *int32
+}
+
+func (cr countingReceiver) HandleEvent(r receivers.ReceiverEvent, c chan error) {
+	atomic.AddInt32(cr.count, 1)
+	close(c)
+}
+
+func TestSendEventToReceiversOnlyByLeader(t *testing.T) {
+	defer setLeader(true)
+
+	var count int32
+	receivers.ReceiverMap["countingReceiver"] = countingReceiver{count: &count}
+	defer delete(receivers.ReceiverMap, "countingReceiver")
+
+	setLeader(false)
+	sendEventToReceivers(receivers.ReceiverEvent{EventName: receivers.AddEvent}, []string{"countingReceiver"})
+
+	if atomic.LoadInt32(&count) != 0 {
+		t.Error("Events shouldn't be sent to receivers when the replica isn't the leader")
+	}
+
+	setLeader(true)
+	sendEventToReceivers(receivers.ReceiverEvent{EventName: receivers.AddEvent}, []string{"countingReceiver"})
+
+	if atomic.LoadInt32(&count) != 1 {
+		t.Error("Events should be sent to receivers by the leader")
+	}
+}
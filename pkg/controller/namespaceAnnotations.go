@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceCacheSyncTimeout is how long the watchers wait for the namespaces cache before they start.
+// without permissions on namespaces the cache never syncs and the namespace defaults are not used
+const namespaceCacheSyncTimeout = 30 * time.Second
+
+// namespaceIndexer caches the namespaces, so the namespace annotations are resolved
+// without a request to the api server per event
+var namespaceIndexer cache.Indexer
+
+// startNamespaceInformer runs the namespaces cache and waits for it to sync
+func startNamespaceInformer(stopCh chan struct{}) {
+	namespaceListWatcher := cache.NewListWatchFromClient(k8sClient.Clientset.CoreV1().RESTClient(), "namespaces", v1.NamespaceAll, fields.Everything())
+	indexer, informer := cache.NewIndexerInformer(namespaceListWatcher, &v1.Namespace{}, 0, cache.ResourceEventHandlerFuncs{}, cache.Indexers{})
+
+	namespaceIndexer = indexer
+	go informer.Run(stopCh)
+
+	timeoutCh := make(chan struct{})
+	timer := time.AfterFunc(namespaceCacheSyncTimeout, func() { close(timeoutCh) })
+	defer timer.Stop()
+
+	if !cache.WaitForCacheSync(timeoutCh, informer.HasSynced) {
+		log.Warn().Msg(fmt.Sprintf("namespaces cache didn't sync within %v. namespace annotations won't be used until it does", namespaceCacheSyncTimeout))
+	}
+}
+
+// getNamespaceAnnotations returns the annotations of the namespace from the namespaces cache
+func getNamespaceAnnotations(namespace string) map[string]string {
+	if namespaceIndexer == nil || namespace == "" {
+		return nil
+	}
+
+	obj, exists, err := namespaceIndexer.GetByKey(namespace)
+	if err != nil || !exists {
+		return nil
+	}
+
+	return obj.(*v1.Namespace).GetAnnotations()
+}
+
+// withNamespaceAnnotations returns the annotations of a resource on top of the kubeobserver annotations
+// of its namespace, which act as defaults for all the resources in the namespace.
+// the resource annotations always take precedence
+func withNamespaceAnnotations(namespace string, annotations map[string]string) map[string]string {
+	namespaceAnnotations := getNamespaceAnnotations(namespace)
+	if len(namespaceAnnotations) == 0 {
+		return annotations
+	}
+
+	merged := make(map[string]string)
+
+	for k, v := range namespaceAnnotations {
+		if strings.Contains(k, "kubeobserver.io/") {
+			merged[k] = v
+		}
+	}
+
+	for k, v := range annotations {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// workloadIndexers are the caches of the watchers that hold the workloads of the pods, by kind. the workloads
+// of the other kinds, or of disabled watchers, are fetched when needed and kept for workloadCacheTTL
+var workloadIndexers = make(map[string]cache.Indexer)
+
+// workloadCacheTTL is how long a fetched workload is used before it is fetched again
+const workloadCacheTTL = time.Minute
+
+// fetchedWorkloads holds the annotations of the fetched workloads by kind/namespace/name
+var fetchedWorkloads = struct {
+	sync.Mutex
+	workloads map[string]fetchedWorkload
+}{workloads: make(map[string]fetchedWorkload)}
+
+type fetchedWorkload struct {
+	annotations map[string]string
+	expiration  time.Time
+}
+
+// getWorkloadAnnotations returns the annotations of the workload from the cache of its watcher,
+// or from the api server when the watcher doesn't hold it
+func getWorkloadAnnotations(kind string, namespace string, name string) (map[string]string, error) {
+	if indexer, ok := workloadIndexers[kind]; ok {
+		obj, exists, err := indexer.GetByKey(namespace + "/" + name)
+		if err == nil && exists {
+			return obj.(metav1.Object).GetAnnotations(), nil
+		}
+	}
+
+	key := kind + "/" + namespace + "/" + name
+	now := time.Now()
+
+	fetchedWorkloads.Lock()
+	workload, ok := fetchedWorkloads.workloads[key]
+	fetchedWorkloads.Unlock()
+
+	if ok && now.Before(workload.expiration) {
+		return workload.annotations, nil
+	}
+
+	object, err := getInvolvedObject(v1.ObjectReference{Kind: kind, Namespace: namespace, Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	fetchedWorkloads.Lock()
+	defer fetchedWorkloads.Unlock()
+
+	for k, w := range fetchedWorkloads.workloads {
+		if now.After(w.expiration) {
+			delete(fetchedWorkloads.workloads, k)
+		}
+	}
+
+	fetchedWorkloads.workloads[key] = fetchedWorkload{annotations: object.GetAnnotations(), expiration: now.Add(workloadCacheTTL)}
+
+	return object.GetAnnotations(), nil
+}
+
+// withWorkloadSlackChannels sets the slack channels annotation of the workload of a pod on its event, when the
+// pod itself has none. the workload annotation takes precedence over the one of the namespace
+func withWorkloadSlackChannels(receiverEvent *receivers.ReceiverEvent, pod *v1.Pod) {
+	if _, ok := pod.GetAnnotations()[receivers.SlackChannelsAnnotationName]; ok {
+		return
+	}
+
+	workload := receiverEvent.Workload()
+	if workload.Kind == "" {
+		return
+	}
+
+	annotations, err := getWorkloadAnnotations(workload.Kind, receiverEvent.Namespace, workload.Name)
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("couldn't get %s %s/%s of pod %s: %s", workload.Kind, receiverEvent.Namespace, workload.Name, receiverEvent.Name, err.Error()))
+		return
+	}
+
+	channels, ok := annotations[receivers.SlackChannelsAnnotationName]
+	if !ok {
+		return
+	}
+
+	// the annotations of the event may be the ones of the informer cache, which must not be changed
+	eventAnnotations := map[string]string{receivers.SlackChannelsAnnotationName: channels}
+	for k, v := range receiverEvent.Annotations {
+		if k != receivers.SlackChannelsAnnotationName {
+			eventAnnotations[k] = v
+		}
+	}
+
+	receiverEvent.Annotations = eventAnnotations
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/PayU/kubeobserver/pkg/common"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func mockNamespaceIndexer(annotations map[string]string) cache.Indexer {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Annotations: annotations}})
+
+	return indexer
+}
+
+func TestWithNamespaceAnnotations(t *testing.T) {
+	namespaceIndexer = mockNamespaceIndexer(map[string]string{
+		"kubeobserver.io/receivers":              "slack,webhook",
+		watchPodUpdateAnnotationName:             "true",
+		"scheduler.alpha.kubernetes.io/defaults": "ignored",
+	})
+	defer func() { namespaceIndexer = nil }()
+
+	annotations := withNamespaceAnnotations("payments", map[string]string{watchPodUpdateAnnotationName: "false"})
+
+	if annotations["kubeobserver.io/receivers"] != "slack,webhook" {
+		t.Error("Namespace receivers should be inherited by the resource")
+	}
+
+	if annotations[watchPodUpdateAnnotationName] != "false" {
+		t.Error("Resource annotations should override the namespace annotations")
+	}
+
+	if _, ok := annotations["scheduler.alpha.kubernetes.io/defaults"]; ok {
+		t.Error("Only kubeobserver annotations should be inherited from the namespace")
+	}
+
+	if annotations := withNamespaceAnnotations("default", nil); len(annotations) != 0 {
+		t.Errorf("Resources of an unknown namespace shouldn't inherit annotations, got %v", annotations)
+	}
+}
+
+func TestNamespaceAnnotationsDefaults(t *testing.T) {
+	namespaceIndexer = mockNamespaceIndexer(map[string]string{
+		"kubeobserver.io/receivers":      "webhook",
+		ignoreAllPodEventsAnnotationName: "true",
+	})
+	defer func() { namespaceIndexer = nil }()
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "payments"}}
+
+	if shouldWatchPod("payments/checkout", pod) {
+		t.Error("Pod in an ignored namespace shouldn't be watched")
+	}
+
+	pod.Annotations = map[string]string{ignoreAllPodEventsAnnotationName: "false"}
+
+	if !shouldWatchPod("payments/checkout", pod) {
+		t.Error("Pod annotation should override the namespace ignore annotation")
+	}
+
+	receiverEvent := newReceiverEvent(receivers.AddEvent, common.PodKind, pod)
+	if eventReceivers := buildEventReceivers(&receiverEvent); len(eventReceivers) != 1 || eventReceivers[0] != "webhook" {
+		t.Errorf("Expected the namespace receivers, got %v", eventReceivers)
+	}
+}
+
+func TestWithWorkloadSlackChannels(t *testing.T) {
+	defer func(original map[string]cache.Indexer) { workloadIndexers = original }(workloadIndexers)
+	defer func() { fetchedWorkloads.workloads = make(map[string]fetchedWorkload) }()
+
+	deploymentIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	deploymentIndexer.Add(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "payments",
+		Annotations: map[string]string{receivers.SlackChannelsAnnotationName: "#checkout-alerts"}}})
+	workloadIndexers = map[string]cache.Indexer{"Deployment": deploymentIndexer}
+
+	podAnnotations := map[string]string{"kubeobserver.io/receivers": "slack"}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "checkout-5d8f7-x2x1", Namespace: "payments", Annotations: podAnnotations}}
+	event := receivers.ReceiverEvent{Kind: "Pod", Namespace: "payments", Name: pod.Name, Annotations: podAnnotations,
+		Owner: receivers.Owner{Kind: "ReplicaSet", Name: "checkout-5d8f7"}, Labels: map[string]string{"pod-template-hash": "5d8f7"}}
+
+	withWorkloadSlackChannels(&event, pod)
+	if event.Annotations[receivers.SlackChannelsAnnotationName] != "#checkout-alerts" || event.Annotations["kubeobserver.io/receivers"] != "slack" {
+		t.Errorf("Pod should get the slack channels of its deployment, got %v", event.Annotations)
+	}
+
+	if _, ok := podAnnotations[receivers.SlackChannelsAnnotationName]; ok {
+		t.Error("The annotations of the pod shouldn't be changed")
+	}
+
+	pod.Annotations = map[string]string{receivers.SlackChannelsAnnotationName: "#pod-alerts"}
+	event.Annotations = pod.Annotations
+	withWorkloadSlackChannels(&event, pod)
+	if event.Annotations[receivers.SlackChannelsAnnotationName] != "#pod-alerts" {
+		t.Errorf("Pod slack channels should take precedence over the workload ones, got %v", event.Annotations)
+	}
+
+	k8sClient.Clientset = fake.NewSimpleClientset(&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "ledger", Namespace: "payments",
+		Annotations: map[string]string{receivers.SlackChannelsAnnotationName: "#ledger-alerts"}}})
+
+	statefulSetEvent := receivers.ReceiverEvent{Kind: "Pod", Namespace: "payments", Name: "ledger-0", Owner: receivers.Owner{Kind: "StatefulSet", Name: "ledger"}}
+	withWorkloadSlackChannels(&statefulSetEvent, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ledger-0", Namespace: "payments"}})
+	if statefulSetEvent.Annotations[receivers.SlackChannelsAnnotationName] != "#ledger-alerts" {
+		t.Errorf("Pod should get the slack channels of its stateful set, got %v", statefulSetEvent.Annotations)
+	}
+
+	// the fetched workload is kept, so the next events of its pods don't fetch it again
+	k8sClient.Clientset = fake.NewSimpleClientset()
+	statefulSetEvent.Annotations = nil
+	withWorkloadSlackChannels(&statefulSetEvent, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ledger-0", Namespace: "payments"}})
+	if statefulSetEvent.Annotations[receivers.SlackChannelsAnnotationName] != "#ledger-alerts" {
+		t.Errorf("Stateful set should be taken from the fetched workloads, got %v", statefulSetEvent.Annotations)
+	}
+
+	orphan := receivers.ReceiverEvent{Kind: "Pod", Namespace: "payments", Name: "debug", Annotations: map[string]string{receivers.SlackChannelsAnnotationName: "#namespace-alerts"}}
+	withWorkloadSlackChannels(&orphan, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "payments"}})
+	if orphan.Annotations[receivers.SlackChannelsAnnotationName] != "#namespace-alerts" {
+		t.Errorf("Pod without a workload should keep the namespace slack channels, got %v", orphan.Annotations)
+	}
+}
@@ -0,0 +1,296 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/PayU/kubeobserver/pkg/common"
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	ignoreAllNodeEventsAnnotationName = "node-kubeobserver.io/ignore"
+	nodeSlackUserIdsAnnotationName    = "node-watch-kubeobserver.io/slack_users_id"
+
+	// taints with this prefix are added by the node lifecycle controller to reflect the node
+	// conditions and cordon, which are already reported on their own
+	nodeConditionTaintPrefix = "node.kubernetes.io/"
+)
+
+// nodeChange is a single change between the old and the new node
+type nodeChange struct {
+	reason   string
+	severity receivers.Severity
+	message  string
+}
+
+// nodePressureConditions are the node conditions that are reported when they become true
+var nodePressureConditions = []v1.NodeConditionType{v1.NodeMemoryPressure, v1.NodeDiskPressure, v1.NodePIDPressure}
+
+type nodeEvent struct {
+	EventName   receivers.EventName
+	NodeName    string
+	NewNodeData *v1.Node
+	OldNodeData *v1.Node
+}
+
+func newNodeController() *controller {
+	// create the node watcher
+	nodeListWatcher := cache.NewListWatchFromClient(k8sClient.Clientset.CoreV1().RESTClient(), "nodes", v1.NamespaceAll, fields.Everything())
+
+	// create the workqueue
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "node")
+
+	// Bind the workqueue to a cache with the help of an informer. This way we make sure that
+	// whenever the cache is updated, the node key is added to the workqueue.
+	// nodes are reported when their status changes or when they are removed, so add events
+	// (including the initial list) are not queued
+	indexer, informer := cache.NewIndexerInformer(nodeListWatcher, &v1.Node{}, 0, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old interface{}, new interface{}) {
+			key, err := cache.MetaNamespaceKeyFunc(new)
+			newNode := new.(*v1.Node)
+			if err == nil && shouldWatchNode(key, newNode) {
+				out, err := json.Marshal(nodeEvent{
+					EventName:   receivers.UpdateEvent,
+					NodeName:    key,
+					NewNodeData: newNode,
+					OldNodeData: old.(*v1.Node),
+				})
+
+				if err == nil {
+					enqueueEvent(queue, "node", receivers.UpdateEvent, string(out))
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			node, ok := obj.(*v1.Node)
+			if !ok {
+				// the node was deleted while the watch was disconnected
+				tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown)
+				if !isTombstone {
+					return
+				}
+
+				if node, ok = tombstone.Obj.(*v1.Node); !ok {
+					return
+				}
+			}
+
+			key, err := cache.MetaNamespaceKeyFunc(node)
+			if err == nil && shouldWatchNode(key, node) {
+				out, err := json.Marshal(nodeEvent{
+					EventName:   receivers.DeleteEvent,
+					NodeName:    key,
+					NewNodeData: nil,
+					OldNodeData: node,
+				})
+
+				if err == nil {
+					enqueueEvent(queue, "node", receivers.DeleteEvent, string(out))
+				}
+			}
+		},
+	}, cache.Indexers{})
+
+	return newController(queue, indexer, informer, nodeEventsHandler, "node")
+}
+
+// nodeEventsHandler is the business logic of the node controller.
+// In case an error happened, it has to simply return the error.
+func nodeEventsHandler(key string, indexer cache.Indexer) error {
+	log.Debug().Msg("running nodeEventsHandler func")
+	event := nodeEvent{}
+	json.Unmarshal([]byte(key), &event)
+
+	var node *v1.Node
+	var changes []nodeChange
+
+	switch event.EventName {
+	case receivers.DeleteEvent:
+		node = event.OldNodeData
+		changes = []nodeChange{{reason: "Deleted", severity: receivers.WarningSeverity, message: "the node has been removed from the cluster"}}
+	default:
+		if event.NewNodeData == nil || event.OldNodeData == nil {
+			log.Warn().Msg(fmt.Sprintf("Node [%s] old and/or new data is nil. unable to handle 'Update' event", event.NodeName))
+			return nil
+		}
+
+		node = event.NewNodeData
+		changes = getNodeChanges(event.OldNodeData, event.NewNodeData)
+	}
+
+	if node == nil || len(changes) == 0 {
+		return nil
+	}
+
+	// pods that are still running are the ones about to be evicted, or already lost
+	runningPods, err := countRunningPods(node.GetName())
+	if err != nil {
+		return err
+	}
+
+	nodeWatchSlackUsersID := make([]string, 0)
+	if slackUsersID := node.GetAnnotations()[nodeSlackUserIdsAnnotationName]; slackUsersID != "" {
+		nodeWatchSlackUsersID = strings.Split(slackUsersID, ",")
+	}
+
+	receiverEvent := newReceiverEvent(event.EventName, common.NodeKind, node)
+	receiverEvent.Message = buildNodeMessage(event.NodeName, runningPods, changes)
+	receiverEvent.Reason = changes[0].reason
+	receiverEvent.Severity = changes[0].severity
+	receiverEvent.Mentions = nodeWatchSlackUsersID
+	receiverEvent.AdditionalInfo["running_pods"] = runningPods
+
+	// the event reason is the most severe change
+	for _, change := range changes[1:] {
+		if severityRank(change.severity) > severityRank(receiverEvent.Severity) {
+			receiverEvent.Reason = change.reason
+			receiverEvent.Severity = change.severity
+		}
+	}
+
+	eventReceivers := buildEventReceivers(&receiverEvent)
+	log.Debug().Msg(fmt.Sprintf("found %d event receivers for Node[%s]. receivers[%s]. reason: %s.",
+		len(eventReceivers), event.NodeName, strings.Join(eventReceivers, ","), receiverEvent.Reason))
+
+	dispatchEvent(receiverEvent, eventReceivers)
+
+	return nil
+}
+
+// getNodeChanges returns the changes worth reporting between the old and the new node:
+// readiness, pressure conditions, cordon and taints
+func getNodeChanges(oldNode *v1.Node, newNode *v1.Node) []nodeChange {
+	changes := make([]nodeChange, 0)
+
+	oldReady := getNodeCondition(oldNode, v1.NodeReady)
+	newReady := getNodeCondition(newNode, v1.NodeReady)
+
+	if newReady != nil && (oldReady == nil || oldReady.Status != newReady.Status) {
+		switch newReady.Status {
+		case v1.ConditionFalse:
+			changes = append(changes, nodeChange{"NodeNotReady", receivers.CriticalSeverity, fmt.Sprintf("the node is `NotReady`: %s", newReady.Message)})
+		case v1.ConditionUnknown:
+			changes = append(changes, nodeChange{"NodeUnknown", receivers.CriticalSeverity, fmt.Sprintf("the node status is `Unknown`: %s", newReady.Message)})
+		case v1.ConditionTrue:
+			// a node that joined the cluster is not ready at first, it is not worth a recovery message
+			if oldReady != nil {
+				changes = append(changes, nodeChange{"NodeReady", receivers.InfoSeverity, "the node is `Ready` again"})
+			}
+		}
+	}
+
+	for _, conditionType := range nodePressureConditions {
+		oldCondition := getNodeCondition(oldNode, conditionType)
+		newCondition := getNodeCondition(newNode, conditionType)
+		wasTrue := oldCondition != nil && oldCondition.Status == v1.ConditionTrue
+		isTrue := newCondition != nil && newCondition.Status == v1.ConditionTrue
+
+		if isTrue && !wasTrue {
+			changes = append(changes, nodeChange{string(conditionType), receivers.WarningSeverity, fmt.Sprintf("the node has `%s`: %s", conditionType, newCondition.Message)})
+		} else if wasTrue && !isTrue {
+			changes = append(changes, nodeChange{string(conditionType) + "Resolved", receivers.InfoSeverity, fmt.Sprintf("the node has no `%s` anymore", conditionType)})
+		}
+	}
+
+	if newNode.Spec.Unschedulable && !oldNode.Spec.Unschedulable {
+		changes = append(changes, nodeChange{"NodeCordoned", receivers.WarningSeverity, "the node has been `cordoned`, new pods won't be scheduled on it"})
+	} else if !newNode.Spec.Unschedulable && oldNode.Spec.Unschedulable {
+		changes = append(changes, nodeChange{"NodeUncordoned", receivers.InfoSeverity, "the node has been `uncordoned`"})
+	}
+
+	if added := getAddedTaints(oldNode.Spec.Taints, newNode.Spec.Taints); len(added) > 0 {
+		changes = append(changes, nodeChange{"NodeTainted", receivers.WarningSeverity, fmt.Sprintf("the node has been tainted with `%s`", strings.Join(added, "`, `"))})
+	}
+
+	if removed := getAddedTaints(newNode.Spec.Taints, oldNode.Spec.Taints); len(removed) > 0 {
+		changes = append(changes, nodeChange{"NodeUntainted", receivers.InfoSeverity, fmt.Sprintf("the taints `%s` have been removed from the node", strings.Join(removed, "`, `"))})
+	}
+
+	return changes
+}
+
+func getNodeCondition(node *v1.Node, conditionType v1.NodeConditionType) *v1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == conditionType {
+			return &node.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// getAddedTaints returns the taints that exist in the new taints and not in the old ones.
+// the taints that mirror the node conditions are skipped
+func getAddedTaints(oldTaints []v1.Taint, newTaints []v1.Taint) []string {
+	added := make([]string, 0)
+
+	for _, newTaint := range newTaints {
+		if strings.HasPrefix(newTaint.Key, nodeConditionTaintPrefix) {
+			continue
+		}
+
+		exists := false
+		for i := range oldTaints {
+			if oldTaints[i].MatchTaint(&newTaint) {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			added = append(added, newTaint.ToString())
+		}
+	}
+
+	return added
+}
+
+// countRunningPods returns the number of pods on the node that have not finished
+func countRunningPods(nodeName string) (int, error) {
+	pods, err := k8sClient.Clientset.CoreV1().Pods(v1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == nodeName && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func buildNodeMessage(nodeName string, runningPods int, changes []nodeChange) string {
+	var eventMessage strings.Builder
+
+	eventMessage.WriteString(fmt.Sprintf("The node `%s` in `%s` cluster has changed. Running pods:`%d`. Updates:\n", nodeName, config.ClusterName(), runningPods))
+	for _, change := range changes {
+		eventMessage.WriteString(fmt.Sprintf("- %s\n", change.message))
+	}
+
+	return eventMessage.String()
+}
+
+func shouldWatchNode(nodeKey string, node *v1.Node) bool {
+	shouldWatch := node.Annotations == nil || node.Annotations[ignoreAllNodeEventsAnnotationName] != "true"
+
+	if !shouldWatch {
+		log.Debug().Msg(fmt.Sprintf("node-watcher: ignoring node [%s] event", nodeKey))
+	}
+
+	return shouldWatch
+}
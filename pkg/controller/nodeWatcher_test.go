@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func mockNode(ready v1.ConditionStatus, memoryPressure v1.ConditionStatus, unschedulable bool, taints ...v1.Taint) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "mockNode"},
+		Spec:       v1.NodeSpec{Unschedulable: unschedulable, Taints: taints},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: ready, Message: "kubelet stopped posting node status"},
+				{Type: v1.NodeMemoryPressure, Status: memoryPressure},
+			},
+		},
+	}
+}
+
+func TestNewNodeController(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset()
+	nodeController := newNodeController()
+
+	if nodeController == nil {
+		t.Error("TestNewNodeController: couldn't create a new node controller")
+	}
+}
+
+func TestGetNodeChanges(t *testing.T) {
+	healthy := mockNode(v1.ConditionTrue, v1.ConditionFalse, false)
+
+	changes := getNodeChanges(healthy, mockNode(v1.ConditionUnknown, v1.ConditionTrue, false))
+	if len(changes) != 2 || changes[0].reason != "NodeUnknown" || changes[0].severity != receivers.CriticalSeverity || changes[1].reason != "MemoryPressure" {
+		t.Errorf("Unknown node with memory pressure wasn't detected properly: %+v", changes)
+	}
+
+	changes = getNodeChanges(mockNode(v1.ConditionFalse, v1.ConditionFalse, false), healthy)
+	if len(changes) != 1 || changes[0].reason != "NodeReady" {
+		t.Errorf("Node recovery wasn't detected properly: %+v", changes)
+	}
+
+	// cordon adds the unschedulable taint, which is reported as a cordon only
+	cordoned := mockNode(v1.ConditionTrue, v1.ConditionFalse, true,
+		v1.Taint{Key: "node.kubernetes.io/unschedulable", Effect: v1.TaintEffectNoSchedule},
+		v1.Taint{Key: "ToBeDeletedByClusterAutoscaler", Value: "1600000000", Effect: v1.TaintEffectNoSchedule})
+
+	changes = getNodeChanges(healthy, cordoned)
+	if len(changes) != 2 || changes[0].reason != "NodeCordoned" || changes[1].reason != "NodeTainted" {
+		t.Errorf("Cordon and taint weren't detected properly: %+v", changes)
+	}
+
+	if changes := getNodeChanges(healthy, healthy); len(changes) != 0 {
+		t.Errorf("Unchanged node shouldn't be reported: %+v", changes)
+	}
+}
+
+func TestCountRunningPods(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset(
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "mockNode"}, Status: v1.PodStatus{Phase: v1.PodRunning}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "completed", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "mockNode"}, Status: v1.PodStatus{Phase: v1.PodSucceeded}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "otherNode"}, Status: v1.PodStatus{Phase: v1.PodRunning}},
+	)
+
+	count, err := countRunningPods("mockNode")
+	if err != nil || count != 1 {
+		t.Errorf("Expected a single running pod on the node, got %d (%v)", count, err)
+	}
+}
+
+func TestShouldWatchNode(t *testing.T) {
+	node := mockNode(v1.ConditionTrue, v1.ConditionFalse, false)
+
+	if !shouldWatchNode("mockNode", node) {
+		t.Error("Node should be watched by default")
+	}
+
+	node.Annotations = map[string]string{ignoreAllNodeEventsAnnotationName: "true"}
+	if shouldWatchNode("mockNode", node) {
+		t.Error("Node with ignore annotation shouldn't be watched")
+	}
+}
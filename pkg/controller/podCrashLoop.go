@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultCrashLogsLines int64 = 20
+	defaultCrashLogsBytes int64 = 2048
+)
+
+// getPreviousContainerLogs returns the last lines of the logs of the previous instance of a container.
+// it is a variable so tests can replace the k8s api call
+var getPreviousContainerLogs = func(namespace string, podName string, containerName string, lines int64, limitBytes int64) (string, error) {
+	logs, err := k8sClient.Clientset.CoreV1().Pods(namespace).GetLogs(podName, &v1.PodLogOptions{
+		Container:  containerName,
+		Previous:   true,
+		TailLines:  &lines,
+		LimitBytes: &limitBytes,
+	}).DoRaw()
+
+	return string(logs), err
+}
+
+// enrichCrashLoopEvent attaches the last termination details and the last log lines of
+// the crashing container to the event. the logs can be turned off and limited by pod annotations
+func enrichCrashLoopEvent(receiverEvent *receivers.ReceiverEvent, pod *v1.Pod) {
+	containerStatus := findContainerStatus(pod, receiverEvent.Container)
+	if containerStatus == nil {
+		return
+	}
+
+	if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil {
+		receiverEvent.LastTermination = &receivers.ContainerTermination{
+			ExitCode:   terminated.ExitCode,
+			Signal:     terminated.Signal,
+			Reason:     terminated.Reason,
+			Message:    terminated.Message,
+			OOMKilled:  terminated.Reason == "OOMKilled",
+			StartedAt:  terminated.StartedAt.Time,
+			FinishedAt: terminated.FinishedAt.Time,
+		}
+	}
+
+	annotations := withNamespaceAnnotations(pod.GetNamespace(), pod.GetAnnotations())
+	if annotations[podCrashLogsAnnotationName] == "false" {
+		return
+	}
+
+	lines := getInt64Annotation(annotations, podCrashLogsLinesAnnotationName, defaultCrashLogsLines)
+	limitBytes := getInt64Annotation(annotations, podCrashLogsBytesAnnotationName, defaultCrashLogsBytes)
+
+	logs, err := getPreviousContainerLogs(pod.GetNamespace(), pod.GetName(), containerStatus.Name, lines, limitBytes)
+	if err != nil {
+		// the event is still worth sending without the logs
+		log.Warn().Msg(fmt.Sprintf("couldn't get the previous logs of container %s in pod %s/%s: %s", containerStatus.Name, pod.GetNamespace(), pod.GetName(), err))
+		return
+	}
+
+	// the api server limit is applied on the whole log stream, make sure the tail respects it as well
+	if int64(len(logs)) > limitBytes {
+		logs = logs[int64(len(logs))-limitBytes:]
+	}
+
+	receiverEvent.ContainerLogs = logs
+}
+
+// findContainerStatus returns the status of the container (or init container) with the given name
+func findContainerStatus(pod *v1.Pod, containerName string) *v1.ContainerStatus {
+	statuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+
+	for i := range statuses {
+		if statuses[i].Name == containerName {
+			return &statuses[i]
+		}
+	}
+
+	return nil
+}
+
+// getInt64Annotation returns the positive int value of the annotation, or the default value
+// when the annotation is missing or invalid
+func getInt64Annotation(annotations map[string]string, name string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(annotations[name], 10, 64)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
@@ -20,6 +20,9 @@ const (
 	watchPodUpdateAnnotationName        = "pod-update-kubeobserver.io/watch"
 	watchPodInitcontainersAnnotationName = "pod-init-container-kubeobserver.io/watch"
 	podSlackUserIdsAnnotationName        = "pod-watch-kubeobserver.io/slack_users_id"
+	podCrashLogsAnnotationName           = "pod-watch-kubeobserver.io/crash_logs"
+	podCrashLogsLinesAnnotationName      = "pod-watch-kubeobserver.io/crash_logs_lines"
+	podCrashLogsBytesAnnotationName      = "pod-watch-kubeobserver.io/crash_logs_bytes"
 )
 
 var podController *controller
@@ -37,7 +40,7 @@ func newPodController() *controller {
 	podListWatcher := cache.NewListWatchFromClient(k8sClient.Clientset.CoreV1().RESTClient(), "pods", v1.NamespaceAll, fields.Everything())
 
 	// create the workqueue
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "pod")
 
 	// Bind the workqueue to a cache with the help of an informer. This way we make sure that
 	// whenever the cache is updated, the pod key is added to the workqueue.
@@ -54,7 +57,7 @@ func newPodController() *controller {
 				})
 
 				if err == nil {
-					queue.Add(string(out))
+					enqueueEvent(queue, "pod", receivers.AddEvent, string(out))
 				}
 			}
 		},
@@ -70,23 +73,35 @@ func newPodController() *controller {
 				})
 
 				if err == nil {
-					queue.Add(string(out))
+					enqueueEvent(queue, "pod", receivers.UpdateEvent, string(out))
 				}
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
-			key, err := cache.MetaNamespaceKeyFunc(obj)
-			pod := obj.(*v1.Pod)
+			pod, ok := obj.(*v1.Pod)
+			if !ok {
+				// the pod was deleted while the watch was disconnected
+				tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown)
+				if !isTombstone {
+					return
+				}
+
+				if pod, ok = tombstone.Obj.(*v1.Pod); !ok {
+					return
+				}
+			}
+
+			key, err := cache.MetaNamespaceKeyFunc(pod)
 			if err == nil && shouldWatchPod(key, pod) {
 				out, err := json.Marshal(podEvent{
 					EventName:  receivers.DeleteEvent,
 					PodName:    key,
 					NewPodData: nil,
-					OldPodData: nil,
+					OldPodData: pod,
 				})
 
 				if err == nil {
-					queue.Add(string(out))
+					enqueueEvent(queue, "pod", receivers.DeleteEvent, string(out))
 				}
 			}
 		},
@@ -108,50 +123,40 @@ func podEventsHandler(key string, indexer cache.Indexer) error {
 	oldPod := event.OldPodData
 
 	var watchEvent bool = true
+	var pod *v1.Pod
 	var podNamespace string
 	var podAnnotations map[string]string
-	var podControllerKind string
-	var podControllerName string
-	var eventMessage strings.Builder
+	var eventUpdates []string
+	var eventReason string
+	var eventContainer string
+	var recovered bool
+	eventSeverity := receivers.InfoSeverity
 	podWatchSlackUsersID := make([]string, 0)
 
+	// on delete events only the old pod data exists
 	if newPod != nil {
-		podNamespace = newPod.GetNamespace()
-		podAnnotations = newPod.GetObjectMeta().GetAnnotations()
-
-		// fetch the pod owner controller
-		// this value can be any valid controller like StatefulSet, DaemonSet, ReplicaSet, Job and so on..
-		if newPod.GetOwnerReferences() != nil {
-			podControllerKind = newPod.GetOwnerReferences()[0].Kind
-			podControllerName = newPod.GetOwnerReferences()[0].Name
-		}
+		pod = newPod
+	} else if oldPod != nil {
+		pod = oldPod
 	}
 
-	eventReceivers := common.BuildEventReceiversList(podAnnotations)
-
-	log.Debug().
-		Msg(fmt.Sprintf("found %d event receivers for pod %s in namespace %s. receivers:%s. event-type: %s.",
-			len(eventReceivers), podName, podNamespace, strings.Join(eventReceivers, ","), event.EventName))
+	if pod != nil {
+		podNamespace = pod.GetNamespace()
+		podAnnotations = withNamespaceAnnotations(podNamespace, pod.GetObjectMeta().GetAnnotations())
+	}
 
 	switch event.EventName {
-	case "Add":
+	case receivers.AddEvent:
 		log.Debug().Msg(fmt.Sprintf("applicationInitTime: %v. pod creation time: %v",
 			applicationInitTime, newPod.ObjectMeta.CreationTimestamp.Time))
 
 		if (applicationInitTime).Before(newPod.ObjectMeta.CreationTimestamp.Time) {
-			messagePodName := podName
-			if podControllerKind == "StatefulSet" {
-				messagePodName = fmt.Sprintf("%s-%s", podName, newPod.ObjectMeta.UID)
-			}
-
-			eventMessage.WriteString(fmt.Sprintf("A `pod` in namesapce `%s` has been `Created`\n", podNamespace))
-			eventMessage.WriteString(fmt.Sprintf("Pod name:`%s`\n", messagePodName))
-			eventMessage.WriteString(fmt.Sprintf("Environment:`%s`\n", config.ClusterName()))
-			eventMessage.WriteString(fmt.Sprintf("Controller kind:`%s`. Controller name:`%s`\n", podControllerKind, podControllerName))
+			eventReason = "Created"
 		}
 
-	case "Delete":
-		eventMessage.WriteString(fmt.Sprintf("The pod `%s` in `%s` cluster has been deleted\n", podName, config.ClusterName()))
+	case receivers.DeleteEvent:
+		eventReason = "Deleted"
+		eventSeverity = receivers.WarningSeverity
 	default:
 		// update pod event
 		watchEvent = false
@@ -172,52 +177,68 @@ func podEventsHandler(key string, indexer cache.Indexer) error {
 			}
 		}
 
+		containerStatuses := make([]v1.ContainerStatus, 0)
+		oldContainerStatuses := make([]v1.ContainerStatus, 0)
+
 		if watchInitContainers {
 			updates := getStateChangeOfContainers(oldPod.Status.InitContainerStatuses, newPod.Status.InitContainerStatuses)
 			podUpdates = append(podUpdates, updates...)
+			containerStatuses = append(containerStatuses, newPod.Status.InitContainerStatuses...)
+			oldContainerStatuses = append(oldContainerStatuses, oldPod.Status.InitContainerStatuses...)
 		}
 
 		updates := getStateChangeOfContainers(oldPod.Status.ContainerStatuses, newPod.Status.ContainerStatuses)
 		podUpdates = append(podUpdates, updates...)
+		containerStatuses = append(containerStatuses, newPod.Status.ContainerStatuses...)
+		oldContainerStatuses = append(oldContainerStatuses, oldPod.Status.ContainerStatuses...)
 
 		if len(podUpdates) > 0 {
-			messagePodName := podName
-			if podControllerKind == "StatefulSet" {
-				messagePodName = fmt.Sprintf("%s-%s", podName, newPod.ObjectMeta.UID)
-			}
-
-			eventMessage.WriteString(fmt.Sprintf("A `pod` in namesapce `%s` has been `Updated`. Pod-Name:`%s`. Environment:`%s`.\n", podNamespace, messagePodName, config.ClusterName()))
-			eventMessage.WriteString(fmt.Sprintf("Controller kind:`%s`. Controller name:`%s`. Updates:\n", podControllerKind, podControllerName))
-			for _, updateStr := range podUpdates {
-				eventMessage.WriteString(fmt.Sprintf("- %s", updateStr))
-			}
+			eventContainer, eventReason = getContainersUpdateReason(oldContainerStatuses, containerStatuses)
+			eventSeverity = getContainerReasonSeverity(eventReason)
+			eventUpdates = podUpdates
+			recovered = eventReason == "Started" && hasCrashedContainer(oldContainerStatuses)
 		}
 	}
 
 	// if we have any events to update about,
 	// send the updates to the relevant receivers
-	if eventMessage.String() != "" {
-		additionalInfo := make(map[string]interface{})
-		onCrashLoopBack := false
-
-		if strings.Contains(eventMessage.String(), common.PodCrashLoopbackStringIdentifier()) {
-			additionalInfo[common.PodCrashLoopbackStringIdentifier()] = true
-			onCrashLoopBack = true
-		}
-
-		additionalInfo["pod_watcher_users_ids"] = podWatchSlackUsersID
+	if eventReason != "" || len(eventUpdates) > 0 {
+		onCrashLoopBack := eventReason == common.PodCrashLoopbackStringIdentifier()
 
 		// if updated events set to false, but the pod is in crash-loop-back we will still send the
-		// event so we can notify about it.
+		// event so we can notify about it, and its recovery so the receivers can resolve it.
 		// events of add/delete will be sent in any case.
-		if watchEvent || onCrashLoopBack {
-			receiverEvent := receivers.ReceiverEvent{
-				EventName:      event.EventName,
-				Message:        eventMessage.String(),
-				AdditionalInfo: additionalInfo,
+		if watchEvent || onCrashLoopBack || recovered {
+			receiverEvent := newReceiverEvent(event.EventName, common.PodKind, pod)
+			receiverEvent.Severity = eventSeverity
+			receiverEvent.Reason = eventReason
+			receiverEvent.Container = eventContainer
+			receiverEvent.Mentions = podWatchSlackUsersID
+			if len(eventUpdates) > 0 {
+				receiverEvent.AdditionalInfo["updates"] = eventUpdates
 			}
 
-			sendEventToReceivers(receiverEvent, eventReceivers)
+			receiverEvent.Message = receivers.RenderMessage(receiverEvent)
+
+			eventReceivers := buildEventReceivers(&receiverEvent)
+
+			// only the leader notifies about the event, so there is no need for the others to fetch the logs and the workload
+			if IsLeader() {
+				if onCrashLoopBack {
+					enrichCrashLoopEvent(&receiverEvent, pod)
+				}
+
+				// the slack channels of the workload are used by the slack receivers only
+				if hasSlackReceiver(eventReceivers) {
+					withWorkloadSlackChannels(&receiverEvent, pod)
+				}
+			}
+
+			log.Debug().
+				Msg(fmt.Sprintf("found %d event receivers for pod %s in namespace %s. receivers:%s. event-type: %s.",
+					len(eventReceivers), podName, podNamespace, strings.Join(eventReceivers, ","), event.EventName))
+
+			dispatchEvent(receiverEvent, eventReceivers)
 		}
 
 	}
@@ -225,6 +246,89 @@ func podEventsHandler(key string, indexer cache.Indexer) error {
 	return nil
 }
 
+// hasSlackReceiver returns true when one of the receivers is a slack receiver
+func hasSlackReceiver(eventReceivers []string) bool {
+	for _, receiverName := range eventReceivers {
+		if receivers.IsSlackReceiver(receiverName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasCrashedContainer returns true when one of the containers is in a crash loop,
+// the pod condition that is reported without the watch update annotation
+func hasCrashedContainer(containerStatuses []v1.ContainerStatus) bool {
+	for _, container := range containerStatuses {
+		if getContainerStateReason(container.State) == common.PodCrashLoopbackStringIdentifier() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getContainersUpdateReason returns the container name and the reason of the most important state change
+// between the old and new containers. a crash loop always wins, then any waiting or terminated reason.
+// if all containers have just started, the reason is 'Started'
+func getContainersUpdateReason(oldContainerStatus []v1.ContainerStatus, newContainerStatus []v1.ContainerStatus) (string, string) {
+	var containerName, reason string
+	oldState := make(map[string]string)
+
+	for _, container := range oldContainerStatus {
+		oldState[container.Name] = parseContainerState(container.State)
+	}
+
+	for _, container := range newContainerStatus {
+		state := parseContainerState(container.State)
+		if state == "" || oldState[container.Name] == state {
+			continue
+		}
+
+		containerReason := getContainerStateReason(container.State)
+
+		if containerReason == common.PodCrashLoopbackStringIdentifier() {
+			return container.Name, containerReason
+		}
+
+		if reason == "" || (reason == "Started" && containerReason != "Started") {
+			containerName = container.Name
+			reason = containerReason
+		}
+	}
+
+	return containerName, reason
+}
+
+func getContainerStateReason(cs v1.ContainerState) string {
+	if cs.Waiting != nil {
+		return cs.Waiting.Reason
+	} else if cs.Running != nil {
+		return "Started"
+	} else if cs.Terminated != nil {
+		if cs.Terminated.Reason != "" {
+			return cs.Terminated.Reason
+		}
+
+		return "Terminated"
+	}
+
+	return ""
+}
+
+// getContainerReasonSeverity maps a container state reason to the event severity
+func getContainerReasonSeverity(reason string) receivers.Severity {
+	switch reason {
+	case "", "Started", "Completed", "ContainerCreating", "PodInitializing":
+		return receivers.InfoSeverity
+	case common.PodCrashLoopbackStringIdentifier(), "OOMKilled":
+		return receivers.CriticalSeverity
+	default:
+		return receivers.WarningSeverity
+	}
+}
+
 // getStateChangeOfContainer will check the different between the continers
 // from the old state compare to the new state. the ContainerStatus slice can be the init continers or the reguler continers
 // retrun slice of strings that that represents human readable information about the change
@@ -292,10 +396,13 @@ func parseContainerState(cs v1.ContainerState) string {
 // iterate over the exclude pod name slice
 // and check if one (or more) of the slice members contains the pod name
 // if so, return false meaning that the event will ignored
-// in addition, check if the specific pod is mark as ignore (in annotations)
+// in addition, check if the specific pod (or its namespace) is mark as ignore (in annotations)
 // if so, return false. otherwise return true.
 func shouldWatchPod(podNamespaceKey string, pod *v1.Pod) bool {
-	var shouldWatch = pod.Annotations == nil || pod.Annotations[ignoreAllPodEventsAnnotationName] != "true"
+	podAnnotations := withNamespaceAnnotations(pod.GetNamespace(), pod.GetAnnotations())
+
+	var shouldWatch = (podAnnotations == nil || podAnnotations[ignoreAllPodEventsAnnotationName] != "true") &&
+		config.ShouldWatchNamespace(pod.GetNamespace())
 
 	if shouldWatch {
 		for _, pattern := range config.ExcludePodNamePatterns() {
@@ -312,7 +419,12 @@ func shouldWatchPod(podNamespaceKey string, pod *v1.Pod) bool {
 	return shouldWatch
 }
 
-// IsSPodControllerSync is used for server health check
+// IsSPodControllerSync is used for server health check.
+// when the pod watcher is disabled there is nothing to wait for
 func IsSPodControllerSync() bool {
+	if !config.WatcherEnabled("pod") {
+		return true
+	}
+
 	return podController.informer.HasSynced()
 }
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/PayU/kubeobserver/pkg/receivers"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
@@ -114,3 +115,95 @@ func TestIsSPodControllerSync(t *testing.T) {
 		t.Error("TestIsSPodControllerSync: test hasn't evaluated correctly sync status of unexistent controller")
 	}
 }
+
+func TestGetContainersUpdateReason(t *testing.T) {
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	crashLoop := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	pulling := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}
+
+	oldStatuses := []v1.ContainerStatus{{Name: "app", State: running}, {Name: "sidecar", State: running}}
+	newStatuses := []v1.ContainerStatus{{Name: "app", State: pulling}, {Name: "sidecar", State: crashLoop}}
+
+	container, reason := getContainersUpdateReason(oldStatuses, newStatuses)
+
+	if container != "sidecar" || reason != "CrashLoopBackOff" {
+		t.Errorf("TestGetContainersUpdateReason: expected crash loop of sidecar container but got [%s] of [%s]", reason, container)
+	}
+
+	if severity := getContainerReasonSeverity(reason); severity != receivers.CriticalSeverity {
+		t.Errorf("TestGetContainersUpdateReason: expected critical severity for crash loop but got [%s]", severity)
+	}
+}
+
+func TestHasSlackReceiver(t *testing.T) {
+	receivers.ReceiverMap["mockSlack"] = &receivers.SlackReceiver{}
+	defer delete(receivers.ReceiverMap, "mockSlack")
+
+	if !hasSlackReceiver([]string{"webhook", "mockSlack"}) {
+		t.Error("TestHasSlackReceiver: a slack receiver instance is a slack receiver")
+	}
+
+	if hasSlackReceiver([]string{"webhook", "unknown"}) {
+		t.Error("TestHasSlackReceiver: no slack receiver is expected, so the workload shouldn't be looked up")
+	}
+}
+
+func TestHasCrashedContainer(t *testing.T) {
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	crashLoop := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+
+	if !hasCrashedContainer([]v1.ContainerStatus{{Name: "app", State: running}, {Name: "sidecar", State: crashLoop}}) {
+		t.Error("TestHasCrashedContainer: a pod with a container in crash loop has crashed, so its recovery must be sent")
+	}
+
+	if hasCrashedContainer([]v1.ContainerStatus{{Name: "app", State: running}}) {
+		t.Error("TestHasCrashedContainer: a pod with running containers hasn't crashed")
+	}
+}
+
+func TestEnrichCrashLoopEvent(t *testing.T) {
+	defer func(original func(string, string, string, int64, int64) (string, error)) {
+		getPreviousContainerLogs = original
+	}(getPreviousContainerLogs)
+
+	var requestedLines, requestedBytes int64
+	getPreviousContainerLogs = func(namespace string, podName string, containerName string, lines int64, limitBytes int64) (string, error) {
+		requestedLines, requestedBytes = lines, limitBytes
+		return "panic: out of memory\n", nil
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "mockPod",
+			Namespace:   "default",
+			Annotations: map[string]string{podCrashLogsLinesAnnotationName: "5"},
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{{
+				Name: "app",
+				LastTerminationState: v1.ContainerState{
+					Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+				},
+			}},
+		},
+	}
+
+	receiverEvent := receivers.ReceiverEvent{Container: "app"}
+	enrichCrashLoopEvent(&receiverEvent, pod)
+
+	if receiverEvent.LastTermination == nil || receiverEvent.LastTermination.ExitCode != 137 || !receiverEvent.LastTermination.OOMKilled {
+		t.Errorf("Last termination wasn't attached properly: %+v", receiverEvent.LastTermination)
+	}
+
+	if receiverEvent.ContainerLogs != "panic: out of memory\n" || requestedLines != 5 || requestedBytes != defaultCrashLogsBytes {
+		t.Errorf("Container logs weren't attached properly. logs: %s, lines: %d, bytes: %d", receiverEvent.ContainerLogs, requestedLines, requestedBytes)
+	}
+
+	pod.Annotations[podCrashLogsAnnotationName] = "false"
+	receiverEvent = receivers.ReceiverEvent{Container: "app"}
+	enrichCrashLoopEvent(&receiverEvent, pod)
+
+	if receiverEvent.ContainerLogs != "" || receiverEvent.LastTermination == nil {
+		t.Error("Container logs should be skipped when disabled by annotation, termination details should stay")
+	}
+}
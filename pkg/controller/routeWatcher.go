@@ -0,0 +1,426 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// routeStatusSyncPeriod is how often the number of matched events is written to the routes status
+const routeStatusSyncPeriod = 30 * time.Second
+
+var kubeObserverRouteResource = schema.GroupVersionResource{Group: "kubeobserver.io", Version: "v1alpha1", Resource: "kubeobserverroutes"}
+
+// kubeObserverRoute is the KubeObserverRoute custom resource. it routes the events of the resources
+// in its own namespace that match its selectors, so teams can own their alerting in their namespace
+type kubeObserverRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   kubeObserverRouteSpec   `json:"spec"`
+	Status kubeObserverRouteStatus `json:"status,omitempty"`
+}
+
+type kubeObserverRouteSpec struct {
+	// Selector, Kinds and Reasons select the events of the route. an empty condition matches any value
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	Kinds    []string              `json:"kinds,omitempty"`
+	Reasons  []string              `json:"reasons,omitempty"`
+
+	// Receivers, Mentions, Template and Severity are applied to the matched events
+	Receivers []string `json:"receivers"`
+	Mentions  []string `json:"mentions,omitempty"`
+	Template  string   `json:"template,omitempty"`
+	Severity  string   `json:"severity,omitempty"`
+}
+
+type kubeObserverRouteStatus struct {
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"`
+	Valid              bool         `json:"valid"`
+	Errors             []string     `json:"errors,omitempty"`
+	MatchedEvents      int64        `json:"matchedEvents"`
+	LastMatchedTime    *metav1.Time `json:"lastMatchedTime,omitempty"`
+}
+
+type routeEvent struct {
+	EventName receivers.EventName
+	RouteName string
+}
+
+// resourceRoute is a valid route with the number of events it has matched since it was loaded
+type resourceRoute struct {
+	namespace   string
+	generation  int64
+	spec        kubeObserverRouteSpec
+	selector    labels.Selector
+	matched     int64
+	synced      int64
+	lastMatched time.Time
+}
+
+// resourceRoutes holds the valid routes by their namespace/name key
+var resourceRoutes = struct {
+	sync.Mutex
+	routes map[string]*resourceRoute
+}{routes: make(map[string]*resourceRoute)}
+
+// runningRouteController is kept so all the routes can be validated again, when the receivers
+// were reloaded or when this replica became the leader and owns the routes status
+var runningRouteController = struct {
+	sync.Mutex
+	controller *controller
+}{}
+
+func newRouteController() *controller {
+	// create the route watcher
+	routeClient := k8sClient.DynamicClient.Resource(kubeObserverRouteResource).Namespace(v1.NamespaceAll)
+	routeListWatcher := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return routeClient.List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return routeClient.Watch(options)
+		},
+	}
+
+	// create the workqueue
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "route")
+
+	// Bind the workqueue to a cache with the help of an informer. This way we make sure that
+	// whenever the cache is updated, the route key is added to the workqueue.
+	// the handler reads the latest route from the cache, so only the route key is queued
+	enqueueRoute := func(eventName receivers.EventName, obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err == nil {
+			enqueueRouteKey(queue, eventName, key)
+		}
+	}
+
+	indexer, informer := cache.NewIndexerInformer(routeListWatcher, &unstructured.Unstructured{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueueRoute(receivers.AddEvent, obj)
+		},
+		UpdateFunc: func(old interface{}, new interface{}) {
+			enqueueRoute(receivers.UpdateEvent, new)
+		},
+		DeleteFunc: func(obj interface{}) {
+			enqueueRoute(receivers.DeleteEvent, obj)
+		},
+	}, cache.Indexers{})
+
+	routeController := newController(queue, indexer, informer, routesEventsHandler, "route")
+
+	runningRouteController.Lock()
+	runningRouteController.controller = routeController
+	runningRouteController.Unlock()
+
+	return routeController
+}
+
+func enqueueRouteKey(queue workqueue.RateLimitingInterface, eventName receivers.EventName, key string) {
+	out, err := json.Marshal(routeEvent{EventName: eventName, RouteName: key})
+	if err == nil {
+		enqueueEvent(queue, "route", eventName, string(out))
+	}
+}
+
+// RevalidateRoutes queues all the routes to be validated again, for example after
+// the receivers were reloaded and the receivers of the routes were added or removed
+func RevalidateRoutes() {
+	runningRouteController.Lock()
+	routeController := runningRouteController.controller
+	runningRouteController.Unlock()
+
+	if routeController == nil {
+		return
+	}
+
+	for _, key := range routeController.indexer.ListKeys() {
+		enqueueRouteKey(routeController.queue, receivers.UpdateEvent, key)
+	}
+}
+
+// routeResourcesInstalled returns true when the KubeObserverRoute custom resource definition is installed
+func routeResourcesInstalled() bool {
+	resources, err := k8sClient.Clientset.Discovery().ServerResourcesForGroupVersion(kubeObserverRouteResource.GroupVersion().String())
+	if err != nil {
+		return false
+	}
+
+	for _, resource := range resources.APIResources {
+		if resource.Name == kubeObserverRouteResource.Resource {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routesEventsHandler is the business logic of the route controller.
+// In case an error happened, it has to simply return the error.
+func routesEventsHandler(key string, indexer cache.Indexer) error {
+	log.Debug().Msg("running routesEventsHandler func")
+	event := routeEvent{}
+	json.Unmarshal([]byte(key), &event)
+
+	obj, exists, err := indexer.GetByKey(event.RouteName)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		resourceRoutes.Lock()
+		delete(resourceRoutes.routes, event.RouteName)
+		resourceRoutes.Unlock()
+
+		log.Info().Msg(fmt.Sprintf("KubeObserverRoute [%s] has been removed", event.RouteName))
+		return nil
+	}
+
+	route := kubeObserverRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).UnstructuredContent(), &route); err != nil {
+		log.Warn().Msg(fmt.Sprintf("KubeObserverRoute [%s] can't be parsed: %s", event.RouteName, err))
+		return nil
+	}
+
+	// the route is validated again on every update, since the receivers it refers to may have been reloaded
+	selector, errs := validateRoute(route.Spec)
+
+	resourceRoutes.Lock()
+	current, wasLoaded := resourceRoutes.routes[event.RouteName]
+	if len(errs) == 0 {
+		loaded := &resourceRoute{namespace: route.GetNamespace(), generation: route.GetGeneration(), spec: route.Spec, selector: selector}
+
+		// the route was changed, the events it has already matched are still counted
+		if wasLoaded {
+			loaded.matched, loaded.synced, loaded.lastMatched = current.matched, current.synced, current.lastMatched
+		}
+
+		resourceRoutes.routes[event.RouteName] = loaded
+	} else {
+		delete(resourceRoutes.routes, event.RouteName)
+	}
+	resourceRoutes.Unlock()
+
+	if len(errs) == 0 && (!wasLoaded || current.generation != route.GetGeneration()) {
+		log.Info().Msg(fmt.Sprintf("KubeObserverRoute [%s] has been loaded. receivers[%s]", event.RouteName, strings.Join(route.Spec.Receivers, ",")))
+	}
+
+	// the status updates of the route don't change its generation or validity, there is nothing to write again
+	if route.Status.ObservedGeneration == route.GetGeneration() && route.Status.Valid == (len(errs) == 0) && reflect.DeepEqual(route.Status.Errors, errs) {
+		return nil
+	}
+
+	if len(errs) != 0 {
+		log.Warn().Msg(fmt.Sprintf("KubeObserverRoute [%s] is invalid: %s", event.RouteName, strings.Join(errs, ". ")))
+	}
+
+	return updateRouteStatus(route.GetNamespace(), route.GetName(), func(status *kubeObserverRouteStatus) {
+		status.ObservedGeneration = route.GetGeneration()
+		status.Valid = len(errs) == 0
+		status.Errors = errs
+	})
+}
+
+// validateRoute returns the label selector of the route and its validation errors
+func validateRoute(spec kubeObserverRouteSpec) (labels.Selector, []string) {
+	errs := make([]string, 0)
+
+	selector := labels.Everything()
+	if spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(spec.Selector); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid selector: %s", err))
+		}
+	}
+
+	if len(spec.Receivers) == 0 && len(spec.Mentions) == 0 && spec.Template == "" && spec.Severity == "" {
+		errs = append(errs, "at least one of receivers, mentions, template or severity is required")
+	}
+
+	for _, receiverName := range spec.Receivers {
+		if receivers.GetReceiver(receiverName) == nil {
+			errs = append(errs, fmt.Sprintf("unknown receiver '%s'", receiverName))
+		}
+	}
+
+	switch receivers.Severity(spec.Severity) {
+	case "", receivers.InfoSeverity, receivers.WarningSeverity, receivers.CriticalSeverity:
+	default:
+		errs = append(errs, fmt.Sprintf("invalid severity '%s', expected one of info, warning, critical", spec.Severity))
+	}
+
+	if len(errs) == 0 {
+		return selector, nil
+	}
+
+	return nil, errs
+}
+
+// applyResourceRoutes applies the routes in the namespace of the event that match it. the mentions of all
+// the matched routes are added to the event, the first matched route (by name) sets the severity and the template.
+// it returns the receivers of all the matched routes
+func applyResourceRoutes(receiverEvent *receivers.ReceiverEvent) []string {
+	eventReceivers := make([]string, 0)
+	if receiverEvent.Namespace == "" {
+		return eventReceivers
+	}
+
+	resourceRoutes.Lock()
+	defer resourceRoutes.Unlock()
+
+	keys := make([]string, 0)
+	for key, route := range resourceRoutes.routes {
+		if route.namespace == receiverEvent.Namespace {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	var severity receivers.Severity
+	for _, key := range keys {
+		route := resourceRoutes.routes[key]
+		if !route.matches(*receiverEvent) {
+			continue
+		}
+
+		// all replicas apply the routes, only the events of the leader are sent and counted
+		if IsLeader() {
+			route.matched++
+			route.lastMatched = time.Now()
+		}
+
+		eventReceivers = appendMissing(eventReceivers, route.spec.Receivers...)
+		receiverEvent.Mentions = appendMissing(receiverEvent.Mentions, route.spec.Mentions...)
+
+		if receiverEvent.Template == "" {
+			receiverEvent.Template = route.spec.Template
+		}
+
+		if severity == "" {
+			severity = receivers.Severity(route.spec.Severity)
+		}
+	}
+
+	if severity != "" {
+		receiverEvent.Severity = severity
+	}
+
+	return eventReceivers
+}
+
+func (r *resourceRoute) matches(receiverEvent receivers.ReceiverEvent) bool {
+	return (len(r.spec.Kinds) == 0 || contains(r.spec.Kinds, receiverEvent.Kind)) &&
+		(len(r.spec.Reasons) == 0 || contains(r.spec.Reasons, receiverEvent.Reason)) &&
+		r.selector.Matches(labels.Set(receiverEvent.Labels))
+}
+
+// syncRoutesStatus writes the number of events each route has matched to its status
+func syncRoutesStatus(stopCh chan struct{}) {
+	wait.Until(func() {
+		// only the leader counts the matched events and writes them
+		if !IsLeader() {
+			return
+		}
+
+		type routeMatches struct {
+			key         string
+			namespace   string
+			name        string
+			matched     int64
+			lastMatched time.Time
+		}
+
+		pending := make([]routeMatches, 0)
+
+		resourceRoutes.Lock()
+		for key, route := range resourceRoutes.routes {
+			if route.matched > route.synced {
+				name := strings.TrimPrefix(key, route.namespace+"/")
+				pending = append(pending, routeMatches{key, route.namespace, name, route.matched - route.synced, route.lastMatched})
+			}
+		}
+		resourceRoutes.Unlock()
+
+		for _, routeMatch := range pending {
+			err := updateRouteStatus(routeMatch.namespace, routeMatch.name, func(status *kubeObserverRouteStatus) {
+				lastMatched := metav1.NewTime(routeMatch.lastMatched)
+				status.MatchedEvents += routeMatch.matched
+				status.LastMatchedTime = &lastMatched
+			})
+
+			if err != nil {
+				log.Warn().Msg(fmt.Sprintf("couldn't update the status of KubeObserverRoute [%s]: %s", routeMatch.key, err))
+				continue
+			}
+
+			resourceRoutes.Lock()
+			if route, ok := resourceRoutes.routes[routeMatch.key]; ok {
+				route.synced += routeMatch.matched
+			}
+			resourceRoutes.Unlock()
+		}
+	}, routeStatusSyncPeriod, stopCh)
+}
+
+// updateRouteStatus applies the change to the latest status of the route. only the leader updates the status
+func updateRouteStatus(namespace string, name string, change func(*kubeObserverRouteStatus)) error {
+	if !IsLeader() {
+		return nil
+	}
+
+	routeClient := k8sClient.DynamicClient.Resource(kubeObserverRouteResource).Namespace(namespace)
+
+	obj, err := routeClient.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	route := kubeObserverRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), &route); err != nil {
+		return err
+	}
+
+	change(&route.Status)
+
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&route.Status)
+	if err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedField(obj.Object, status, "status"); err != nil {
+		return err
+	}
+
+	_, err = routeClient.UpdateStatus(obj, metav1.UpdateOptions{})
+	return err
+}
+
+// appendMissing appends the values that are not in the slice yet
+func appendMissing(slice []string, values ...string) []string {
+	for _, value := range values {
+		if !contains(slice, value) {
+			slice = append(slice, value)
+		}
+	}
+
+	return slice
+}
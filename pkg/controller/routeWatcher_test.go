@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func mockRoute(spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kubeobserver.io/v1alpha1",
+		"kind":       "KubeObserverRoute",
+		"metadata": map[string]interface{}{
+			"name":       "payments-critical",
+			"namespace":  "payments",
+			"generation": int64(1),
+		},
+		"spec": spec,
+	}}
+}
+
+func resetResourceRoutes() {
+	resourceRoutes.Lock()
+	resourceRoutes.routes = make(map[string]*resourceRoute)
+	resourceRoutes.Unlock()
+}
+
+func TestValidateRoute(t *testing.T) {
+	receivers.LoadReceivers()
+
+	if _, errs := validateRoute(kubeObserverRouteSpec{Receivers: []string{"slack"}, Severity: "critical"}); len(errs) != 0 {
+		t.Errorf("Route should be valid, got %v", errs)
+	}
+
+	invalid := kubeObserverRouteSpec{
+		Receivers: []string{"slack", "pager"},
+		Severity:  "urgent",
+		Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: "Matches"},
+		}},
+	}
+
+	if _, errs := validateRoute(invalid); len(errs) != 3 {
+		t.Errorf("Expected selector, receiver and severity errors, got %v", errs)
+	}
+}
+
+func TestRoutesEventsHandler(t *testing.T) {
+	defer resetResourceRoutes()
+
+	receivers.LoadReceivers()
+
+	route := mockRoute(map[string]interface{}{
+		"selector":  map[string]interface{}{"matchLabels": map[string]interface{}{"team": "payments"}},
+		"reasons":   []interface{}{"CrashLoopBackOff"},
+		"receivers": []interface{}{"slack"},
+		"mentions":  []interface{}{"U0123456"},
+		"severity":  "critical",
+	})
+
+	defer func(dynamicClient dynamic.Interface) { k8sClient.DynamicClient = dynamicClient }(k8sClient.DynamicClient)
+	k8sClient.DynamicClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), route.DeepCopy())
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(route)
+
+	key, _ := json.Marshal(routeEvent{EventName: receivers.AddEvent, RouteName: "payments/payments-critical"})
+	if err := routesEventsHandler(string(key), indexer); err != nil {
+		t.Fatalf("Route handler failed: %v", err)
+	}
+
+	updated, _ := k8sClient.DynamicClient.Resource(kubeObserverRouteResource).Namespace("payments").Get("payments-critical", metav1.GetOptions{})
+	if valid, _, _ := unstructured.NestedBool(updated.Object, "status", "valid"); !valid {
+		t.Errorf("Route status should be valid, got %v", updated.Object["status"])
+	}
+
+	receiverEvent := receivers.ReceiverEvent{
+		Namespace: "payments",
+		Kind:      "Pod",
+		Reason:    "CrashLoopBackOff",
+		Severity:  receivers.WarningSeverity,
+		Labels:    map[string]string{"team": "payments"},
+	}
+
+	if eventReceivers := buildEventReceivers(&receiverEvent); len(eventReceivers) != 1 || eventReceivers[0] != "slack" {
+		t.Errorf("Expected the route receivers, got %v", eventReceivers)
+	}
+
+	if receiverEvent.Severity != receivers.CriticalSeverity || len(receiverEvent.Mentions) != 1 {
+		t.Errorf("Route severity and mentions weren't applied: %+v", receiverEvent)
+	}
+
+	otherNamespace := receiverEvent
+	otherNamespace.Namespace = "checkout"
+
+	if eventReceivers := applyResourceRoutes(&otherNamespace); len(eventReceivers) != 0 {
+		t.Errorf("Route shouldn't match events of other namespaces, got %v", eventReceivers)
+	}
+
+	if matched := resourceRoutes.routes["payments/payments-critical"].matched; matched != 1 {
+		t.Errorf("Expected a single matched event, got %d", matched)
+	}
+
+	// the events of a follower aren't sent, so they aren't counted
+	setLeader(false)
+	applyResourceRoutes(&receiverEvent)
+	setLeader(true)
+
+	if matched := resourceRoutes.routes["payments/payments-critical"].matched; matched != 1 {
+		t.Errorf("A follower shouldn't count matched events, got %d", matched)
+	}
+
+	// the receivers were reloaded without the receiver of the route
+	delete(receivers.ReceiverMap, "slack")
+	routesEventsHandler(string(key), indexer)
+	receivers.LoadReceivers()
+
+	updated, _ = k8sClient.DynamicClient.Resource(kubeObserverRouteResource).Namespace("payments").Get("payments-critical", metav1.GetOptions{})
+	if valid, _, _ := unstructured.NestedBool(updated.Object, "status", "valid"); valid || len(resourceRoutes.routes) != 0 {
+		t.Errorf("Route of a removed receiver should be invalid, got %v", updated.Object["status"])
+	}
+
+	indexer.Delete(route)
+	routesEventsHandler(string(key), indexer)
+
+	if len(resourceRoutes.routes) != 0 {
+		t.Error("Deleted route should be removed")
+	}
+}
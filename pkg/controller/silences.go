@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+)
+
+// silencesConfigMapName is the ConfigMap that holds the silences, in the namespace of the leader election lease.
+// every replica watches it, so a silence applies to the events of the leader whichever replica received the action
+const silencesConfigMapName = "kubeobserver-silences"
+
+// silences holds the workloads that were silenced from slack, by namespace.kind.name, until their
+// expiration. it is a copy of the silences ConfigMap, updated by its informer
+var silences = struct {
+	sync.Mutex
+	until map[string]time.Time
+}{until: make(map[string]time.Time)}
+
+// silenceKey is the key of the silence in the ConfigMap. ConfigMap keys may contain dots, but not slashes
+func silenceKey(namespace string, kind string, name string) string {
+	return namespace + "." + kind + "." + name
+}
+
+// startSilencesInformer runs the informer of the silences ConfigMap and waits for it to sync
+func startSilencesInformer(stopCh chan struct{}) {
+	silencesListWatcher := cache.NewListWatchFromClient(k8sClient.Clientset.CoreV1().RESTClient(), "configmaps", config.LeaderElectionNamespace(),
+		fields.OneTermEqualSelector("metadata.name", silencesConfigMapName))
+
+	_, informer := cache.NewIndexerInformer(silencesListWatcher, &v1.ConfigMap{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			loadSilences(obj.(*v1.ConfigMap).Data)
+		},
+		UpdateFunc: func(old interface{}, new interface{}) {
+			loadSilences(new.(*v1.ConfigMap).Data)
+		},
+		DeleteFunc: func(obj interface{}) {
+			loadSilences(nil)
+		},
+	}, cache.Indexers{})
+
+	go informer.Run(stopCh)
+
+	timeoutCh := make(chan struct{})
+	timer := time.AfterFunc(namespaceCacheSyncTimeout, func() { close(timeoutCh) })
+	defer timer.Stop()
+
+	if !cache.WaitForCacheSync(timeoutCh, informer.HasSynced) {
+		log.Warn().Msg(fmt.Sprintf("silences cache didn't sync within %v. silences won't be applied until it does", namespaceCacheSyncTimeout))
+	}
+}
+
+// loadSilences replaces the silences with the ones of the ConfigMap data
+func loadSilences(data map[string]string) {
+	until := make(map[string]time.Time, len(data))
+
+	for key, value := range data {
+		expiration, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Error().Msg(fmt.Sprintf("ignoring silence %s with invalid expiration %s: %s", key, value, err.Error()))
+			continue
+		}
+
+		until[key] = expiration
+	}
+
+	silences.Lock()
+	defer silences.Unlock()
+
+	silences.until = until
+}
+
+// SilenceWorkload drops the events of the workload and of its pods for the given duration.
+// the silence is written to the silences ConfigMap, and it returns the time the silence expires
+func SilenceWorkload(namespace string, kind string, name string, duration time.Duration) (time.Time, error) {
+	until := time.Now().Add(duration).Truncate(time.Second)
+	key := silenceKey(namespace, kind, name)
+	configMaps := k8sClient.Clientset.CoreV1().ConfigMaps(config.LeaderElectionNamespace())
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := configMaps.Get(silencesConfigMapName, metav1.GetOptions{})
+		exists := err == nil
+
+		if errors.IsNotFound(err) {
+			configMap = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: silencesConfigMapName, Namespace: config.LeaderElectionNamespace()}}
+		} else if err != nil {
+			return err
+		}
+
+		data := make(map[string]string)
+		now := time.Now()
+
+		// expired silences are removed, so the ConfigMap doesn't grow
+		for k, value := range configMap.Data {
+			if expiration, err := time.Parse(time.RFC3339, value); err == nil && now.Before(expiration) {
+				data[k] = value
+			}
+		}
+
+		data[key] = until.Format(time.RFC3339)
+		configMap.Data = data
+
+		if exists {
+			_, err = configMaps.Update(configMap)
+			return err
+		}
+
+		// another replica may have created the ConfigMap in the meantime, which is retried like a conflict
+		if _, err = configMaps.Create(configMap); errors.IsAlreadyExists(err) {
+			return errors.NewConflict(v1.Resource("configmaps"), silencesConfigMapName, err)
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return until, fmt.Errorf("couldn't silence %s %s/%s: %s", kind, namespace, name, err.Error())
+	}
+
+	// the informer updates the silences as well, but the silence should apply right away
+	silences.Lock()
+	defer silences.Unlock()
+
+	silences.until[key] = until
+
+	return until, nil
+}
+
+// isSilenced returns true when the event resource or its workload is silenced
+func isSilenced(receiverEvent receivers.ReceiverEvent) bool {
+	keys := []string{silenceKey(receiverEvent.Namespace, receiverEvent.Kind, receiverEvent.Name)}
+	if workload := receiverEvent.Workload(); workload.Kind != "" {
+		keys = append(keys, silenceKey(receiverEvent.Namespace, workload.Kind, workload.Name))
+	}
+
+	now := time.Now()
+
+	silences.Lock()
+	defer silences.Unlock()
+
+	for _, key := range keys {
+		if until, ok := silences.until[key]; ok && now.Before(until) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RolloutRestart restarts the pods of the deployment the same way 'kubectl rollout restart' does,
+// by changing an annotation of its pod template
+func RolloutRestart(namespace string, name string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{"kubectl.kubernetes.io/restartedAt": time.Now().Format(time.RFC3339)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = k8sClient.Clientset.AppsV1().Deployments(namespace).Patch(name, types.StrategicMergePatchType, patch); err != nil {
+		return fmt.Errorf("couldn't restart deployment %s/%s: %s", namespace, name, err.Error())
+	}
+
+	return nil
+}
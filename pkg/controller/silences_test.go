@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSilenceWorkload(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset()
+	defer loadSilences(nil)
+
+	event := mockCrashLoopEvent("checkout-5d8f7-x2x1", receivers.CriticalSeverity)
+	if isSilenced(event) {
+		t.Fatal("TestSilenceWorkload: event shouldn't be silenced before the workload is silenced")
+	}
+
+	until, err := SilenceWorkload("payments", "Deployment", "checkout", time.Hour)
+	if err != nil {
+		t.Fatalf("TestSilenceWorkload: unexpected error: %s", err.Error())
+	}
+
+	if until.Before(time.Now().Add(59 * time.Minute)) {
+		t.Errorf("TestSilenceWorkload: unexpected silence expiration %s", until)
+	}
+
+	if !isSilenced(event) {
+		t.Error("TestSilenceWorkload: pods of a silenced deployment should be silenced")
+	}
+
+	other := mockCrashLoopEvent("orders-7f9c4-a1b2", receivers.CriticalSeverity)
+	other.Owner = receivers.Owner{Kind: "ReplicaSet", Name: "orders-7f9c4"}
+	other.Labels = map[string]string{"pod-template-hash": "7f9c4"}
+	if isSilenced(other) {
+		t.Error("TestSilenceWorkload: pods of other deployments shouldn't be silenced")
+	}
+
+	if _, err = SilenceWorkload("payments", "Deployment", "checkout", -time.Second); err != nil {
+		t.Fatalf("TestSilenceWorkload: unexpected error: %s", err.Error())
+	}
+
+	if isSilenced(event) {
+		t.Error("TestSilenceWorkload: expired silence shouldn't drop events")
+	}
+}
+
+func TestSilencesAreShared(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset()
+	defer loadSilences(nil)
+
+	if _, err := SilenceWorkload("payments", "Deployment", "checkout", time.Hour); err != nil {
+		t.Fatalf("TestSilencesAreShared: unexpected error: %s", err.Error())
+	}
+
+	if _, err := SilenceWorkload("payments", "Deployment", "orders", -time.Second); err != nil {
+		t.Fatalf("TestSilencesAreShared: unexpected error: %s", err.Error())
+	}
+
+	configMap, err := k8sClient.Clientset.CoreV1().ConfigMaps(config.LeaderElectionNamespace()).Get(silencesConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("TestSilencesAreShared: the silences ConfigMap should be created: %s", err.Error())
+	}
+
+	if _, ok := configMap.Data["payments.Deployment.checkout"]; !ok || len(configMap.Data) != 2 {
+		t.Errorf("TestSilencesAreShared: unexpected silences %v", configMap.Data)
+	}
+
+	// another replica loads the silences from the ConfigMap informer
+	loadSilences(nil)
+	if isSilenced(mockCrashLoopEvent("checkout-5d8f7-x2x1", receivers.CriticalSeverity)) {
+		t.Fatal("TestSilencesAreShared: no silences should be left after they were cleared")
+	}
+
+	loadSilences(configMap.Data)
+	if !isSilenced(mockCrashLoopEvent("checkout-5d8f7-x2x1", receivers.CriticalSeverity)) {
+		t.Error("TestSilencesAreShared: silences of the ConfigMap should apply on every replica")
+	}
+
+	// expired silences are removed when the ConfigMap is written again
+	if _, err = SilenceWorkload("payments", "Deployment", "checkout", time.Hour); err != nil {
+		t.Fatalf("TestSilencesAreShared: unexpected error: %s", err.Error())
+	}
+
+	configMap, _ = k8sClient.Clientset.CoreV1().ConfigMaps(config.LeaderElectionNamespace()).Get(silencesConfigMapName, metav1.GetOptions{})
+	if _, ok := configMap.Data["payments.Deployment.orders"]; ok {
+		t.Errorf("TestSilencesAreShared: expired silences should be removed, got %v", configMap.Data)
+	}
+}
+
+func TestRolloutRestart(t *testing.T) {
+	k8sClient.Clientset = fake.NewSimpleClientset(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "payments"}})
+
+	if err := RolloutRestart("payments", "checkout"); err != nil {
+		t.Fatalf("TestRolloutRestart: unexpected error: %s", err.Error())
+	}
+
+	deployment, err := k8sClient.Clientset.AppsV1().Deployments("payments").Get("checkout", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("TestRolloutRestart: couldn't get deployment: %s", err.Error())
+	}
+
+	if deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] == "" {
+		t.Error("TestRolloutRestart: pod template should have the restartedAt annotation")
+	}
+
+	if err := RolloutRestart("payments", "missing"); err == nil {
+		t.Error("TestRolloutRestart: restarting a missing deployment should fail")
+	}
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const namespace = "kubeobserver"
+
+// ConfigReloads counts the configuration reload attempts by result (success / failure)
+var ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "config_reloads_total",
+	Help:      "Total number of configuration reload attempts by result",
+}, []string{"result"})
+
+// ConfigLastReloadSuccessful is 1 when the last configuration reload succeeded and 0 when it was rejected
+var ConfigLastReloadSuccessful = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "config_last_reload_successful",
+	Help:      "Whether the last configuration reload attempt was successful",
+})
+
+// IsLeader is 1 when this replica holds the leader election lease (or leader election is disabled) and 0 otherwise
+var IsLeader = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "is_leader",
+	Help:      "Whether this replica is the leader and sends events to the receivers",
+})
+
+// EventsEnqueued counts the events the watchers added to their queue by controller and event type
+var EventsEnqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "events_enqueued_total",
+	Help:      "Total number of events added to the controllers queue",
+}, []string{"controller", "event_type"})
+
+// EventsProcessed counts the events handled by the controllers by controller, event type and result (success / error).
+// an event that failed and was requeued is counted on every attempt
+var EventsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "events_processed_total",
+	Help:      "Total number of events handled by the controllers",
+}, []string{"controller", "event_type", "result"})
+
+// EventsDropped counts the events the controllers gave up on after all of the retries failed
+var EventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "events_dropped_total",
+	Help:      "Total number of events dropped after all of the retries failed",
+}, []string{"controller", "event_type"})
+
+// EventsDeduplicated counts the events that were not sent since the same event of the resource was already sent
+var EventsDeduplicated = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "events_deduplicated_total",
+	Help:      "Total number of repeating events of a resource that were not sent again within the aggregation window",
+}, []string{"kind"})
+
+// EventsSilenced counts the events that were not sent since their workload was silenced from slack
+var EventsSilenced = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "events_silenced_total",
+	Help:      "Total number of events that were not sent since their workload was silenced",
+}, []string{"kind"})
+
+// EventsAggregated counts the events that were sent as part of an aggregated summary instead of on their own
+var EventsAggregated = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "events_aggregated_total",
+	Help:      "Total number of events that were sent as part of an aggregated summary",
+}, []string{"kind"})
+
+// ReceiverSendDuration observes how long it took a receiver to handle an event
+var ReceiverSendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "receiver_send_duration_seconds",
+	Help:      "Time it took a receiver to handle an event",
+	Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+}, []string{"receiver"})
+
+// ReceiverEvents counts the events sent to every receiver by result (success / failure)
+var ReceiverEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "receiver_events_total",
+	Help:      "Total number of events sent to the receivers by result",
+}, []string{"receiver", "result"})
+
+// SlackRateLimited counts the slack api responses that were rejected by slack rate limit
+var SlackRateLimited = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "slack_rate_limited_total",
+	Help:      "Total number of slack api requests rejected by slack rate limit",
+})
+
+// TemplateErrors counts the message templates that couldn't be parsed or rendered. the default template is used instead
+var TemplateErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "template_errors_total",
+	Help:      "Total number of message templates that failed to parse or render",
+}, []string{"template"})
+
+func init() {
+	// the configuration loaded at startup is always valid, otherwise kubeobserver won't start
+	ConfigLastReloadSuccessful.Set(1)
+
+	// the provider has to be set before the controllers create their queues
+	workqueue.SetProvider(workqueueMetricsProvider{})
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const workqueueSubsystem = "workqueue"
+
+var workqueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: workqueueSubsystem,
+	Name:      "depth",
+	Help:      "Current depth of the controller workqueue",
+}, []string{"name"})
+
+var workqueueAdds = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: workqueueSubsystem,
+	Name:      "adds_total",
+	Help:      "Total number of adds handled by the controller workqueue",
+}, []string{"name"})
+
+var workqueueLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: workqueueSubsystem,
+	Name:      "queue_duration_seconds",
+	Help:      "How long in seconds an item stays in the controller workqueue before being requested",
+	Buckets:   prometheus.ExponentialBuckets(10e-9, 10, 10),
+}, []string{"name"})
+
+var workqueueWorkDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: workqueueSubsystem,
+	Name:      "work_duration_seconds",
+	Help:      "How long in seconds processing an item from the controller workqueue takes",
+	Buckets:   prometheus.ExponentialBuckets(10e-9, 10, 10),
+}, []string{"name"})
+
+var workqueueUnfinishedWork = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: workqueueSubsystem,
+	Name:      "unfinished_work_seconds",
+	Help:      "How many seconds of work has been done that is in progress and hasn't been observed by work_duration",
+}, []string{"name"})
+
+var workqueueLongestRunningProcessor = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: workqueueSubsystem,
+	Name:      "longest_running_processor_seconds",
+	Help:      "How many seconds has the longest running processor of the controller workqueue been running",
+}, []string{"name"})
+
+var workqueueRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: workqueueSubsystem,
+	Name:      "retries_total",
+	Help:      "Total number of retries handled by the controller workqueue",
+}, []string{"name"})
+
+// workqueueMetricsProvider exposes the metrics of the named controller workqueues as prometheus metrics
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return workqueueDepth.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return workqueueAdds.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return workqueueLatency.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return workqueueWorkDuration.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return workqueueUnfinishedWork.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return workqueueLongestRunningProcessor.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return workqueueRetries.WithLabelValues(name)
+}
@@ -0,0 +1,363 @@
+package receivers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
+var alertmanagerReceiverName = "alertmanager"
+
+// alertmanagerReceiverAlias is the receiver name used in the documentation of earlier versions
+var alertmanagerReceiverAlias = "alert-manager"
+var alertmanagerAlertsPath = "/api/v2/alerts"
+
+// runbookAnnotationName is the resource annotation with the runbook URL that is added to the alerts
+var runbookAnnotationName = "kubeobserver.io/runbook_url"
+
+// alertmanagerResendFactor is the number of resend intervals an active alert is valid for,
+// so a single failed resend doesn't resolve it. prometheus uses the same factor
+const alertmanagerResendFactor = 4
+
+// AlertmanagerReceiver is a struct built for posting events as alerts to Prometheus Alertmanager.
+// critical and warning events fire an alert and recovery events resolve it
+type AlertmanagerReceiver struct {
+	URLs           []string
+	ResendInterval time.Duration
+	AlertTTL       time.Duration
+	Retries        int
+	RetryBackoff   time.Duration
+	HTTPClient     *http.Client
+}
+
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// activeAlert is an alert that has a recovery event. a resolved alert is kept
+// until its resolution was posted, so a failed post is retried by the resend
+type activeAlert struct {
+	alert    alertmanagerAlert
+	resolved bool
+}
+
+// activeAlerts holds the firing alerts that have a recovery event (by incident key).
+// alertmanager resolves an alert when its endsAt passes, so these alerts are sent again
+// every resend interval until they are resolved. it outlives the receivers, which are built
+// again on configuration reload
+var activeAlerts = struct {
+	sync.Mutex
+	alerts map[string]activeAlert
+}{alerts: make(map[string]activeAlert)}
+
+// alertmanagerResend controls the resend of the active alerts. the alerts are resent by the leader only, since only the
+// leader sends the recovery events that resolve them, and a replica that loses the leadership forgets its alerts
+var alertmanagerResend = struct {
+	sync.Mutex
+	leader bool
+	stopCh chan struct{}
+}{}
+
+func init() {
+	registerReceiver(alertmanagerReceiverName, newAlertmanagerReceiver)
+	registerReceiver(alertmanagerReceiverAlias, newAlertmanagerReceiver)
+}
+
+func newAlertmanagerReceiver() Receiver {
+	return &AlertmanagerReceiver{
+		URLs:           config.AlertmanagerURLs(),
+		ResendInterval: config.AlertmanagerResendInterval(),
+		AlertTTL:       config.AlertmanagerAlertTTL(),
+		Retries:        config.WebhookRetries(),
+		RetryBackoff:   time.Second,
+		HTTPClient:     &http.Client{Timeout: config.WebhookTimeout()},
+	}
+}
+
+// HandleEvent is an implementation of the Receiver interface for Alertmanager
+func (ar *AlertmanagerReceiver) HandleEvent(receiverEvent ReceiverEvent, c chan error) {
+	// no matter what happens, close the channel after function exits
+	defer close(c)
+
+	receiverEvent.Message = renderMessage(alertmanagerReceiverName, receiverEvent)
+
+	// this will be true in case some event has alertmanager receiver
+	// but no urls were provided in the configuration
+	if len(ar.URLs) == 0 {
+		c <- errors.New("HandleEvent of alertmanager was triggered but no alertmanager urls were found in configuration")
+		return
+	}
+
+	alerts, resolvedKeys := ar.buildAlerts(receiverEvent, time.Now())
+
+	// info events that don't resolve any alert are not alerts
+	if len(alerts) == 0 {
+		log.Debug().Msg(fmt.Sprintf("alertmanager receiver: ignoring %s event of %s [%s]", receiverEvent.Reason, receiverEvent.Kind, receiverEvent.Name))
+		return
+	}
+
+	if err := ar.postAlerts(alerts); err != nil {
+		c <- err
+		return
+	}
+
+	removeResolvedAlerts(resolvedKeys)
+}
+
+// buildAlerts returns the resolved alerts and their keys for a recovery event, or a firing alert for a
+// critical or warning event. alerts that can be resolved are kept active until their resolution is posted.
+// an aggregated summary doesn't fire, since its name may be the name of the controller of the resources
+// and the first event of its group has already fired an alert
+func (ar *AlertmanagerReceiver) buildAlerts(receiverEvent ReceiverEvent, now time.Time) ([]alertmanagerAlert, []string) {
+	activeAlerts.Lock()
+	defer activeAlerts.Unlock()
+
+	alerts := make([]alertmanagerAlert, 0)
+	resolvedKeys := make([]string, 0)
+
+	// a deleted resource is a warning, but it resolves the alerts of the resource as well
+	if resolved, ok := resolvedReasons[receiverEvent.Kind][receiverEvent.Reason]; ok {
+		for _, event := range summarizedEvents(receiverEvent) {
+			for _, reason := range resolved {
+				key := incidentKey(event, reason)
+
+				// alerts fired before a restart are not known, they resolve when their endsAt passes
+				if active, ok := activeAlerts.alerts[key]; ok {
+					if !active.resolved {
+						active.alert.EndsAt = now
+						active.resolved = true
+						activeAlerts.alerts[key] = active
+					}
+
+					alerts = append(alerts, active.alert)
+					resolvedKeys = append(resolvedKeys, key)
+				}
+			}
+		}
+
+		return alerts, resolvedKeys
+	}
+
+	if isAggregated(receiverEvent) {
+		return alerts, resolvedKeys
+	}
+
+	if receiverEvent.Severity == CriticalSeverity || receiverEvent.Severity == WarningSeverity {
+		key := incidentKey(receiverEvent, receiverEvent.Reason)
+		alert := buildAlertmanagerAlert(receiverEvent, now)
+
+		if !isResolvable(receiverEvent.Kind, receiverEvent.Reason) {
+			alert.EndsAt = now.Add(ar.AlertTTL)
+			return []alertmanagerAlert{alert}, resolvedKeys
+		}
+
+		// the alert keeps its original start time while the condition lasts
+		if active, ok := activeAlerts.alerts[key]; ok && !active.resolved {
+			alert.StartsAt = active.alert.StartsAt
+		}
+
+		alert.EndsAt = now.Add(alertmanagerResendFactor * ar.ResendInterval)
+		activeAlerts.alerts[key] = activeAlert{alert: alert}
+		startAlertmanagerResend()
+
+		return []alertmanagerAlert{alert}, resolvedKeys
+	}
+
+	return alerts, resolvedKeys
+}
+
+// removeResolvedAlerts removes the alerts whose resolution was posted. an alert that fired
+// again in the meantime is kept
+func removeResolvedAlerts(keys []string) {
+	activeAlerts.Lock()
+	defer activeAlerts.Unlock()
+
+	for _, key := range keys {
+		if active, ok := activeAlerts.alerts[key]; ok && active.resolved {
+			delete(activeAlerts.alerts, key)
+		}
+	}
+}
+
+func buildAlertmanagerAlert(receiverEvent ReceiverEvent, now time.Time) alertmanagerAlert {
+	labels := map[string]string{
+		"alertname": receiverEvent.Reason,
+		"cluster":   receiverEvent.Cluster,
+		"kind":      receiverEvent.Kind,
+		"name":      receiverEvent.Name,
+		"reason":    receiverEvent.Reason,
+		"severity":  string(receiverEvent.Severity),
+	}
+
+	if receiverEvent.Namespace != "" {
+		labels["namespace"] = receiverEvent.Namespace
+	}
+
+	if receiverEvent.Kind == "Pod" {
+		labels["pod"] = receiverEvent.Name
+	}
+
+	if receiverEvent.Container != "" {
+		labels["container"] = receiverEvent.Container
+	}
+
+	annotations := map[string]string{"message": receiverEvent.Message}
+	if runbook := receiverEvent.Annotations[runbookAnnotationName]; runbook != "" {
+		annotations["runbook_url"] = runbook
+	}
+
+	startsAt := receiverEvent.Timestamp
+	if startsAt.IsZero() {
+		startsAt = now
+	}
+
+	return alertmanagerAlert{Labels: labels, Annotations: annotations, StartsAt: startsAt}
+}
+
+// setAlertmanagerLeader allows the leader to resend its active alerts, and stops the resend
+// of a replica that lost the leadership
+func setAlertmanagerLeader(leader bool) {
+	alertmanagerResend.Lock()
+	alertmanagerResend.leader = leader
+	alertmanagerResend.Unlock()
+
+	if !leader {
+		stopAlertmanagerResend()
+	}
+}
+
+// startAlertmanagerResend starts the resend of the active alerts on the leader, unless it is already running
+func startAlertmanagerResend() {
+	alertmanagerResend.Lock()
+	defer alertmanagerResend.Unlock()
+
+	if alertmanagerResend.leader && alertmanagerResend.stopCh == nil {
+		alertmanagerResend.stopCh = make(chan struct{})
+		go runAlertmanagerResend(alertmanagerResend.stopCh)
+	}
+}
+
+// stopAlertmanagerResend stops the resend of the active alerts and forgets them. the new leader
+// fires them again on their next events, and the ones it doesn't resolve when their endsAt passes
+func stopAlertmanagerResend() {
+	alertmanagerResend.Lock()
+	if alertmanagerResend.stopCh != nil {
+		close(alertmanagerResend.stopCh)
+		alertmanagerResend.stopCh = nil
+	}
+	alertmanagerResend.Unlock()
+
+	activeAlerts.Lock()
+	defer activeAlerts.Unlock()
+
+	activeAlerts.alerts = make(map[string]activeAlert)
+}
+
+// runAlertmanagerResend sends the active alerts again every resend interval, with the
+// alertmanager receiver of the current configuration, until it is stopped
+func runAlertmanagerResend(stopCh chan struct{}) {
+	for {
+		interval := time.Minute
+		if receiver, ok := GetReceiver(alertmanagerReceiverName).(*AlertmanagerReceiver); ok && receiver.ResendInterval > 0 {
+			interval = receiver.ResendInterval
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(interval):
+		}
+
+		if receiver, ok := GetReceiver(alertmanagerReceiverName).(*AlertmanagerReceiver); ok {
+			if err := receiver.resendActiveAlerts(time.Now()); err != nil {
+				log.Error().Msg(fmt.Sprintf("alertmanager receiver failed to resend the active alerts: %s", err))
+			}
+		}
+	}
+}
+
+// resendActiveAlerts extends the endsAt of the firing alerts and sends them again,
+// along with the resolved alerts whose resolution wasn't posted yet
+func (ar *AlertmanagerReceiver) resendActiveAlerts(now time.Time) error {
+	activeAlerts.Lock()
+	alerts := make([]alertmanagerAlert, 0, len(activeAlerts.alerts))
+	resolvedKeys := make([]string, 0)
+
+	for key, active := range activeAlerts.alerts {
+		if active.resolved {
+			resolvedKeys = append(resolvedKeys, key)
+		} else {
+			active.alert.EndsAt = now.Add(alertmanagerResendFactor * ar.ResendInterval)
+			activeAlerts.alerts[key] = active
+		}
+
+		alerts = append(alerts, active.alert)
+	}
+	activeAlerts.Unlock()
+
+	if len(alerts) == 0 || len(ar.URLs) == 0 {
+		return nil
+	}
+
+	if err := ar.postAlerts(alerts); err != nil {
+		return err
+	}
+
+	removeResolvedAlerts(resolvedKeys)
+
+	return nil
+}
+
+// postAlerts posts the alerts to all of the alertmanager instances, which don't share their alerts
+func (ar *AlertmanagerReceiver) postAlerts(alerts []alertmanagerAlert) error {
+	payload, err := json.Marshal(alerts)
+	if err != nil {
+		return fmt.Errorf("alertmanager receiver couldn't marshal the alerts -> %s", err.Error())
+	}
+
+	errorsStr := make([]string, 0)
+
+	for _, url := range ar.URLs {
+		url = strings.TrimSuffix(url, "/") + alertmanagerAlertsPath
+
+		err := retryRequest(alertmanagerReceiverName, url, ar.Retries, ar.RetryBackoff, func() (bool, error) {
+			return ar.post(url, payload)
+		})
+
+		if err != nil {
+			errorsStr = append(errorsStr, err.Error())
+		}
+	}
+
+	// the receivers contract allows a single error per event
+	if len(errorsStr) > 0 {
+		return fmt.Errorf("alertmanager receiver got unexpected error -> %s", strings.Join(errorsStr, "; "))
+	}
+
+	return nil
+}
+
+// post sends a single request and returns whether a failure is worth retrying
+func (ar *AlertmanagerReceiver) post(url string, payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+
+	return doRequest(ar.HTTPClient, req, alertmanagerReceiverName)
+}
@@ -0,0 +1,345 @@
+package receivers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newMockAlertmanagerReceiver(urls ...string) *AlertmanagerReceiver {
+	return &AlertmanagerReceiver{
+		URLs:           urls,
+		ResendInterval: time.Minute,
+		AlertTTL:       time.Hour,
+		Retries:        2,
+		RetryBackoff:   time.Millisecond,
+		HTTPClient:     &http.Client{Timeout: time.Second},
+	}
+}
+
+// newMockAlertmanagerServer returns a server that records the alerts it receives
+func newMockAlertmanagerServer(t *testing.T, alerts *[]alertmanagerAlert) *httptest.Server {
+	var lock sync.Mutex
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != alertmanagerAlertsPath {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		received := make([]alertmanagerAlert, 0)
+
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("unexpected payload %s. err: %v", string(body), err)
+		}
+
+		lock.Lock()
+		*alerts = append(*alerts, received...)
+		lock.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func handleAlertmanagerEvent(t *testing.T, receiver *AlertmanagerReceiver, event ReceiverEvent) {
+	c := make(chan error)
+	go receiver.HandleEvent(event, c)
+
+	if err := <-c; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestAlertmanagerFireAndResolve(t *testing.T) {
+	alerts := make([]alertmanagerAlert, 0)
+	server := newMockAlertmanagerServer(t, &alerts)
+	defer server.Close()
+
+	receiver := newMockAlertmanagerReceiver(server.URL)
+	event := ReceiverEvent{EventName: UpdateEvent, Message: "mockMessage", Cluster: "mockCluster", Kind: "Pod", Namespace: "mockNamespace",
+		Name: "mockPod", Container: "mockContainer", Severity: CriticalSeverity, Reason: "CrashLoopBackOff",
+		Annotations: map[string]string{runbookAnnotationName: "https://runbooks/crashloop"}}
+
+	handleAlertmanagerEvent(t, receiver, event)
+
+	if len(alerts) != 1 {
+		t.Fatalf("TestAlertmanagerFireAndResolve: expected a single alert but got %d", len(alerts))
+	}
+
+	fired := alerts[0]
+	expectedLabels := map[string]string{"alertname": "CrashLoopBackOff", "cluster": "mockCluster", "kind": "Pod", "name": "mockPod", "pod": "mockPod",
+		"namespace": "mockNamespace", "container": "mockContainer", "reason": "CrashLoopBackOff", "severity": "critical"}
+
+	for name, value := range expectedLabels {
+		if fired.Labels[name] != value {
+			t.Errorf("TestAlertmanagerFireAndResolve: expected label %s=%s but got %s", name, value, fired.Labels[name])
+		}
+	}
+
+	if fired.Annotations["message"] != "mockMessage" || fired.Annotations["runbook_url"] != "https://runbooks/crashloop" {
+		t.Errorf("TestAlertmanagerFireAndResolve: unexpected annotations %v", fired.Annotations)
+	}
+
+	if !fired.EndsAt.After(time.Now()) {
+		t.Errorf("TestAlertmanagerFireAndResolve: a firing alert should end in the future. endsAt: %v", fired.EndsAt)
+	}
+
+	event.Reason = "Started"
+	event.Severity = InfoSeverity
+	handleAlertmanagerEvent(t, receiver, event)
+
+	if len(alerts) != 2 {
+		t.Fatalf("TestAlertmanagerFireAndResolve: expected a resolved alert but got %d alerts", len(alerts))
+	}
+
+	resolved := alerts[1]
+	if resolved.Labels["reason"] != "CrashLoopBackOff" || resolved.Labels["severity"] != "critical" || resolved.EndsAt.After(time.Now()) {
+		t.Errorf("TestAlertmanagerFireAndResolve: unexpected resolved alert %+v", resolved)
+	}
+
+	if !resolved.StartsAt.Equal(fired.StartsAt) {
+		t.Errorf("TestAlertmanagerFireAndResolve: the resolved alert should keep its start time")
+	}
+
+	// the alert isn't active anymore, so it isn't resolved again
+	handleAlertmanagerEvent(t, receiver, event)
+
+	if len(alerts) != 2 {
+		t.Errorf("TestAlertmanagerFireAndResolve: expected no more alerts but got %d", len(alerts))
+	}
+}
+
+func TestAlertmanagerDeletedResourceResolves(t *testing.T) {
+	alerts := make([]alertmanagerAlert, 0)
+	server := newMockAlertmanagerServer(t, &alerts)
+	defer server.Close()
+
+	receiver := newMockAlertmanagerReceiver(server.URL)
+	event := ReceiverEvent{Cluster: "mockCluster", Kind: "Node", Name: "mockNode", Severity: WarningSeverity, Reason: "MemoryPressure"}
+	handleAlertmanagerEvent(t, receiver, event)
+
+	event.Reason = "Deleted"
+	handleAlertmanagerEvent(t, receiver, event)
+
+	if len(alerts) != 2 {
+		t.Fatalf("TestAlertmanagerDeletedResourceResolves: expected a fired and a resolved alert but got %d alerts", len(alerts))
+	}
+
+	if alerts[1].Labels["reason"] != "MemoryPressure" || alerts[1].EndsAt.After(time.Now()) {
+		t.Errorf("TestAlertmanagerDeletedResourceResolves: unexpected resolved alert %+v", alerts[1])
+	}
+}
+
+func TestAlertmanagerAlertWithoutRecovery(t *testing.T) {
+	alerts := make([]alertmanagerAlert, 0)
+	server := newMockAlertmanagerServer(t, &alerts)
+	defer server.Close()
+
+	receiver := newMockAlertmanagerReceiver(server.URL)
+	handleAlertmanagerEvent(t, receiver, ReceiverEvent{Cluster: "mockCluster", Kind: "Job", Namespace: "mockNamespace", Name: "mockJob",
+		Severity: CriticalSeverity, Reason: "JobFailed"})
+
+	if len(alerts) != 1 {
+		t.Fatalf("TestAlertmanagerAlertWithoutRecovery: expected a single alert but got %d", len(alerts))
+	}
+
+	// alerts without a recovery event end after the alert ttl
+	if ttl := alerts[0].EndsAt.Sub(alerts[0].StartsAt); ttl < 59*time.Minute || ttl > time.Hour {
+		t.Errorf("TestAlertmanagerAlertWithoutRecovery: expected the alert to end after an hour but got %v", ttl)
+	}
+
+	activeAlerts.Lock()
+	_, active := activeAlerts.alerts["mockCluster/Job/mockNamespace/mockJob/JobFailed"]
+	activeAlerts.Unlock()
+
+	if active {
+		t.Error("TestAlertmanagerAlertWithoutRecovery: alerts without a recovery event should not be resent")
+	}
+}
+
+func TestAlertmanagerIgnoredEvent(t *testing.T) {
+	alerts := make([]alertmanagerAlert, 0)
+	server := newMockAlertmanagerServer(t, &alerts)
+	defer server.Close()
+
+	handleAlertmanagerEvent(t, newMockAlertmanagerReceiver(server.URL), ReceiverEvent{Kind: "Pod", Reason: "Created", Severity: InfoSeverity})
+
+	if len(alerts) != 0 {
+		t.Errorf("TestAlertmanagerIgnoredEvent: expected no alerts but got %d", len(alerts))
+	}
+}
+
+func TestAlertmanagerResendActiveAlerts(t *testing.T) {
+	alerts := make([]alertmanagerAlert, 0)
+	server := newMockAlertmanagerServer(t, &alerts)
+	defer server.Close()
+
+	receiver := newMockAlertmanagerReceiver(server.URL)
+	handleAlertmanagerEvent(t, receiver, ReceiverEvent{Cluster: "mockCluster", Kind: "Deployment", Namespace: "mockNamespace", Name: "mockDeployment",
+		Severity: CriticalSeverity, Reason: "ProgressDeadlineExceeded"})
+
+	later := time.Now().Add(10 * time.Minute)
+	if err := receiver.resendActiveAlerts(later); err != nil {
+		t.Fatalf("TestAlertmanagerResendActiveAlerts: unexpected error: %s", err)
+	}
+
+	resent := false
+	for _, alert := range alerts[1:] {
+		if alert.Labels["name"] == "mockDeployment" {
+			resent = alert.EndsAt.Equal(later.Add(alertmanagerResendFactor * receiver.ResendInterval))
+		}
+	}
+
+	if !resent {
+		t.Error("TestAlertmanagerResendActiveAlerts: the active alert should be resent with a later endsAt")
+	}
+}
+
+func TestAlertmanagerFailedResolveIsKept(t *testing.T) {
+	alerts := make([]alertmanagerAlert, 0)
+	server := newMockAlertmanagerServer(t, &alerts)
+	defer server.Close()
+
+	var failing int32
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer failingServer.Close()
+
+	receiver := newMockAlertmanagerReceiver(failingServer.URL)
+	event := ReceiverEvent{Cluster: "mockCluster", Kind: "Node", Name: "mockFailingNode", Severity: CriticalSeverity, Reason: "NodeNotReady"}
+	handleAlertmanagerEvent(t, receiver, event)
+
+	atomic.StoreInt32(&failing, 1)
+	event.Reason = "NodeReady"
+	event.Severity = InfoSeverity
+
+	c := make(chan error)
+	go receiver.HandleEvent(event, c)
+
+	if err := <-c; err == nil {
+		t.Fatal("TestAlertmanagerFailedResolveIsKept: expected an error when the resolution can't be posted")
+	}
+
+	key := "mockCluster/Node//mockFailingNode/NodeNotReady"
+	activeAlerts.Lock()
+	active, ok := activeAlerts.alerts[key]
+	activeAlerts.Unlock()
+
+	if !ok || !active.resolved {
+		t.Fatal("TestAlertmanagerFailedResolveIsKept: a resolved alert should be kept until its resolution is posted")
+	}
+
+	// the resend posts the resolution again, without extending its endsAt
+	atomic.StoreInt32(&failing, 0)
+	if err := receiver.resendActiveAlerts(time.Now().Add(10 * time.Minute)); err != nil {
+		t.Fatalf("TestAlertmanagerFailedResolveIsKept: unexpected error: %s", err)
+	}
+
+	resolved := false
+	for _, alert := range alerts[1:] {
+		if alert.Labels["name"] == "mockFailingNode" {
+			resolved = alert.EndsAt.Equal(active.alert.EndsAt)
+		}
+	}
+
+	activeAlerts.Lock()
+	_, ok = activeAlerts.alerts[key]
+	activeAlerts.Unlock()
+
+	if !resolved || ok {
+		t.Error("TestAlertmanagerFailedResolveIsKept: the resend should post the resolution and remove the alert")
+	}
+}
+
+func TestAlertmanagerAggregatedEvents(t *testing.T) {
+	alerts := make([]alertmanagerAlert, 0)
+	server := newMockAlertmanagerServer(t, &alerts)
+	defer server.Close()
+
+	receiver := newMockAlertmanagerReceiver(server.URL)
+	for _, name := range []string{"checkout-1", "checkout-2"} {
+		handleAlertmanagerEvent(t, receiver, ReceiverEvent{Cluster: "mockCluster", Kind: "Pod", Namespace: "payments", Name: name,
+			Severity: CriticalSeverity, Reason: "CrashLoopBackOff"})
+	}
+
+	// the summary is named after the deployment, so its alert would never be resolved
+	aggregatedInfo := map[string]interface{}{"aggregated_events": 2, "aggregated_resources": 2, "aggregated_names": []string{"checkout-1", "checkout-2"}}
+	summary := ReceiverEvent{Cluster: "mockCluster", Kind: "Pod", Namespace: "payments", Name: "checkout",
+		Severity: CriticalSeverity, Reason: "CrashLoopBackOff", AdditionalInfo: aggregatedInfo}
+	handleAlertmanagerEvent(t, receiver, summary)
+
+	if len(alerts) != 2 {
+		t.Fatalf("TestAlertmanagerAggregatedEvents: expected no alert for a summary but got %d alerts", len(alerts))
+	}
+
+	summary.Severity = InfoSeverity
+	summary.Reason = "Started"
+	handleAlertmanagerEvent(t, receiver, summary)
+
+	if len(alerts) != 4 || alerts[2].Labels["name"] == alerts[3].Labels["name"] || alerts[2].EndsAt.After(time.Now()) || alerts[3].EndsAt.After(time.Now()) {
+		t.Errorf("TestAlertmanagerAggregatedEvents: expected the alerts of both pods to be resolved, got %+v", alerts[2:])
+	}
+}
+
+func TestAlertmanagerResendOnLeaderOnly(t *testing.T) {
+	alerts := make([]alertmanagerAlert, 0)
+	server := newMockAlertmanagerServer(t, &alerts)
+	defer server.Close()
+
+	SetLeader(true)
+	defer SetLeader(false)
+
+	handleAlertmanagerEvent(t, newMockAlertmanagerReceiver(server.URL), ReceiverEvent{Cluster: "mockCluster", Kind: "Node", Name: "mockLeaderNode",
+		Severity: CriticalSeverity, Reason: "NodeNotReady"})
+
+	alertmanagerResend.Lock()
+	running := alertmanagerResend.stopCh != nil
+	alertmanagerResend.Unlock()
+
+	if !running {
+		t.Fatal("TestAlertmanagerResendOnLeaderOnly: the leader should resend the active alerts")
+	}
+
+	// only the new leader gets the recovery events, so the alerts of the former leader would fire forever
+	SetLeader(false)
+
+	alertmanagerResend.Lock()
+	running = alertmanagerResend.stopCh != nil
+	alertmanagerResend.Unlock()
+
+	activeAlerts.Lock()
+	active := len(activeAlerts.alerts)
+	activeAlerts.Unlock()
+
+	if running || active != 0 {
+		t.Errorf("TestAlertmanagerResendOnLeaderOnly: a replica that lost the leadership should stop the resend and forget its %d alerts", active)
+	}
+}
+
+func TestAlertmanagerAlias(t *testing.T) {
+	LoadReceivers()
+
+	if _, ok := GetReceiver(alertmanagerReceiverAlias).(*AlertmanagerReceiver); !ok {
+		t.Errorf("TestAlertmanagerAlias: %s should be registered as an alertmanager receiver", alertmanagerReceiverAlias)
+	}
+}
+
+func TestAlertmanagerWithoutURLs(t *testing.T) {
+	c := make(chan error)
+	go newMockAlertmanagerReceiver().HandleEvent(ReceiverEvent{Kind: "Pod", Reason: "OOMKilled", Severity: CriticalSeverity}, c)
+
+	if err := <-c; err == nil {
+		t.Error("TestAlertmanagerWithoutURLs: should receive an error when no urls are configured")
+	}
+}
@@ -0,0 +1,448 @@
+package receivers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+var emailReceiverName = "email"
+
+// emailRecipientsAnnotationName is the resource annotation with the recipients of the resource events.
+// it replaces the default recipients of the configuration
+var emailRecipientsAnnotationName = "kubeobserver.io/email-recipients"
+
+// emailTimeout bounds a whole SMTP session, from the connection to the end of the mail
+const emailTimeout = 30 * time.Second
+
+// emailDigestMaxEvents is the number of events a digest is sent with before its interval ends,
+// so a burst of events doesn't build a huge mail
+const emailDigestMaxEvents = 200
+
+// EmailReceiver is a struct built for sending events by mail through an SMTP server.
+// events are sent one per mail, or batched into a digest mail per interval
+type EmailReceiver struct {
+	Host           string
+	Port           int
+	Username       string
+	Password       string
+	StartTLS       bool
+	From           string
+	To             []string
+	DigestInterval time.Duration
+	Timeout        time.Duration
+
+	// TLSConfig is used for the STARTTLS upgrade. by default the server certificate is verified against the host
+	TLSConfig *tls.Config
+}
+
+type emailTemplateData struct {
+	Cluster string
+	Events  []ReceiverEvent
+}
+
+// emailDigests holds the events waiting for the next digest mail by their recipients.
+// it outlives the receivers, which are built again on configuration reload
+var emailDigests = struct {
+	sync.Mutex
+	digests map[string]*emailDigest
+}{digests: make(map[string]*emailDigest)}
+
+type emailDigest struct {
+	to     []string
+	events []ReceiverEvent
+}
+
+var emailDigestOnce sync.Once
+
+var emailTextTemplate = template.Must(template.New("email-text").Funcs(template.FuncMap{"ts": emailTimestamp}).Parse(
+	`{{range .Events}}{{.Kind}} {{if .Namespace}}{{.Namespace}}/{{end}}{{.Name}}{{if .Reason}} - {{.Reason}}{{end}} ({{.Severity}})
+Cluster: {{.Cluster}}
+Time: {{ts .Timestamp}}
+{{if .Owner.Kind}}Controller: {{.Owner.Kind}}/{{.Owner.Name}}
+{{end}}{{if .Container}}Container: {{.Container}}
+{{end}}
+{{.Message}}
+{{if .ContainerLogs}}
+Last logs of {{.Container}}:
+{{.ContainerLogs}}
+{{end}}
+----
+{{end}}Sent by kubeobserver from {{.Cluster}} cluster
+`))
+
+var emailHTMLTemplate = htmltemplate.Must(htmltemplate.New("email-html").Funcs(htmltemplate.FuncMap{"ts": emailTimestamp, "message": emailHTMLMessage, "color": emailSeverityColor}).Parse(
+	`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, Helvetica, sans-serif; font-size: 14px;">
+{{range .Events}}<table style="border-left: 4px solid {{color .Severity}}; margin-bottom: 16px; padding-left: 8px;">
+<tr><td colspan="2"><strong>{{.Kind}} {{if .Namespace}}{{.Namespace}}/{{end}}{{.Name}}{{if .Reason}} - {{.Reason}}{{end}}</strong></td></tr>
+<tr><td>Cluster</td><td>{{.Cluster}}</td></tr>
+<tr><td>Severity</td><td>{{.Severity}}</td></tr>
+<tr><td>Time</td><td>{{ts .Timestamp}}</td></tr>
+{{if .Owner.Kind}}<tr><td>Controller</td><td>{{.Owner.Kind}}/{{.Owner.Name}}</td></tr>
+{{end}}{{if .Container}}<tr><td>Container</td><td>{{.Container}}</td></tr>
+{{end}}<tr><td colspan="2">{{message .Message}}</td></tr>
+{{if .ContainerLogs}}<tr><td colspan="2">Last logs of <code>{{.Container}}</code>:<pre>{{.ContainerLogs}}</pre></td></tr>
+{{end}}</table>
+{{end}}<p style="color: #888888; font-size: 12px;">Sent by kubeobserver from {{.Cluster}} cluster</p>
+</body>
+</html>
+`))
+
+func init() {
+	registerReceiver(emailReceiverName, newEmailReceiver)
+}
+
+func newEmailReceiver() Receiver {
+	return &EmailReceiver{
+		Host:           config.EmailSMTPHost(),
+		Port:           config.EmailSMTPPort(),
+		Username:       config.EmailUsername(),
+		Password:       config.EmailPassword(),
+		StartTLS:       config.EmailStartTLS(),
+		From:           config.EmailFrom(),
+		To:             config.EmailTo(),
+		DigestInterval: config.EmailDigestInterval(),
+		Timeout:        emailTimeout,
+	}
+}
+
+// HandleEvent is an implementation of the Receiver interface for email
+func (er *EmailReceiver) HandleEvent(receiverEvent ReceiverEvent, c chan error) {
+	// no matter what happens, close the channel after function exits
+	defer close(c)
+
+	receiverEvent.Message = renderMessage(emailReceiverName, receiverEvent)
+
+	// this will be true in case some event has email receiver
+	// but no smtp server was provided in the configuration
+	if er.Host == "" || er.From == "" {
+		c <- errors.New("HandleEvent of email was triggered but no smtp host or sender address were found in configuration")
+		return
+	}
+
+	to := er.getRecipients(receiverEvent)
+	if len(to) == 0 {
+		c <- fmt.Errorf("HandleEvent of email was triggered but no recipients were found for %s [%s]", receiverEvent.Kind, receiverEvent.Name)
+		return
+	}
+
+	log.Debug().Msg(fmt.Sprintf("received %s message in email receiver: %s", receiverEvent.EventName, receiverEvent.Message))
+
+	if er.DigestInterval <= 0 {
+		if err := er.send(to, []ReceiverEvent{receiverEvent}); err != nil {
+			c <- fmt.Errorf("email receiver got unexpected error -> %s", err.Error())
+		}
+
+		return
+	}
+
+	// a full digest is sent right away instead of waiting for the interval
+	if full := addToEmailDigest(to, receiverEvent); full != nil {
+		if err := er.send(full.to, full.events); err != nil {
+			c <- fmt.Errorf("email receiver got unexpected error -> %s", err.Error())
+		}
+	}
+}
+
+// getRecipients returns the recipients of the email recipients annotation, or the default recipients
+func (er *EmailReceiver) getRecipients(receiverEvent ReceiverEvent) []string {
+	recipients := make([]string, 0)
+
+	for _, recipient := range strings.Split(receiverEvent.Annotations[emailRecipientsAnnotationName], ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	if len(recipients) == 0 {
+		return er.To
+	}
+
+	return recipients
+}
+
+// addToEmailDigest adds the event to the digest of its recipients and returns the digest when it is full
+func addToEmailDigest(to []string, receiverEvent ReceiverEvent) *emailDigest {
+	emailDigestOnce.Do(func() { go runEmailDigests() })
+
+	sorted := append([]string{}, to...)
+	sort.Strings(sorted)
+	key := strings.Join(sorted, ",")
+
+	emailDigests.Lock()
+	defer emailDigests.Unlock()
+
+	digest, ok := emailDigests.digests[key]
+	if !ok {
+		digest = &emailDigest{to: to}
+		emailDigests.digests[key] = digest
+	}
+
+	digest.events = append(digest.events, receiverEvent)
+
+	if len(digest.events) >= emailDigestMaxEvents {
+		delete(emailDigests.digests, key)
+		return digest
+	}
+
+	return nil
+}
+
+// runEmailDigests sends the waiting digests every digest interval, with the
+// email receiver of the current configuration
+func runEmailDigests() {
+	for {
+		interval := time.Minute
+		if receiver, ok := GetReceiver(emailReceiverName).(*EmailReceiver); ok && receiver.DigestInterval > 0 {
+			interval = receiver.DigestInterval
+		}
+
+		time.Sleep(interval)
+
+		if receiver, ok := GetReceiver(emailReceiverName).(*EmailReceiver); ok {
+			receiver.sendDigests()
+		}
+	}
+}
+
+// sendDigests sends a mail for each of the waiting digests. a digest that fails to be sent
+// is kept and sent again on the next interval, together with the events that were added meanwhile
+func (er *EmailReceiver) sendDigests() {
+	emailDigests.Lock()
+	digests := emailDigests.digests
+	emailDigests.digests = make(map[string]*emailDigest)
+	emailDigests.Unlock()
+
+	for key, digest := range digests {
+		if err := er.send(digest.to, digest.events); err != nil {
+			metrics.ReceiverEvents.WithLabelValues(emailReceiverName, "failure").Inc()
+			log.Error().Msg(fmt.Sprintf("email receiver failed to send a digest of %d events to %s, it will be sent again on the next interval: %s",
+				len(digest.events), strings.Join(digest.to, ","), err))
+
+			restoreEmailDigest(key, digest)
+		}
+	}
+}
+
+// restoreEmailDigest puts the events of a digest that failed to be sent before the events that were added
+// to its recipients digest meanwhile. the digest keeps its newest emailDigestMaxEvents events at most
+func restoreEmailDigest(key string, digest *emailDigest) {
+	emailDigests.Lock()
+	defer emailDigests.Unlock()
+
+	if waiting, ok := emailDigests.digests[key]; ok {
+		digest.events = append(digest.events, waiting.events...)
+	}
+
+	if dropped := len(digest.events) - emailDigestMaxEvents; dropped > 0 {
+		log.Warn().Msg(fmt.Sprintf("email receiver dropped the %d oldest events of the digest to %s", dropped, strings.Join(digest.to, ",")))
+		digest.events = digest.events[dropped:]
+	}
+
+	emailDigests.digests[key] = digest
+}
+
+func (er *EmailReceiver) send(to []string, events []ReceiverEvent) error {
+	msg, err := er.buildMessage(to, events)
+	if err != nil {
+		return err
+	}
+
+	return er.sendMail(to, msg)
+}
+
+// buildMessage renders the events into a multipart mail with a plain text and an HTML part
+func (er *EmailReceiver) buildMessage(to []string, events []ReceiverEvent) ([]byte, error) {
+	data := emailTemplateData{Cluster: events[0].Cluster, Events: events}
+
+	var text, html bytes.Buffer
+	if err := emailTextTemplate.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	if err := emailHTMLTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	writer := multipart.NewWriter(&msg)
+
+	headers := []string{
+		"From: " + er.From,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", emailSubject(events)),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+
+	msg.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{{"text/plain; charset=utf-8", text.Bytes()}, {"text/html; charset=utf-8", html.Bytes()}} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.body); err != nil {
+			return nil, err
+		}
+
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return msg.Bytes(), nil
+}
+
+// sendMail sends the message in a single SMTP session. with StartTLS the session must be
+// encrypted before the credentials and the message are sent
+func (er *EmailReceiver) sendMail(to []string, msg []byte) error {
+	addr := net.JoinHostPort(er.Host, strconv.Itoa(er.Port))
+
+	conn, err := net.DialTimeout("tcp", addr, er.Timeout)
+	if err != nil {
+		return err
+	}
+
+	conn.SetDeadline(time.Now().Add(er.Timeout))
+
+	client, err := smtp.NewClient(conn, er.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if er.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s doesn't support STARTTLS", addr)
+		}
+
+		tlsConfig := er.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: er.Host}
+		}
+
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if er.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", er.Username, er.Password, er.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(er.From); err != nil {
+		return err
+	}
+
+	for _, recipient := range to {
+		if err = client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func emailSubject(events []ReceiverEvent) string {
+	if len(events) > 1 {
+		return fmt.Sprintf("[kubeobserver] %s cluster: %d events", events[0].Cluster, len(events))
+	}
+
+	event := events[0]
+	resource := event.Name
+	if event.Namespace != "" {
+		resource = event.Namespace + "/" + event.Name
+	}
+
+	subject := fmt.Sprintf("[kubeobserver] %s cluster: %s %s", event.Cluster, event.Kind, resource)
+	if event.Reason != "" {
+		subject += " " + event.Reason
+	}
+
+	return subject
+}
+
+func emailTimestamp(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	return t.UTC().Format(time.RFC1123)
+}
+
+// emailHTMLMessage renders the slack flavored inline code of the message as HTML code
+// and keeps its line breaks. the rest of the message is escaped
+func emailHTMLMessage(message string) htmltemplate.HTML {
+	var msgBuilder strings.Builder
+
+	for i, part := range strings.Split(htmltemplate.HTMLEscapeString(strings.TrimSpace(message)), "`") {
+		if i%2 == 1 {
+			msgBuilder.WriteString("<code>" + part + "</code>")
+		} else {
+			msgBuilder.WriteString(part)
+		}
+	}
+
+	return htmltemplate.HTML(strings.ReplaceAll(msgBuilder.String(), "\n", "<br>\n"))
+}
+
+func emailSeverityColor(severity Severity) string {
+	switch severity {
+	case CriticalSeverity:
+		return "#C70039"
+	case WarningSeverity:
+		return "#DAA038"
+	default:
+		return "#2EB886"
+	}
+}
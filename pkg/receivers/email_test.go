@@ -0,0 +1,340 @@
+package receivers
+
+import (
+	"bufio"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// mockSMTPServer is an in-process SMTP stand-in that accepts every mail and records it.
+// it doesn't support STARTTLS
+type mockSMTPServer struct {
+	listener  net.Listener
+	lock      sync.Mutex
+	mails     []mockMail
+	authCount int
+}
+
+type mockMail struct {
+	from string
+	to   []string
+	data string
+}
+
+func newMockSMTPServer(t *testing.T) *mockSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldn't start the mock smtp server: %s", err)
+	}
+
+	server := &mockSMTPServer{listener: listener}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *mockSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	current := mockMail{}
+
+	reply("220 mock ESMTP")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"):
+			reply("250-mock")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(command, "AUTH"):
+			s.lock.Lock()
+			s.authCount++
+			s.lock.Unlock()
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			current = mockMail{from: strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			current.to = append(current.to, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+
+				if dataLine == ".\r\n" {
+					break
+				}
+
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+
+			current.data = data.String()
+			s.lock.Lock()
+			s.mails = append(s.mails, current)
+			s.lock.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *mockSMTPServer) getMails() []mockMail {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]mockMail{}, s.mails...)
+}
+
+func (s *mockSMTPServer) getAuthCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.authCount
+}
+
+func (s *mockSMTPServer) newReceiver() *EmailReceiver {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return &EmailReceiver{
+		Host:     host,
+		Port:     portNumber,
+		Username: "mockUser",
+		Password: "mockPassword",
+		From:     "kubeobserver@mock.com",
+		To:       []string{"sre@mock.com"},
+		Timeout:  time.Second,
+	}
+}
+
+// readMailParts returns the subject and the decoded plain text and html parts of the mail
+func readMailParts(t *testing.T, data string) (string, string, string) {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("couldn't parse the mail: %s", err)
+	}
+
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("couldn't parse the mail content type: %s", err)
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+
+		body, _ := ioutil.ReadAll(quotedprintable.NewReader(part))
+		parts[strings.Split(part.Header.Get("Content-Type"), ";")[0]] = string(body)
+	}
+
+	return subject, parts["text/plain"], parts["text/html"]
+}
+
+func handleEmailEvent(receiver *EmailReceiver, event ReceiverEvent) error {
+	c := make(chan error)
+	go receiver.HandleEvent(event, c)
+
+	return <-c
+}
+
+func TestEmailHandleEvent(t *testing.T) {
+	server := newMockSMTPServer(t)
+	defer server.listener.Close()
+
+	event := ReceiverEvent{EventName: DeleteEvent, Message: "The pod `mockPod` has been deleted <script>", Cluster: "mockCluster",
+		Kind: "Pod", Namespace: "mockNamespace", Name: "mockPod", Severity: WarningSeverity, Reason: "Deleted"}
+
+	if err := handleEmailEvent(server.newReceiver(), event); err != nil {
+		t.Fatalf("TestEmailHandleEvent: unexpected error: %s", err)
+	}
+
+	mails := server.getMails()
+	if len(mails) != 1 {
+		t.Fatalf("TestEmailHandleEvent: expected a single mail but got %d", len(mails))
+	}
+
+	if mails[0].from != "kubeobserver@mock.com" || len(mails[0].to) != 1 || mails[0].to[0] != "sre@mock.com" {
+		t.Errorf("TestEmailHandleEvent: unexpected sender or recipients %+v", mails[0])
+	}
+
+	if server.getAuthCount() != 1 {
+		t.Errorf("TestEmailHandleEvent: expected a single authentication but got %d", server.getAuthCount())
+	}
+
+	subject, text, html := readMailParts(t, mails[0].data)
+
+	if subject != "[kubeobserver] mockCluster cluster: Pod mockNamespace/mockPod Deleted" {
+		t.Errorf("TestEmailHandleEvent: unexpected subject %s", subject)
+	}
+
+	if !strings.Contains(text, "The pod `mockPod` has been deleted") || !strings.Contains(text, "Cluster: mockCluster") {
+		t.Errorf("TestEmailHandleEvent: unexpected plain text part %s", text)
+	}
+
+	if !strings.Contains(html, "<code>mockPod</code>") || strings.Contains(html, "<script>") {
+		t.Errorf("TestEmailHandleEvent: unexpected html part %s", html)
+	}
+}
+
+func TestEmailRecipientsAnnotation(t *testing.T) {
+	server := newMockSMTPServer(t)
+	defer server.listener.Close()
+
+	event := ReceiverEvent{Kind: "Pod", Name: "mockPod", Severity: InfoSeverity,
+		Annotations: map[string]string{emailRecipientsAnnotationName: "first@mock.com, second@mock.com"}}
+
+	if err := handleEmailEvent(server.newReceiver(), event); err != nil {
+		t.Fatalf("TestEmailRecipientsAnnotation: unexpected error: %s", err)
+	}
+
+	mails := server.getMails()
+	if len(mails) != 1 || strings.Join(mails[0].to, ",") != "first@mock.com,second@mock.com" {
+		t.Errorf("TestEmailRecipientsAnnotation: expected the annotation recipients to replace the default ones. got %+v", mails)
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	server := newMockSMTPServer(t)
+	defer server.listener.Close()
+
+	receiver := server.newReceiver()
+	receiver.DigestInterval = time.Hour
+	receiver.To = []string{"digest@mock.com"}
+
+	for _, name := range []string{"firstPod", "secondPod", "thirdPod"} {
+		if err := handleEmailEvent(receiver, ReceiverEvent{Cluster: "mockCluster", Kind: "Pod", Name: name, Reason: "Deleted", Severity: WarningSeverity}); err != nil {
+			t.Fatalf("TestEmailDigest: unexpected error: %s", err)
+		}
+	}
+
+	if mails := server.getMails(); len(mails) != 0 {
+		t.Fatalf("TestEmailDigest: events should wait for the digest but %d mails were sent", len(mails))
+	}
+
+	receiver.sendDigests()
+
+	mails := server.getMails()
+	if len(mails) != 1 {
+		t.Fatalf("TestEmailDigest: expected a single digest mail but got %d", len(mails))
+	}
+
+	subject, text, _ := readMailParts(t, mails[0].data)
+	if subject != "[kubeobserver] mockCluster cluster: 3 events" {
+		t.Errorf("TestEmailDigest: unexpected subject %s", subject)
+	}
+
+	for _, name := range []string{"firstPod", "secondPod", "thirdPod"} {
+		if !strings.Contains(text, name) {
+			t.Errorf("TestEmailDigest: expected the digest to contain %s", name)
+		}
+	}
+}
+
+func TestEmailDigestRetry(t *testing.T) {
+	server := newMockSMTPServer(t)
+	defer server.listener.Close()
+
+	// the mock server doesn't support STARTTLS, so the digest fails to be sent
+	receiver := server.newReceiver()
+	receiver.DigestInterval = time.Hour
+	receiver.To = []string{"retry@mock.com"}
+	receiver.StartTLS = true
+
+	failures := testutil.ToFloat64(metrics.ReceiverEvents.WithLabelValues(emailReceiverName, "failure"))
+
+	if err := handleEmailEvent(receiver, ReceiverEvent{Cluster: "mockCluster", Kind: "Pod", Name: "firstPod", Reason: "Deleted"}); err != nil {
+		t.Fatalf("TestEmailDigestRetry: unexpected error: %s", err)
+	}
+
+	receiver.sendDigests()
+
+	if got := testutil.ToFloat64(metrics.ReceiverEvents.WithLabelValues(emailReceiverName, "failure")); got != failures+1 {
+		t.Errorf("TestEmailDigestRetry: expected the failed digest to be counted, got %v failures", got-failures)
+	}
+
+	receiver.StartTLS = false
+
+	if err := handleEmailEvent(receiver, ReceiverEvent{Cluster: "mockCluster", Kind: "Pod", Name: "secondPod", Reason: "Deleted"}); err != nil {
+		t.Fatalf("TestEmailDigestRetry: unexpected error: %s", err)
+	}
+
+	receiver.sendDigests()
+
+	mails := server.getMails()
+	if len(mails) != 1 {
+		t.Fatalf("TestEmailDigestRetry: expected a single digest mail but got %d", len(mails))
+	}
+
+	_, text, _ := readMailParts(t, mails[0].data)
+	if strings.Index(text, "firstPod") < 0 || strings.Index(text, "firstPod") > strings.Index(text, "secondPod") {
+		t.Error("TestEmailDigestRetry: expected the failed events to be sent before the later ones")
+	}
+}
+
+func TestEmailStartTLSNotSupported(t *testing.T) {
+	server := newMockSMTPServer(t)
+	defer server.listener.Close()
+
+	receiver := server.newReceiver()
+	receiver.StartTLS = true
+
+	if err := handleEmailEvent(receiver, ReceiverEvent{Kind: "Pod", Name: "mockPod"}); err == nil {
+		t.Error("TestEmailStartTLSNotSupported: should receive an error when the server doesn't support STARTTLS")
+	}
+
+	if server.getAuthCount() != 0 || len(server.getMails()) != 0 {
+		t.Error("TestEmailStartTLSNotSupported: credentials and mails should not be sent without STARTTLS")
+	}
+}
+
+func TestEmailWithoutConfiguration(t *testing.T) {
+	if err := handleEmailEvent(&EmailReceiver{}, ReceiverEvent{Kind: "Pod", Name: "mockPod"}); err == nil {
+		t.Error("TestEmailWithoutConfiguration: should receive an error when no smtp server is configured")
+	}
+}
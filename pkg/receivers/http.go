@@ -0,0 +1,63 @@
+package receivers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxErrorBodySize is the size of the response body that is added to the error of a failed request
+const maxErrorBodySize = 512
+
+// retryRequest calls the request function until it succeeds, fails with an error that is not worth
+// retrying, or the retries are exhausted. the backoff between the attempts grows exponentially
+func retryRequest(receiverName string, url string, retries int, backoff time.Duration, request func() (bool, error)) error {
+	var err error
+
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			log.Debug().Msg(fmt.Sprintf("retrying %s request to %s in %v. attempt %d out of %d", receiverName, url, backoff, attempt, retries))
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var retryable bool
+		if retryable, err = request(); err == nil || !retryable {
+			return err
+		}
+	}
+
+	return err
+}
+
+// doRequest sends the request and returns whether a failure is worth retrying.
+// network errors, 5xx and 429 responses are worth retrying
+func doRequest(client *http.Client, req *http.Request, receiverName string) (bool, error) {
+	url := req.URL.String()
+
+	res, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		log.Debug().Msg(fmt.Sprintf("Successfully posted event to %s %s", receiverName, url))
+		return false, nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	retryable := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+	err = fmt.Errorf("%s %s responded with status code %d", receiverName, url, res.StatusCode)
+
+	if details := strings.TrimSpace(string(body)); details != "" {
+		err = fmt.Errorf("%s: %s", err, details)
+	}
+
+	return retryable, err
+}
@@ -0,0 +1,285 @@
+package receivers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/rs/zerolog/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+var kafkaReceiverName = "kafka"
+
+// kafkaMaxAttempts is the number of times the writer tries to publish a message before
+// the delivery fails and is retried by the controller
+const kafkaMaxAttempts = 3
+
+// kafkaProducer publishes messages and returns once the brokers acknowledged them
+type kafkaProducer interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+}
+
+// KafkaReceiver is a struct built for receiving and passing onward events as JSON messages to a kafka topic.
+// the messages are keyed by the resource, so the events of a resource keep their order on its partition
+type KafkaReceiver struct {
+	Producer kafkaProducer
+	Topic    string
+
+	// ConfigError is the reason the producer couldn't be built from the configuration
+	ConfigError error
+}
+
+// kafkaProducers holds the producer of the current configuration. it lives across receivers reloads,
+// so the connections and the pending batches are kept as long as the kafka configuration is the same
+var kafkaProducers = struct {
+	sync.Mutex
+	settings kafkaSettings
+	producer *kafka.Writer
+}{}
+
+// kafkaKeyLocks serializes the messages of each key. a message is published, including the retries of
+// the writer, before the next message of its resource, so a failure never lets a later event overtake it
+var kafkaKeyLocks = struct {
+	sync.Mutex
+	locks map[string]*kafkaKeyLock
+}{locks: make(map[string]*kafkaKeyLock)}
+
+type kafkaKeyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// kafkaSettings are the configuration values the producer is built from
+type kafkaSettings struct {
+	brokers       string
+	topic         string
+	tls           bool
+	tlsCAFile     string
+	saslMechanism string
+	saslUsername  string
+	saslPassword  string
+	batchSize     int
+	batchTimeout  time.Duration
+}
+
+func init() {
+	registerReceiver(kafkaReceiverName, newKafkaReceiver)
+}
+
+func newKafkaReceiver() Receiver {
+	settings := kafkaSettings{
+		brokers:       strings.Join(config.KafkaBrokers(), ","),
+		topic:         config.KafkaTopic(),
+		tls:           config.KafkaTLS(),
+		tlsCAFile:     config.KafkaTLSCAFile(),
+		saslMechanism: config.KafkaSASLMechanism(),
+		saslUsername:  config.KafkaSASLUsername(),
+		saslPassword:  config.KafkaSASLPassword(),
+		batchSize:     config.KafkaBatchSize(),
+		batchTimeout:  config.KafkaBatchTimeout(),
+	}
+
+	receiver := &KafkaReceiver{Topic: settings.topic}
+
+	// kafka receiver is optional, there is nothing to build without brokers and topic
+	if settings.brokers == "" || settings.topic == "" {
+		return receiver
+	}
+
+	producer, err := getKafkaProducer(settings)
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("couldn't build kafka receiver producer: %s", err.Error()))
+		receiver.ConfigError = err
+
+		return receiver
+	}
+
+	receiver.Producer = producer
+
+	return receiver
+}
+
+// getKafkaProducer returns the producer of the given settings. the producer of the previous
+// settings is closed once its pending messages are sent
+func getKafkaProducer(settings kafkaSettings) (*kafka.Writer, error) {
+	kafkaProducers.Lock()
+	defer kafkaProducers.Unlock()
+
+	if kafkaProducers.producer != nil && kafkaProducers.settings == settings {
+		return kafkaProducers.producer, nil
+	}
+
+	dialer := &kafka.Dialer{ClientID: "kubeobserver", Timeout: 10 * time.Second, DualStack: true}
+
+	if settings.tls {
+		tlsConfig, err := kafkaTLSConfig(settings.tlsCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		dialer.TLS = tlsConfig
+	}
+
+	if settings.saslMechanism != "" {
+		mechanism, err := kafkaSASLMechanism(settings.saslMechanism, settings.saslUsername, settings.saslPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		dialer.SASLMechanism = mechanism
+	}
+
+	// murmur2 keeps the messages of a key on the partition the java producers would pick, and
+	// the messages are acknowledged by all of the in-sync replicas
+	producer := kafka.NewWriter(kafka.WriterConfig{
+		Brokers:      strings.Split(settings.brokers, ","),
+		Topic:        settings.topic,
+		Dialer:       dialer,
+		Balancer:     kafka.Murmur2Balancer{},
+		BatchSize:    settings.batchSize,
+		BatchTimeout: settings.batchTimeout,
+		MaxAttempts:  kafkaMaxAttempts,
+		RequiredAcks: -1,
+	})
+
+	if previous := kafkaProducers.producer; previous != nil {
+		go previous.Close()
+	}
+
+	kafkaProducers.settings = settings
+	kafkaProducers.producer = producer
+
+	return producer, nil
+}
+
+func kafkaSASLMechanism(name string, username string, password string) (sasl.Mechanism, error) {
+	switch name {
+	case "PLAIN":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "SCRAM-SHA-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "SCRAM-SHA-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, fmt.Errorf("unsupported kafka SASL mechanism %s", name)
+	}
+}
+
+func kafkaTLSConfig(caFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read kafka CA file -> %s", err.Error())
+	}
+
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("kafka CA file %s has no valid certificates", caFile)
+	}
+
+	return tlsConfig, nil
+}
+
+// HandleEvent is an implementation of the Receiver interface for kafka
+func (kr *KafkaReceiver) HandleEvent(receiverEvent ReceiverEvent, c chan error) {
+	// no matter what happens, close the channel after function exits
+	defer close(c)
+
+	receiverEvent.Message = renderMessage(kafkaReceiverName, receiverEvent)
+
+	if kr.ConfigError != nil {
+		c <- fmt.Errorf("HandleEvent of kafka was triggered but the configuration is invalid -> %s", kr.ConfigError.Error())
+		return
+	}
+
+	// this will be true in case some event has kafka receiver
+	// but no brokers or topic were provided in the configuration
+	if kr.Producer == nil || kr.Topic == "" {
+		c <- errors.New("HandleEvent of kafka was triggered but no kafka brokers or topic were found in configuration")
+		return
+	}
+
+	log.Debug().Msg(fmt.Sprintf("received %s message in kafka receiver: %s", receiverEvent.EventName, receiverEvent.Message))
+
+	message, err := buildKafkaMessage(receiverEvent)
+	if err != nil {
+		c <- fmt.Errorf("kafka receiver couldn't marshal the event -> %s", err.Error())
+		return
+	}
+
+	unlock := lockKafkaKey(string(message.Key))
+	defer unlock()
+
+	// brokers may be unavailable or leaders may move, the writer retries these failures before it returns
+	// and the controller retries the delivery after that, keeping the order of the resource events
+	if err := kr.Producer.WriteMessages(context.Background(), message); err != nil {
+		c <- &RetryableError{Err: fmt.Errorf("kafka receiver got unexpected error -> %s", err.Error())}
+	}
+}
+
+// lockKafkaKey waits for the messages of the key that are being published and returns the function that releases the key
+func lockKafkaKey(key string) func() {
+	kafkaKeyLocks.Lock()
+	lock, ok := kafkaKeyLocks.locks[key]
+	if !ok {
+		lock = &kafkaKeyLock{}
+		kafkaKeyLocks.locks[key] = lock
+	}
+	lock.refs++
+	kafkaKeyLocks.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		kafkaKeyLocks.Lock()
+		defer kafkaKeyLocks.Unlock()
+
+		if lock.refs--; lock.refs == 0 {
+			delete(kafkaKeyLocks.locks, key)
+		}
+	}
+}
+
+func buildKafkaMessage(receiverEvent ReceiverEvent) (kafka.Message, error) {
+	value, err := json.Marshal(receiverEvent)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	timestamp := receiverEvent.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	return kafka.Message{
+		Key:     []byte(kafkaMessageKey(receiverEvent)),
+		Value:   value,
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/json")}},
+		Time:    timestamp,
+	}, nil
+}
+
+// kafkaMessageKey is the cluster, namespace and name of the event resource. cluster scoped
+// resources like nodes have an empty namespace
+func kafkaMessageKey(receiverEvent ReceiverEvent) string {
+	return strings.Join([]string{receiverEvent.Cluster, receiverEvent.Namespace, receiverEvent.Name}, "/")
+}
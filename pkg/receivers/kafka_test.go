@@ -0,0 +1,131 @@
+package receivers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type mockKafkaProducer struct {
+	lock     sync.Mutex
+	messages []kafka.Message
+	err      error
+}
+
+func (p *mockKafkaProducer) WriteMessages(ctx context.Context, messages ...kafka.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.messages = append(p.messages, messages...)
+
+	return nil
+}
+
+func handleKafkaEvent(receiver *KafkaReceiver, event ReceiverEvent) error {
+	c := make(chan error)
+	go receiver.HandleEvent(event, c)
+
+	return <-c
+}
+
+func TestKafkaHandleEvent(t *testing.T) {
+	producer := &mockKafkaProducer{}
+	receiver := &KafkaReceiver{Producer: producer, Topic: "events"}
+
+	timestamp := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := ReceiverEvent{EventName: UpdateEvent, Cluster: "mockCluster", Kind: "Pod", Namespace: "mockNamespace",
+		Name: "mockPod", Reason: "CrashLoopBackOff", Severity: CriticalSeverity, Timestamp: timestamp}
+
+	if err := handleKafkaEvent(receiver, event); err != nil {
+		t.Fatalf("TestKafkaHandleEvent: unexpected error: %s", err)
+	}
+
+	if len(producer.messages) != 1 {
+		t.Fatalf("TestKafkaHandleEvent: expected a single message but got %d", len(producer.messages))
+	}
+
+	message := producer.messages[0]
+	if string(message.Key) != "mockCluster/mockNamespace/mockPod" || !message.Time.Equal(timestamp) {
+		t.Errorf("TestKafkaHandleEvent: unexpected message key %s or time %s", string(message.Key), message.Time)
+	}
+
+	var published ReceiverEvent
+	if err := json.Unmarshal(message.Value, &published); err != nil || published.Reason != "CrashLoopBackOff" || published.Kind != "Pod" {
+		t.Errorf("TestKafkaHandleEvent: unexpected message value %s. err: %v", string(message.Value), err)
+	}
+}
+
+func TestKafkaProduceFailure(t *testing.T) {
+	receiver := &KafkaReceiver{Producer: &mockKafkaProducer{err: errors.New("broker is down")}, Topic: "events"}
+
+	if err := handleKafkaEvent(receiver, ReceiverEvent{Kind: "Pod", Name: "mockPod"}); !IsRetryable(err) {
+		t.Errorf("TestKafkaProduceFailure: expected the writer error to be retried by the controller, got %v", err)
+	}
+}
+
+func TestLockKafkaKey(t *testing.T) {
+	unlock := lockKafkaKey("mockCluster/mockNamespace/mockPod")
+
+	locked := make(chan struct{})
+	go func() {
+		defer lockKafkaKey("mockCluster/mockNamespace/mockPod")()
+		close(locked)
+	}()
+
+	// other keys are published while the key is locked
+	lockKafkaKey("mockCluster/mockNamespace/otherPod")()
+
+	select {
+	case <-locked:
+		t.Fatal("TestLockKafkaKey: the next message of the key shouldn't be published before the current one")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	<-locked
+
+	kafkaKeyLocks.Lock()
+	defer kafkaKeyLocks.Unlock()
+
+	if len(kafkaKeyLocks.locks) != 0 {
+		t.Errorf("TestLockKafkaKey: expected the released keys to be removed but got %d", len(kafkaKeyLocks.locks))
+	}
+}
+
+func TestKafkaWithoutConfiguration(t *testing.T) {
+	if err := handleKafkaEvent(&KafkaReceiver{}, ReceiverEvent{Kind: "Pod", Name: "mockPod"}); err == nil {
+		t.Error("TestKafkaWithoutConfiguration: expected a configuration error")
+	}
+}
+
+func TestGetKafkaProducer(t *testing.T) {
+	settings := kafkaSettings{brokers: "localhost:9092", topic: "events"}
+
+	first, err := getKafkaProducer(settings)
+	if err != nil {
+		t.Fatalf("TestGetKafkaProducer: unexpected error: %s", err)
+	}
+
+	if same, _ := getKafkaProducer(settings); same != first {
+		t.Error("TestGetKafkaProducer: expected the producer to be reused for the same settings")
+	}
+
+	settings.topic = "other"
+	if other, _ := getKafkaProducer(settings); other == first {
+		t.Error("TestGetKafkaProducer: expected a new producer for new settings")
+	}
+
+	settings.saslMechanism = "GSSAPI"
+	if _, err := getKafkaProducer(settings); err == nil {
+		t.Error("TestGetKafkaProducer: should receive an error for an unsupported SASL mechanism")
+	}
+}
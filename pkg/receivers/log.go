@@ -12,10 +12,17 @@ var logReceiverName = "log"
 type LogReceiver struct{}
 
 func init() {
-	ReceiverMap[logReceiverName] = &LogReceiver{}
+	registerReceiver(logReceiverName, func() Receiver {
+		return &LogReceiver{}
+	})
 }
 
 // HandleEvent is an implementation of the Receiver interface for Slack
 func (sr *LogReceiver) HandleEvent(receiverEvent ReceiverEvent, c chan error) {
+	// close the channel so the sender won't wait for this receiver forever
+	defer close(c)
+
+	receiverEvent.Message = renderMessage(logReceiverName, receiverEvent)
+
 	log.Info().Msg(fmt.Sprintf("log recevier event message[%s]", receiverEvent.Message))
 }
@@ -0,0 +1,188 @@
+package receivers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
+var pagerDutyReceiverName = "pagerduty"
+var pagerDutyEnqueuePath = "/v2/enqueue"
+
+const (
+	pagerDutyTriggerAction = "trigger"
+	pagerDutyResolveAction = "resolve"
+)
+
+// PagerDutyReceiver is a struct built for triggering and resolving PagerDuty incidents
+// with the Events API v2. critical events trigger an incident and recovery events resolve it
+type PagerDutyReceiver struct {
+	URL                  string
+	RoutingKey           string
+	NamespaceRoutingKeys map[string]string
+	Retries              int
+	RetryBackoff         time.Duration
+	HTTPClient           *http.Client
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Timestamp     string         `json:"timestamp,omitempty"`
+	Component     string         `json:"component,omitempty"`
+	Group         string         `json:"group,omitempty"`
+	Class         string         `json:"class,omitempty"`
+	CustomDetails *ReceiverEvent `json:"custom_details,omitempty"`
+}
+
+func init() {
+	registerReceiver(pagerDutyReceiverName, newPagerDutyReceiver)
+}
+
+func newPagerDutyReceiver() Receiver {
+	return &PagerDutyReceiver{
+		URL:                  config.PagerDutyURL(),
+		RoutingKey:           config.PagerDutyRoutingKey(),
+		NamespaceRoutingKeys: config.PagerDutyNamespaceRoutingKeys(),
+		Retries:              config.WebhookRetries(),
+		RetryBackoff:         time.Second,
+		HTTPClient:           &http.Client{Timeout: config.WebhookTimeout()},
+	}
+}
+
+// HandleEvent is an implementation of the Receiver interface for PagerDuty
+func (pr *PagerDutyReceiver) HandleEvent(receiverEvent ReceiverEvent, c chan error) {
+	// no matter what happens, close the channel after function exits
+	defer close(c)
+
+	receiverEvent.Message = renderMessage(pagerDutyReceiverName, receiverEvent)
+
+	events := pr.buildPagerDutyEvents(receiverEvent)
+
+	// events that are neither critical nor a recovery are not worth paging anyone
+	if len(events) == 0 {
+		log.Debug().Msg(fmt.Sprintf("pagerduty receiver: ignoring %s event of %s [%s]", receiverEvent.Reason, receiverEvent.Kind, receiverEvent.Name))
+		return
+	}
+
+	routingKey := pr.getRoutingKey(receiverEvent.Namespace)
+	if routingKey == "" {
+		c <- fmt.Errorf("HandleEvent of pagerduty was triggered but no routing key was found in configuration for namespace '%s'", receiverEvent.Namespace)
+		return
+	}
+
+	url := strings.TrimSuffix(pr.URL, "/") + pagerDutyEnqueuePath
+	errorsStr := make([]string, 0)
+
+	for _, event := range events {
+		event.RoutingKey = routingKey
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			errorsStr = append(errorsStr, err.Error())
+			continue
+		}
+
+		err = retryRequest(pagerDutyReceiverName, url, pr.Retries, pr.RetryBackoff, func() (bool, error) {
+			return pr.post(url, payload)
+		})
+
+		if err != nil {
+			errorsStr = append(errorsStr, err.Error())
+		}
+	}
+
+	// the receivers contract allows a single error per event
+	if len(errorsStr) > 0 {
+		c <- fmt.Errorf("pagerduty receiver got unexpected error -> %s", strings.Join(errorsStr, "; "))
+	}
+}
+
+// buildPagerDutyEvents returns a trigger for a critical event, or a resolve
+// for each of the incidents a recovery event resolves. an aggregated summary never triggers,
+// since the first event of its group has already triggered an incident that it would never resolve
+func (pr *PagerDutyReceiver) buildPagerDutyEvents(receiverEvent ReceiverEvent) []pagerDutyEvent {
+	if receiverEvent.Severity == CriticalSeverity {
+		if isAggregated(receiverEvent) {
+			return nil
+		}
+
+		return []pagerDutyEvent{{
+			EventAction: pagerDutyTriggerAction,
+			DedupKey:    incidentKey(receiverEvent, receiverEvent.Reason),
+			Payload:     buildPagerDutyPayload(receiverEvent),
+		}}
+	}
+
+	events := make([]pagerDutyEvent, 0)
+
+	for _, event := range summarizedEvents(receiverEvent) {
+		for _, reason := range resolvedReasons[event.Kind][event.Reason] {
+			events = append(events, pagerDutyEvent{
+				EventAction: pagerDutyResolveAction,
+				DedupKey:    incidentKey(event, reason),
+			})
+		}
+	}
+
+	return events
+}
+
+func buildPagerDutyPayload(receiverEvent ReceiverEvent) *pagerDutyPayload {
+	resource := receiverEvent.Name
+	if receiverEvent.Namespace != "" {
+		resource = receiverEvent.Namespace + "/" + receiverEvent.Name
+	}
+
+	timestamp := ""
+	if !receiverEvent.Timestamp.IsZero() {
+		timestamp = receiverEvent.Timestamp.Format(time.RFC3339)
+	}
+
+	return &pagerDutyPayload{
+		Summary:       fmt.Sprintf("%s [%s] %s in %s cluster", receiverEvent.Kind, resource, receiverEvent.Reason, receiverEvent.Cluster),
+		Source:        receiverEvent.Cluster,
+		Severity:      string(CriticalSeverity),
+		Timestamp:     timestamp,
+		Component:     resource,
+		Group:         receiverEvent.Namespace,
+		Class:         receiverEvent.Kind,
+		CustomDetails: &receiverEvent,
+	}
+}
+
+// getRoutingKey returns the routing key of the namespace, or the default routing key
+func (pr *PagerDutyReceiver) getRoutingKey(namespace string) string {
+	if routingKey := pr.NamespaceRoutingKeys[namespace]; routingKey != "" {
+		return routingKey
+	}
+
+	return pr.RoutingKey
+}
+
+// post sends a single request and returns whether a failure is worth retrying
+func (pr *PagerDutyReceiver) post(url string, payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+
+	return doRequest(pr.HTTPClient, req, pagerDutyReceiverName)
+}
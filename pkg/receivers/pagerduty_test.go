@@ -0,0 +1,202 @@
+package receivers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newMockPagerDutyReceiver(url string) *PagerDutyReceiver {
+	return &PagerDutyReceiver{
+		URL:                  url,
+		RoutingKey:           "defaultKey",
+		NamespaceRoutingKeys: map[string]string{"payments": "paymentsKey"},
+		Retries:              2,
+		RetryBackoff:         time.Millisecond,
+		HTTPClient:           &http.Client{Timeout: time.Second},
+	}
+}
+
+// newMockPagerDutyServer returns a server that records the events it receives
+func newMockPagerDutyServer(t *testing.T, events *[]pagerDutyEvent) *httptest.Server {
+	var lock sync.Mutex
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pagerDutyEnqueuePath {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		event := pagerDutyEvent{}
+
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("unexpected payload %s. err: %v", string(body), err)
+		}
+
+		lock.Lock()
+		*events = append(*events, event)
+		lock.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+}
+
+func TestPagerDutyTrigger(t *testing.T) {
+	events := make([]pagerDutyEvent, 0)
+	server := newMockPagerDutyServer(t, &events)
+	defer server.Close()
+
+	event := ReceiverEvent{EventName: UpdateEvent, Cluster: "mockCluster", Kind: "Pod", Namespace: "payments", Name: "mockPod",
+		Severity: CriticalSeverity, Reason: "CrashLoopBackOff", Timestamp: time.Now()}
+
+	c := make(chan error)
+	go newMockPagerDutyReceiver(server.URL).HandleEvent(event, c)
+
+	if err := <-c; err != nil {
+		t.Fatalf("TestPagerDutyTrigger: unexpected error: %s", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("TestPagerDutyTrigger: expected a single event but got %d", len(events))
+	}
+
+	received := events[0]
+	if received.EventAction != pagerDutyTriggerAction || received.RoutingKey != "paymentsKey" {
+		t.Errorf("TestPagerDutyTrigger: expected a trigger with the namespace routing key. got %+v", received)
+	}
+
+	if received.DedupKey != "mockCluster/Pod/payments/mockPod/CrashLoopBackOff" {
+		t.Errorf("TestPagerDutyTrigger: unexpected dedup key %s", received.DedupKey)
+	}
+
+	if received.Payload == nil || received.Payload.Severity != "critical" || received.Payload.Source != "mockCluster" || received.Payload.Summary == "" {
+		t.Errorf("TestPagerDutyTrigger: unexpected payload %+v", received.Payload)
+	}
+}
+
+func TestPagerDutyResolve(t *testing.T) {
+	events := make([]pagerDutyEvent, 0)
+	server := newMockPagerDutyServer(t, &events)
+	defer server.Close()
+
+	event := ReceiverEvent{EventName: UpdateEvent, Cluster: "mockCluster", Kind: "Node", Name: "mockNode",
+		Severity: InfoSeverity, Reason: "NodeReady"}
+
+	c := make(chan error)
+	go newMockPagerDutyReceiver(server.URL).HandleEvent(event, c)
+
+	if err := <-c; err != nil {
+		t.Fatalf("TestPagerDutyResolve: unexpected error: %s", err)
+	}
+
+	expectedKeys := map[string]bool{"mockCluster/Node//mockNode/NodeNotReady": true, "mockCluster/Node//mockNode/NodeUnknown": true}
+	if len(events) != len(expectedKeys) {
+		t.Fatalf("TestPagerDutyResolve: expected %d events but got %d", len(expectedKeys), len(events))
+	}
+
+	for _, received := range events {
+		if received.EventAction != pagerDutyResolveAction || !expectedKeys[received.DedupKey] || received.RoutingKey != "defaultKey" || received.Payload != nil {
+			t.Errorf("TestPagerDutyResolve: unexpected event %+v", received)
+		}
+	}
+}
+
+func TestPagerDutyAggregatedEvents(t *testing.T) {
+	events := make([]pagerDutyEvent, 0)
+	server := newMockPagerDutyServer(t, &events)
+	defer server.Close()
+
+	pr := newMockPagerDutyReceiver(server.URL)
+	aggregatedInfo := map[string]interface{}{"aggregated_events": 2, "aggregated_resources": 2, "aggregated_names": []string{"checkout-1", "checkout-2"}}
+
+	// the summary is named after the deployment, so a trigger would never be resolved
+	c := make(chan error)
+	go pr.HandleEvent(ReceiverEvent{EventName: UpdateEvent, Cluster: "mockCluster", Kind: "Pod", Namespace: "payments", Name: "checkout",
+		Severity: CriticalSeverity, Reason: "CrashLoopBackOff", AdditionalInfo: aggregatedInfo}, c)
+
+	if err := <-c; err != nil || len(events) != 0 {
+		t.Fatalf("TestPagerDutyAggregatedEvents: expected no trigger for a summary but got %d events. err: %v", len(events), err)
+	}
+
+	c = make(chan error)
+	go pr.HandleEvent(ReceiverEvent{EventName: UpdateEvent, Cluster: "mockCluster", Kind: "Pod", Namespace: "payments", Name: "checkout",
+		Severity: InfoSeverity, Reason: "Started", AdditionalInfo: aggregatedInfo}, c)
+
+	if err := <-c; err != nil {
+		t.Fatalf("TestPagerDutyAggregatedEvents: unexpected error: %s", err)
+	}
+
+	expectedKeys := map[string]bool{
+		"mockCluster/Pod/payments/checkout-1/CrashLoopBackOff": true, "mockCluster/Pod/payments/checkout-1/OOMKilled": true,
+		"mockCluster/Pod/payments/checkout-2/CrashLoopBackOff": true, "mockCluster/Pod/payments/checkout-2/OOMKilled": true,
+	}
+
+	if len(events) != len(expectedKeys) {
+		t.Fatalf("TestPagerDutyAggregatedEvents: expected %d resolves but got %d", len(expectedKeys), len(events))
+	}
+
+	for _, received := range events {
+		if received.EventAction != pagerDutyResolveAction || !expectedKeys[received.DedupKey] {
+			t.Errorf("TestPagerDutyAggregatedEvents: unexpected event %+v", received)
+		}
+	}
+}
+
+func TestPagerDutyIgnoredEvent(t *testing.T) {
+	events := make([]pagerDutyEvent, 0)
+	server := newMockPagerDutyServer(t, &events)
+	defer server.Close()
+
+	c := make(chan error)
+	go newMockPagerDutyReceiver(server.URL).HandleEvent(ReceiverEvent{EventName: AddEvent, Kind: "Pod", Reason: "Created", Severity: InfoSeverity}, c)
+
+	if err := <-c; err != nil {
+		t.Errorf("TestPagerDutyIgnoredEvent: unexpected error: %s", err)
+	}
+
+	if len(events) != 0 {
+		t.Errorf("TestPagerDutyIgnoredEvent: expected no events but got %d", len(events))
+	}
+}
+
+func TestPagerDutyRetries(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	c := make(chan error)
+	go newMockPagerDutyReceiver(server.URL).HandleEvent(ReceiverEvent{Kind: "Deployment", Reason: "ProgressDeadlineExceeded", Severity: CriticalSeverity}, c)
+
+	if err := <-c; err != nil {
+		t.Errorf("TestPagerDutyRetries: request should succeed on the third attempt. err: %s", err)
+	}
+
+	if requests != 3 {
+		t.Errorf("TestPagerDutyRetries: expected 3 requests but got %d", requests)
+	}
+}
+
+func TestPagerDutyWithoutRoutingKey(t *testing.T) {
+	receiver := newMockPagerDutyReceiver("http://localhost")
+	receiver.RoutingKey = ""
+
+	c := make(chan error)
+	go receiver.HandleEvent(ReceiverEvent{Kind: "Pod", Namespace: "mockNamespace", Reason: "OOMKilled", Severity: CriticalSeverity}, c)
+
+	if err := <-c; err == nil {
+		t.Error("TestPagerDutyWithoutRoutingKey: should receive an error when no routing key is configured")
+	}
+}
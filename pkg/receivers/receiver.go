@@ -1,30 +1,281 @@
 package receivers
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
 type EventName string
 
 const (
 	// AddEvent is bla
 	AddEvent EventName = "Add"
 	// DeleteEvent is bla
-	DeleteEvent EventName = "EventName"
+	DeleteEvent EventName = "Delete"
 	// UpdateEvent is bla
 	UpdateEvent EventName = "Update"
 )
 
-// ReceiverMap is a global map that map receiver name to he's specific struct
-// each 'Receiver' interface implementation should add himself to this map with an init function that will
-// automatically be called at the start of the application
+// Severity describes how urgent it is to act upon an event
+type Severity string
+
+const (
+	// InfoSeverity is used for events that require no action
+	InfoSeverity Severity = "info"
+	// WarningSeverity is used for events that might require an action
+	WarningSeverity Severity = "warning"
+	// CriticalSeverity is used for events that require an immediate action
+	CriticalSeverity Severity = "critical"
+)
+
+// resolvedReasons maps the reasons of recovery events to the reasons of the conditions they resolve, by kind.
+// a deleted resource resolves all of its conditions, since it can't recover anymore.
+// receivers that keep track of incidents or alerts use it to close them
+var resolvedReasons = map[string]map[string][]string{
+	"Pod": {
+		"Started": {"CrashLoopBackOff", "OOMKilled"},
+		"Deleted": {"CrashLoopBackOff", "OOMKilled"},
+	},
+	"Deployment": {
+		"RolloutCompleted": {"ProgressDeadlineExceeded"},
+	},
+	"Node": {
+		"NodeReady":              {"NodeNotReady", "NodeUnknown"},
+		"MemoryPressureResolved": {"MemoryPressure"},
+		"DiskPressureResolved":   {"DiskPressure"},
+		"PIDPressureResolved":    {"PIDPressure"},
+		"NodeUncordoned":         {"NodeCordoned"},
+		"Deleted":                {"NodeNotReady", "NodeUnknown", "MemoryPressure", "DiskPressure", "PIDPressure", "NodeCordoned"},
+	},
+	"Job": {
+		"JobCompleted": {"JobRunningTooLong"},
+	},
+}
+
+// isResolvable returns true when the condition of the given kind and reason has a recovery event
+func isResolvable(kind string, reason string) bool {
+	for _, resolved := range resolvedReasons[kind] {
+		for _, r := range resolved {
+			if r == reason {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// incidentKey identifies a condition of a resource, so the same condition is deduplicated
+// while it lasts and can be resolved when the resource recovers
+func incidentKey(receiverEvent ReceiverEvent, reason string) string {
+	return strings.Join([]string{receiverEvent.Cluster, receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name, reason}, "/")
+}
+
+// isAggregated returns true when the event is a summary of aggregated events
+func isAggregated(receiverEvent ReceiverEvent) bool {
+	_, ok := receiverEvent.AdditionalInfo["aggregated_events"]
+
+	return ok
+}
+
+// summarizedEvents returns an event for each of the resources an aggregated event summarizes,
+// since the name of the summary may be the name of their controller. other events are returned as is
+func summarizedEvents(receiverEvent ReceiverEvent) []ReceiverEvent {
+	names, ok := receiverEvent.AdditionalInfo["aggregated_names"].([]string)
+	if !ok {
+		return []ReceiverEvent{receiverEvent}
+	}
+
+	events := make([]ReceiverEvent, 0, len(names))
+	for _, name := range names {
+		event := receiverEvent
+		event.Name = name
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// ReceiverMap is a global map that map receiver name to he's specific struct.
+// each 'Receiver' interface implementation should register himself using registerReceiver with an init function,
+// and the receivers are built from the configuration by LoadReceivers when the application starts
 var ReceiverMap = make(map[string]Receiver)
 
+// receiverFactories map receiver name to a function that builds the default receiver of its type from the
+// current configuration. it is used to build the receivers again when the configuration is reloaded
+var receiverFactories = make(map[string]func() Receiver)
+
+// instanceFactories map receiver type to a function that builds a named receiver instance of the type
+var instanceFactories = make(map[string]func(instance config.ReceiverInstance) Receiver)
+var receiverMapLock sync.RWMutex
+
+// registerReceiver adds the factory of the default receiver with the given name
+func registerReceiver(name string, factory func() Receiver) {
+	receiverFactories[name] = factory
+}
+
+// registerReceiverType adds the factory of the named receiver instances of the given type
+func registerReceiverType(receiverType string, factory func(instance config.ReceiverInstance) Receiver) {
+	instanceFactories[receiverType] = factory
+}
+
+// GetReceiver returns the receiver with the given name, or nil for unknown receivers
+func GetReceiver(name string) Receiver {
+	receiverMapLock.RLock()
+	defer receiverMapLock.RUnlock()
+
+	return ReceiverMap[name]
+}
+
+// NewReceiverInstance builds a named receiver instance from its settings
+func NewReceiverInstance(instance config.ReceiverInstance) (Receiver, error) {
+	factory, ok := instanceFactories[instance.Type]
+	if !ok {
+		return nil, fmt.Errorf("receiver instance %s has unsupported type %s", instance.Name, instance.Type)
+	}
+
+	return factory(instance), nil
+}
+
+// BuildReceivers builds the default receiver of every type from the current configuration and the given
+// named receiver instances. instances named after a default receiver or of an unsupported type are skipped
+func BuildReceivers(instances []config.ReceiverInstance) map[string]Receiver {
+	receiverMap := make(map[string]Receiver)
+
+	for name, factory := range receiverFactories {
+		receiverMap[name] = factory()
+	}
+
+	for _, instance := range instances {
+		if _, ok := receiverMap[instance.Name]; ok {
+			log.Error().Msg(fmt.Sprintf("receiver instance %s is skipped since its name is used by a default receiver", instance.Name))
+			continue
+		}
+
+		receiver, err := NewReceiverInstance(instance)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			continue
+		}
+
+		receiverMap[instance.Name] = receiver
+	}
+
+	return receiverMap
+}
+
+// LoadReceivers builds all the receivers from the current configuration and replaces
+// the ReceiverMap in one step, so events are never sent to a partially built map
+func LoadReceivers() {
+	receiverMap := BuildReceivers(config.ReceiverInstances())
+
+	receiverMapLock.Lock()
+	defer receiverMapLock.Unlock()
+
+	ReceiverMap = receiverMap
+}
+
+// ReloadReceivers loads the message templates and the receivers again from the current configuration
+func ReloadReceivers() {
+	loadMessageTemplates()
+	LoadReceivers()
+}
+
+// SetLeader is called when the replica becomes the leader or loses the leadership. receivers that keep
+// sending on their own, like the alertmanager alerts resend, do that only while the replica is the leader
+func SetLeader(leader bool) {
+	setAlertmanagerLeader(leader)
+}
+
 // The Receiver interface
 type Receiver interface {
 	HandleEvent(receiverEvent ReceiverEvent, c chan error)
 }
 
+// RetryableError is sent by receivers when the delivery of an event failed but may succeed later.
+// the controller retries these deliveries instead of dropping the event
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+// IsRetryable returns true when the error of a receiver is a RetryableError
+func IsRetryable(err error) bool {
+	var retryableError *RetryableError
+
+	return errors.As(err, &retryableError)
+}
+
+// Owner represent the controller (ReplicaSet, StatefulSet, Job and so on..) of a k8s resource
+type Owner struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ContainerTermination describes the last termination of a container
+type ContainerTermination struct {
+	ExitCode   int32     `json:"exit_code"`
+	Signal     int32     `json:"signal,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	OOMKilled  bool      `json:"oom_killed"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
 // ReceiverEvent represent any processed event
 // from a watcher (pod watcher, config-map watcher and so on..)
+// Message is a human readable rendering of the event, receivers that
+// need to make decisions should use the structured fields instead
 type ReceiverEvent struct {
-	EventName      EventName
-	Message        string
-	AdditionalInfo map[string]interface{}
+	EventName      EventName              `json:"event_name"`
+	Message        string                 `json:"message"`
+	AdditionalInfo map[string]interface{} `json:"additional_info,omitempty"`
+
+	Cluster     string            `json:"cluster"`
+	Kind        string            `json:"kind"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Name        string            `json:"name"`
+	UID         string            `json:"uid,omitempty"`
+	Owner       Owner             `json:"owner"`
+	Severity    Severity          `json:"severity"`
+	Reason      string            `json:"reason,omitempty"`
+	Container   string            `json:"container,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Mentions    []string          `json:"mentions,omitempty"`
+
+	// Template is the name of the message template requested for the event by its route
+	Template string `json:"template,omitempty"`
+
+	// LastTermination and ContainerLogs describe the previous instance of a
+	// crashing container. they are set only on crash loop events
+	LastTermination *ContainerTermination `json:"last_termination,omitempty"`
+	ContainerLogs   string                `json:"container_logs,omitempty"`
+
+	// CreationTimestamp is the creation time of the k8s resource
+	// and Timestamp is the time kubeobserver has processed the event
+	CreationTimestamp time.Time `json:"creation_timestamp"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// Workload returns the controller of the event resource. pods of a deployment are owned by
+// a ReplicaSet, which is resolved to the deployment using the pod-template-hash label
+func (receiverEvent ReceiverEvent) Workload() Owner {
+	owner := receiverEvent.Owner
+
+	if hash := receiverEvent.Labels["pod-template-hash"]; owner.Kind == "ReplicaSet" && hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+		return Owner{Kind: "Deployment", Name: strings.TrimSuffix(owner.Name, "-"+hash)}
+	}
+
+	return owner
 }
@@ -0,0 +1,74 @@
+package receivers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+)
+
+func TestReloadReceivers(t *testing.T) {
+	before := GetReceiver(webhookReceiverName)
+
+	ReloadReceivers()
+
+	after := GetReceiver(webhookReceiverName)
+	if after == nil || after == before {
+		t.Error("Webhook receiver should be rebuilt on reload")
+	}
+
+	if GetReceiver(logReceiverName) == nil || GetReceiver(slackReceiverName) == nil {
+		t.Error("All registered receivers should exist after reload")
+	}
+
+	if GetReceiver("unknown") != nil {
+		t.Error("Unknown receiver should not exist")
+	}
+}
+
+func TestBuildReceiverInstances(t *testing.T) {
+	instances := []config.ReceiverInstance{
+		{Name: "slack-platform", Type: "slack", Slack: config.SlackSettings{Token: "platform-token", Channels: []string{"C0PLATFORM"}}},
+		{Name: "slack-payments", Type: "slack", Slack: config.SlackSettings{Token: "payments-token", Channels: []string{"C0PAYMENTS"}}},
+		{Name: "webhook-audit", Type: "webhook", Webhook: config.WebhookSettings{URLs: []string{"https://audit.internal"}, Timeout: time.Second, Retries: 1}},
+		{Name: "webhook", Type: "webhook", Webhook: config.WebhookSettings{Retries: 7}},
+		{Name: "kafka-audit", Type: "kafka"},
+	}
+
+	receiverMap := BuildReceivers(instances)
+
+	platform, ok := receiverMap["slack-platform"].(*SlackReceiver)
+	if !ok || platform.ChannelNames[0] != "C0PLATFORM" {
+		t.Fatalf("Slack instance should be built from its settings, got %+v", receiverMap["slack-platform"])
+	}
+
+	payments, ok := receiverMap["slack-payments"].(*SlackReceiver)
+	if !ok || payments.ChannelNames[0] != "C0PAYMENTS" || payments.SlackClient == platform.SlackClient {
+		t.Fatalf("Every slack instance should have its own client and channels, got %+v", receiverMap["slack-payments"])
+	}
+
+	if payments.Actions {
+		t.Error("Slack instances shouldn't have action buttons")
+	}
+
+	audit, ok := receiverMap["webhook-audit"].(*WebhookReceiver)
+	if !ok || audit.URLs[0] != "https://audit.internal" || audit.Retries != 1 || audit.HTTPClient.Timeout != time.Second {
+		t.Errorf("Webhook instance should be built from its settings, got %+v", receiverMap["webhook-audit"])
+	}
+
+	if defaultWebhook, ok := receiverMap[webhookReceiverName].(*WebhookReceiver); !ok || defaultWebhook.Retries != config.WebhookRetries() {
+		t.Error("Instance named after a default receiver shouldn't replace it")
+	}
+
+	if receiverMap[slackReceiverName] == nil || receiverMap[logReceiverName] == nil {
+		t.Error("Default receivers should be built next to the instances")
+	}
+
+	if _, ok := receiverMap["kafka-audit"]; ok {
+		t.Error("Instance of an unsupported type should be skipped")
+	}
+
+	if _, err := NewReceiverInstance(config.ReceiverInstance{Name: "kafka-audit", Type: "kafka"}); err == nil {
+		t.Error("NewReceiverInstance should fail for an unsupported type")
+	}
+}
@@ -1,141 +1,523 @@
 package receivers
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PayU/kubeobserver/pkg/common"
 	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/metrics"
 	"github.com/rs/zerolog/log"
 	"github.com/slack-go/slack"
 )
 
 var slackReceiverName = "slack"
 var slackAuthorIcon string = "https://raw.githubusercontent.com/kubernetes/community/master/icons/png/resources/unlabeled/pod-128.png"
-var slackFooterIcon string = "https://avatars2.githubusercontent.com/u/652790"
 var warningIcon string = "https://raw.githubusercontent.com/Keyamoon/IcoMoon-Free/master/PNG/64px/264-warning.png"
 var skullIconsSlackStr string = ":skull_and_crossbones::skull_and_crossbones::skull_and_crossbones::skull_and_crossbones::skull_and_crossbones::skull_and_crossbones:"
 
+// SlackChannelsAnnotationName is the annotation with the slack channels of the resource events. it is read from the
+// resource, the workload of a pod or the namespace, and replaces the channels of the configuration
+var SlackChannelsAnnotationName = "kubeobserver.io/slack-channels"
+
+// slack rejects section texts longer than 3000 characters and header texts longer than 150
+const slackMaxTextLength = 3000
+const slackMaxHeaderLength = 150
+
 // SlackReceiver is a struct built for receiving and passing onward events messages to Slack
 type SlackReceiver struct {
-	ChannelNames []string
-	SlackClient  *slack.Client
+	// Name is the name of the receiver, the default slack receiver or a receiver instance. instances may post
+	// to other workspaces, so their threads are kept apart even when their channels have the same name
+	Name               string
+	ChannelNames       []string
+	ChannelRoutes      []config.SlackChannelRoute
+	SlackClient        *slack.Client
+	DefaultMentions    []string
+	Threads            bool
+	UpdateThreadParent bool
+
+	// Actions adds action buttons to the messages. the buttons need the interactivity endpoint,
+	// so they are enabled only when the signing secret of the slack app is configured
+	Actions          bool
+	RestartAllowlist []string
 }
 
 func init() {
-	ReceiverMap[slackReceiverName] = &SlackReceiver{
-		ChannelNames: config.SlackChannelNames(),
-		SlackClient:  slack.New(config.SlackToken()),
+	registerReceiver(slackReceiverName, newSlackReceiver)
+	registerReceiverType(slackReceiverName, func(instance config.ReceiverInstance) Receiver {
+		receiver := NewSlackReceiver(instance.Slack)
+		receiver.Name = instance.Name
+
+		return receiver
+	})
+}
+
+// IsSlackReceiver returns true when the receiver with the given name is the slack receiver or a slack receiver instance
+func IsSlackReceiver(name string) bool {
+	_, ok := GetReceiver(name).(*SlackReceiver)
+
+	return ok
+}
+
+func newSlackReceiver() Receiver {
+	receiver := NewSlackReceiver(config.SlackSettings{
+		Token:    config.SlackToken(),
+		Channels: config.SlackChannelNames(),
+		Mentions: config.SlackMentions(),
+		Routes:   config.SlackChannelRoutes(),
+	})
+
+	receiver.Name = slackReceiverName
+
+	// the interactivity endpoint replies with the default slack receiver, so only its messages have buttons
+	receiver.Actions = config.SlackSigningSecret() != ""
+	receiver.RestartAllowlist = config.SlackRestartAllowlist()
+
+	return receiver
+}
+
+// NewSlackReceiver builds a slack receiver from its settings. the threads settings are shared by all the slack receivers
+func NewSlackReceiver(settings config.SlackSettings) *SlackReceiver {
+	slackThreads.configure(config.SlackThreadTTL(), config.SlackThreadCacheSize())
+
+	return &SlackReceiver{
+		ChannelNames:       settings.Channels,
+		ChannelRoutes:      settings.Routes,
+		SlackClient:        slack.New(settings.Token),
+		DefaultMentions:    settings.Mentions,
+		Threads:            config.SlackThreadsEnabled(),
+		UpdateThreadParent: config.SlackThreadUpdateParent(),
 	}
 }
 
 // HandleEvent is an implementation of the Receiver interface for Slack
 func (sr *SlackReceiver) HandleEvent(receiverEvent ReceiverEvent, c chan error) {
-	message := receiverEvent.Message
-	eventName := receiverEvent.EventName
-	additionalInfo := receiverEvent.AdditionalInfo
-	var colorType string
-	var thumbURL string
-	var text string
+	// no matter what happens, close the channel after function exits
+	defer close(c)
 
 	// this will be true in case some event has slack receiver
-	// but no channels were provided in the configuration
-	if len(sr.ChannelNames) == 0 {
+	// but no channels were provided in the configuration or in the annotations
+	channels := sr.getChannels(receiverEvent)
+	if len(channels) == 0 {
 		c <- errors.New("HandleEvent of slack was triggered but no slack channel names were found in configuration")
 		return
 	}
 
-	if eventName == AddEvent {
-		colorType = "good"
-	} else if eventName == UpdateEvent {
-		colorType = "warning"
-	} else if eventName == DeleteEvent {
-		colorType = "danger"
+	receiverEvent.Message = renderMessage(slackReceiverName, receiverEvent)
+	log.Debug().Msg(fmt.Sprintf("received %s message in slack receiver: %s", receiverEvent.EventName, receiverEvent.Message))
+
+	// critical events of resources without their own users to mention will mention the default users
+	mentions := receiverEvent.Mentions
+	if len(mentions) == 0 && receiverEvent.Severity == CriticalSeverity {
+		mentions = sr.DefaultMentions
 	}
 
-	// no matter what happens, close the channel after function exits
-	defer close(c)
+	message := buildSlackMessage(receiverEvent, mentions)
+	if sr.Actions {
+		message = withSlackActions(message, receiverEvent, sr.RestartAllowlist)
+	}
+
+	log.Debug().Msg(fmt.Sprintf("Sending message to Slack: %s", message.Text))
+
+	errorsStr := make([]string, 0)
+
+	for _, channel := range channels {
+		if err := sr.postEvent(channel, receiverEvent, message); err != nil {
+			errorsStr = append(errorsStr, err.Error())
+		}
+	}
 
-	log.Debug().Msg(fmt.Sprintf("received %s message in slack receiver: %s", eventName, message))
-	log.Debug().Msg(fmt.Sprintf("building message in Slack format"))
+	// the receivers contract allows a single error per event
+	if len(errorsStr) > 0 {
+		c <- fmt.Errorf("slack receiver got unexpected error -> %s", strings.Join(errorsStr, "; "))
+	}
+}
 
-	if additionalInfo[common.PodCrashLoopbackStringIdentifier()] == true { // crash loopback event
-		// this will make sure the red color flag
+// getChannels returns the channels of the slack channels annotation. otherwise the channels of all the
+// channel routes that match the event are used, and when no route matches, the default channels
+func (sr *SlackReceiver) getChannels(receiverEvent ReceiverEvent) []string {
+	channels := make([]string, 0)
+	seen := make(map[string]bool)
+
+	add := func(channel string) {
+		if channel = strings.TrimSpace(channel); channel != "" && !seen[channel] {
+			seen[channel] = true
+			channels = append(channels, channel)
+		}
+	}
+
+	for _, channel := range strings.Split(receiverEvent.Annotations[SlackChannelsAnnotationName], ",") {
+		add(channel)
+	}
+
+	if len(channels) > 0 {
+		return channels
+	}
+
+	for _, route := range sr.ChannelRoutes {
+		if !route.Matches(receiverEvent.Namespace, receiverEvent.Kind, string(receiverEvent.Severity), receiverEvent.Reason) {
+			continue
+		}
+
+		for _, channel := range route.Channels {
+			add(channel)
+		}
+	}
+
+	if len(channels) > 0 {
+		return channels
+	}
+
+	return sr.ChannelNames
+}
+
+// slackMessage is a Block Kit message. Text is the notification text, which slack
+// shows in places the blocks can't be rendered, such as push notifications
+type slackMessage struct {
+	Text   string        `json:"text"`
+	Blocks []slack.Block `json:"blocks"`
+}
+
+func (m slackMessage) options() []slack.MsgOption {
+	return []slack.MsgOption{slack.MsgOptionText(m.Text, false), slack.MsgOptionBlocks(m.Blocks...)}
+}
+
+// slackHeaderBlock is a Block Kit header block, which the slack client doesn't support yet
+type slackHeaderBlock struct {
+	Type    slack.MessageBlockType `json:"type"`
+	BlockID string                 `json:"block_id,omitempty"`
+	Text    *slack.TextBlockObject `json:"text"`
+}
+
+// BlockType returns the type of the block
+func (b slackHeaderBlock) BlockType() slack.MessageBlockType {
+	return b.Type
+}
+
+func newSlackHeaderBlock(text string) *slackHeaderBlock {
+	return &slackHeaderBlock{
+		Type: "header",
+		Text: slack.NewTextBlockObject(slack.PlainTextType, truncateSlackText(text, slackMaxHeaderLength), true, false),
+	}
+}
+
+// slackStyle is how the event stands out in the channel. the emoji of the header
+// takes the place of the legacy attachments color
+type slackStyle struct {
+	emoji    string
+	prefix   string
+	thumbURL string
+	skulls   bool
+}
+
+// getSlackStyle returns the style of the event. events like the hpa ones, which have no style of
+// their own, are prefixed with the event name and colored by it
+func getSlackStyle(receiverEvent ReceiverEvent) slackStyle {
+	style := slackStyle{prefix: "`" + string(receiverEvent.EventName) + "` event received: "}
+
+	if receiverEvent.EventName == AddEvent {
+		style.emoji = ":large_green_circle:"
+	} else if receiverEvent.EventName == UpdateEvent {
+		style.emoji = ":large_yellow_circle:"
+	} else if receiverEvent.EventName == DeleteEvent {
+		style.emoji = ":red_circle:"
+	}
+
+	if receiverEvent.Reason == common.PodCrashLoopbackStringIdentifier() { // crash loopback event
+		// this will make sure the red flag
 		// add warning thumb on the right side of the message.
 		// in addition, skull icons will appear on start and the end of the message
-		var msgBuilder strings.Builder
+		style = slackStyle{emoji: ":skull_and_crossbones:", thumbURL: warningIcon, skulls: true}
+	} else if receiverEvent.Kind == common.PodKind {
+		// the fields and the header tell what happened to the pod
+		style.prefix = ""
+	} else if receiverEvent.Kind == common.DeploymentKind {
+		style.prefix = "`Rollout` event received: "
+
+		// a completed rollout is good news, a stalled or rolled back one is not
+		if receiverEvent.Reason == "RolloutCompleted" {
+			style.emoji = ":large_green_circle:"
+		} else if receiverEvent.Severity != InfoSeverity {
+			style.emoji = ":red_circle:"
+			style.thumbURL = warningIcon
+		}
+	} else if receiverEvent.Kind == common.NodeKind || receiverEvent.Kind == common.JobKind || receiverEvent.Kind == common.CronJobKind {
+		style.prefix = "`" + receiverEvent.Kind + "` event received: "
+
+		// a node that is healthy again or a job that completed after a long run is good news, anything else is not
+		if receiverEvent.Severity == CriticalSeverity {
+			style.emoji = ":red_circle:"
+			style.thumbURL = warningIcon
+		} else if receiverEvent.Severity == InfoSeverity {
+			style.emoji = ":large_green_circle:"
+		}
+	} else if receiverEvent.EventName == AddEvent && receiverEvent.Severity != InfoSeverity {
+		// k8s Warning events are added, but they are not good news
+		style.emoji = ":large_yellow_circle:"
+	}
+
+	return style
+}
 
-		msgBuilder.WriteString(skullIconsSlackStr)
-		msgBuilder.WriteString(message)
-		msgBuilder.WriteString(skullIconsSlackStr)
+// buildSlackMessage builds the Block Kit message of the event: a header, the rendered message,
+// the resource fields, the container updates and crash details, the mentions and a context footer
+func buildSlackMessage(receiverEvent ReceiverEvent, mentions []string) slackMessage {
+	style := getSlackStyle(receiverEvent)
 
-		usersIDS := additionalInfo["pod_watcher_users_ids"].([]string)
+	title := string(receiverEvent.EventName)
+	if receiverEvent.Reason != "" {
+		title = receiverEvent.Reason
+	}
+
+	header := strings.TrimSpace(strings.Join([]string{style.emoji, receiverEvent.Kind, title}, " "))
+	blocks := []slack.Block{newSlackHeaderBlock(header)}
+
+	text := style.prefix + strings.TrimSpace(receiverEvent.Message)
+	if style.skulls {
+		text = skullIconsSlackStr + "\n" + text + "\n" + skullIconsSlackStr
+	}
+
+	var accessory *slack.Accessory
+	if style.thumbURL != "" {
+		accessory = slack.NewAccessory(slack.NewImageBlockElement(style.thumbURL, "warning"))
+	}
+
+	blocks = append(blocks, slack.NewSectionBlock(slackMarkdown(text), nil, accessory))
+
+	if fields := slackFields(receiverEvent); len(fields) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+	}
 
-		for _, userID := range usersIDS {
-			msgBuilder.WriteString(fmt.Sprintf("<@%s>", userID))
+	if updates := slackUpdates(receiverEvent); updates != "" {
+		blocks = append(blocks, slack.NewSectionBlock(slackMarkdown(updates), nil, nil))
+	}
+
+	if details := strings.TrimSpace(slackCrashLoopDetails(receiverEvent)); details != "" {
+		blocks = append(blocks, slack.NewSectionBlock(slackMarkdown(details), nil, nil))
+	}
+
+	if len(mentions) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(slackMarkdown(slackMentions(mentions)), nil, nil))
+	}
+
+	blocks = append(blocks, slackContext(receiverEvent))
+
+	return slackMessage{Text: header, Blocks: blocks}
+}
+
+func slackMarkdown(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.MarkdownType, truncateSlackText(text, slackMaxTextLength), false, false)
+}
+
+// truncateSlackText cuts texts that are too long for a block
+func truncateSlackText(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+
+	// the cut may split a multi byte character
+	return strings.ToValidUTF8(text[:maxLength-3], "") + "..."
+}
+
+// slackFields are the cluster, namespace, resource, controller and container of the event
+func slackFields(receiverEvent ReceiverEvent) []*slack.TextBlockObject {
+	fields := []*slack.TextBlockObject{slackMarkdown(fmt.Sprintf("*Cluster*\n`%s`", receiverEvent.Cluster))}
+
+	if receiverEvent.Namespace != "" {
+		fields = append(fields, slackMarkdown(fmt.Sprintf("*Namespace*\n`%s`", receiverEvent.Namespace)))
+	}
+
+	if receiverEvent.Kind != "" && receiverEvent.Name != "" {
+		fields = append(fields, slackMarkdown(fmt.Sprintf("*%s*\n`%s`", receiverEvent.Kind, receiverEvent.Name)))
+	}
+
+	if receiverEvent.Owner.Kind != "" {
+		fields = append(fields, slackMarkdown(fmt.Sprintf("*Controller*\n`%s/%s`", receiverEvent.Owner.Kind, receiverEvent.Owner.Name)))
+	}
+
+	if receiverEvent.Container != "" {
+		fields = append(fields, slackMarkdown(fmt.Sprintf("*Container*\n`%s`", receiverEvent.Container)))
+	}
+
+	return fields
+}
+
+// slackUpdates renders the container updates of a pod event as a code block
+func slackUpdates(receiverEvent ReceiverEvent) string {
+	var updates []string
+
+	// the updates are decoded as a list of interfaces when the event was queued for a retry
+	switch value := receiverEvent.AdditionalInfo["updates"].(type) {
+	case []string:
+		updates = value
+	case []interface{}:
+		for _, update := range value {
+			updates = append(updates, fmt.Sprint(update))
 		}
+	}
 
-		colorType = "#C70039"
-		thumbURL = warningIcon
-		text = msgBuilder.String()
-	} else if additionalInfo[common.PodHpaStringIdentifier()] == true {
-        var msgBuilder strings.Builder
+	if len(updates) == 0 {
+		return ""
+	}
 
-        msgBuilder.WriteString("`" + string(eventName) + "`" + " event received: " + message)
+	var msgBuilder strings.Builder
+	for _, update := range updates {
+		msgBuilder.WriteString(strings.TrimRight(update, "\n"))
+		msgBuilder.WriteString("\n")
+	}
 
-        usersIDS := additionalInfo["pod_watcher_users_ids"].([]string)
+	// a code block inside the updates would end the slack code block
+	return fmt.Sprintf("*Updates*\n```%s```", strings.ReplaceAll(strings.TrimRight(msgBuilder.String(), "\n"), "```", "'''"))
+}
 
-        for _, userID := range usersIDS {
-            msgBuilder.WriteString(fmt.Sprintf("<@%s>", userID))
-        }
+// slackContext is the footer of the message with the time of the event
+func slackContext(receiverEvent ReceiverEvent) *slack.ContextBlock {
+	timestamp := receiverEvent.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
 
-        text = msgBuilder.String()
-	} else {
-		text = "`" + string(eventName) + "`" + " event received: " + message
+	return slack.NewContextBlock("",
+		slack.NewImageBlockElement(slackAuthorIcon, "KubeObserver"),
+		slackMarkdown(fmt.Sprintf("*KubeObserver* | <!date^%d^{date_short_pretty} {time_secs}|%s>", timestamp.Unix(), timestamp.UTC().Format(time.RFC3339))),
+	)
+}
+
+// slackCrashLoopDetails renders the last termination and logs of a crashing container.
+// only the end of long logs is kept, so the details fit in a single block
+func slackCrashLoopDetails(receiverEvent ReceiverEvent) string {
+	var msgBuilder strings.Builder
+
+	if termination := receiverEvent.LastTermination; termination != nil {
+		msgBuilder.WriteString(fmt.Sprintf("\nLast termination: `%s`. Exit code: `%d`", termination.Reason, termination.ExitCode))
+
+		if termination.Signal != 0 {
+			msgBuilder.WriteString(fmt.Sprintf(". Signal: `%d`", termination.Signal))
+		}
+
+		if termination.OOMKilled {
+			msgBuilder.WriteString(". The container was killed for running out of memory")
+		}
+
+		msgBuilder.WriteString("\n")
+	}
+
+	if receiverEvent.ContainerLogs != "" {
+		// a code block inside the logs would end the slack code block
+		logs := strings.ReplaceAll(receiverEvent.ContainerLogs, "```", "'''")
+		if maxLogsLength := slackMaxTextLength - msgBuilder.Len() - 200; len(logs) > maxLogsLength {
+			logs = "..." + logs[len(logs)-maxLogsLength:]
+		}
+
+		msgBuilder.WriteString(fmt.Sprintf("Last logs of `%s`:\n```%s```\n", receiverEvent.Container, strings.TrimRight(logs, "\n")))
+	}
+
+	return msgBuilder.String()
+}
+
+func slackMentions(usersIDS []string) string {
+	var msgBuilder strings.Builder
+
+	for _, userID := range usersIDS {
+		msgBuilder.WriteString(fmt.Sprintf("<@%s>", userID))
+	}
+
+	return msgBuilder.String()
+}
+
+// postEvent posts the event message to the channel. when threads are enabled, follow-up events
+// of a resource are posted as replies to the first message that was posted for the resource
+func (sr *SlackReceiver) postEvent(channel string, receiverEvent ReceiverEvent, message slackMessage) error {
+	if !sr.Threads {
+		_, _, err := postMessage(sr.SlackClient, channel, message, "")
+		return err
+	}
+
+	key := slackThreadKey(sr.Name, channel, receiverEvent)
+	defer slackThreads.lock(key)()
+
+	thread, ok := slackThreads.get(key)
+
+	if !ok {
+		channelID, timestamp, err := postMessage(sr.SlackClient, channel, message, "")
+
+		// a deleted resource won't have any follow-up events
+		if err == nil && receiverEvent.EventName != DeleteEvent {
+			slackThreads.add(slackThread{key: key, channel: channelID, ts: timestamp, parent: message})
+		}
+
+		return err
 	}
 
-	attachment := slack.Attachment{
-		Color:      colorType,
-		AuthorName: "KubeObserver",
-		Text:       text,
-		Ts:         json.Number(strconv.FormatInt(time.Now().Unix(), 10)),
-		AuthorIcon: slackAuthorIcon,
-		Footer:     "Slack receiver",
-		FooterIcon: slackFooterIcon,
-		ThumbURL:   thumbURL,
+	if _, _, err := postMessage(sr.SlackClient, channel, message, thread.ts); err != nil {
+		return err
 	}
 
-	log.Debug().Msg(fmt.Sprintf("Sending message to Slack: %v", attachment))
+	if sr.UpdateThreadParent {
+		parent := slackThreadParent(thread, message, receiverEvent)
+
+		if _, _, _, err := sr.SlackClient.UpdateMessage(thread.channel, thread.ts, parent.options()...); err != nil {
+			// the reply was already posted, so the event is not failed
+			log.Warn().Msg(fmt.Sprintf("couldn't update the first message of slack thread %s: %s", thread.ts, err))
+		}
+	}
 
-	for _, channel := range sr.ChannelNames {
-		err := postMessage(sr.SlackClient, channel, &attachment)
+	if receiverEvent.EventName == DeleteEvent {
+		slackThreads.delete(key)
+	} else {
+		slackThreads.reply(key)
+	}
 
-		if err != nil {
-			var errStr strings.Builder
-			errStr.WriteString("slack receiver got unexpected error -> ")
-			errStr.WriteString(err.Error())
-			c <- errors.New(errStr.String())
+	return nil
+}
+
+// slackThreadParent returns the first message of the thread with the header
+// of the latest event and a summary of the updates in the thread
+func slackThreadParent(thread slackThread, message slackMessage, receiverEvent ReceiverEvent) slackMessage {
+	blocks := make([]slack.Block, 0, len(thread.parent.Blocks)+1)
+
+	for _, block := range thread.parent.Blocks {
+		if _, ok := block.(*slackHeaderBlock); ok && len(message.Blocks) > 0 {
+			block = message.Blocks[0]
 		}
+
+		blocks = append(blocks, block)
+	}
+
+	latest := string(receiverEvent.EventName)
+	if receiverEvent.Reason != "" {
+		latest = receiverEvent.Reason
 	}
+
+	blocks = append(blocks, slack.NewContextBlock("", slackMarkdown(fmt.Sprintf("Latest update: `%s` at <!date^%d^{date_short_pretty} {time_secs}|%s>. %d updates in thread",
+		latest, receiverEvent.Timestamp.Unix(), receiverEvent.Timestamp.UTC().Format(time.RFC3339), thread.replies+1))))
+
+	return slackMessage{Text: message.Text, Blocks: blocks}
 }
 
-func postMessage(slackClient *slack.Client, channel string, attachment *slack.Attachment) error {
-	channelID, timestamp, err := slackClient.PostMessage(channel, slack.MsgOptionAttachments(*attachment))
+// postMessage posts the message to the channel, or as a reply in a thread when threadTS is set.
+// it returns the channel ID and the timestamp of the posted message
+func postMessage(slackClient *slack.Client, channel string, message slackMessage, threadTS string) (string, string, error) {
+	options := message.options()
+	if threadTS != "" {
+		options = append(options, slack.MsgOptionTS(threadTS))
+	}
+	channelID, timestamp, err := slackClient.PostMessage(channel, options...)
 
 	if err == nil {
 		log.Debug().Msg(fmt.Sprintf("Successfully posted a message to channel %s at %s", channelID, timestamp))
 	} else {
 		if strings.HasPrefix(err.Error(), "slack rate limit exceeded") {
+			metrics.SlackRateLimited.Inc()
+
 			// slack api allows bursts over that limit for short periods. However,
 			// if your app continues to exceed its allowance over longer periods of time, we will begin rate limiting.
 			// Continuing to send messages after exceeding a rate limit runs the risk of your app being permanently disabled.
 			// this this why we are sleeping for 1.5 sec in order to make sure we won't get block
 			time.Sleep(1500 * time.Millisecond)
-			channelID, timestamp, err = slackClient.PostMessage(channel, slack.MsgOptionAttachments(*attachment))
+			channelID, timestamp, err = slackClient.PostMessage(channel, options...)
 
 			if err == nil {
 				log.Debug().Msg(fmt.Sprintf("Successfully posted a message to channel %s at %s", channelID, timestamp))
@@ -143,5 +525,5 @@ func postMessage(slackClient *slack.Client, channel string, attachment *slack.At
 		}
 	}
 
-	return err
+	return channelID, timestamp, err
 }
@@ -0,0 +1,122 @@
+package receivers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/PayU/kubeobserver/pkg/common"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+// the action IDs of the buttons of slack messages. slack sends them back
+// to the interactivity endpoint when a button is clicked
+const (
+	SlackAckAction     = "kubeobserver_ack"
+	SlackSilenceAction = "kubeobserver_silence"
+	SlackRestartAction = "kubeobserver_restart"
+)
+
+// SlackActionTarget is the value of the action buttons. it identifies the workload of the event,
+// since the interactivity endpoint can't trust the message text
+type SlackActionTarget struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Pod       string `json:"pod,omitempty"`
+}
+
+// ParseSlackActionTarget parses the value of an action button
+func ParseSlackActionTarget(value string) (SlackActionTarget, error) {
+	var target SlackActionTarget
+	if err := json.Unmarshal([]byte(value), &target); err != nil {
+		return target, fmt.Errorf("invalid slack action value %s: %s", value, err.Error())
+	}
+
+	if target.Namespace == "" || target.Kind == "" || target.Name == "" {
+		return target, fmt.Errorf("slack action value %s has no workload", value)
+	}
+
+	return target, nil
+}
+
+// SlackRestartAllowed returns true when the deployment matches one of the namespace/name patterns
+// of the allowlist, for example 'payments/*'
+func SlackRestartAllowed(allowlist []string, namespace string, deployment string) bool {
+	for _, pattern := range allowlist {
+		if matched, _ := path.Match(pattern, namespace+"/"+deployment); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// slackActionsBlock returns the buttons of crash loop events. the rollout restart button is added only
+// for deployments of the allowlist. events of other kinds or without a workload have no buttons
+func slackActionsBlock(receiverEvent ReceiverEvent, restartAllowlist []string) *slack.ActionBlock {
+	if receiverEvent.Reason != common.PodCrashLoopbackStringIdentifier() {
+		return nil
+	}
+
+	target := SlackActionTarget{Cluster: receiverEvent.Cluster, Namespace: receiverEvent.Namespace, Kind: receiverEvent.Kind, Name: receiverEvent.Name}
+	if workload := receiverEvent.Workload(); workload.Kind != "" {
+		target = SlackActionTarget{Cluster: receiverEvent.Cluster, Namespace: receiverEvent.Namespace, Kind: workload.Kind, Name: workload.Name, Pod: receiverEvent.Name}
+	}
+
+	value, err := json.Marshal(target)
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("couldn't build slack actions of %s %s/%s: %s", receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name, err.Error()))
+		return nil
+	}
+
+	ack := slack.NewButtonBlockElement(SlackAckAction, string(value), slack.NewTextBlockObject(slack.PlainTextType, "Ack", false, false))
+	silence := slack.NewButtonBlockElement(SlackSilenceAction, string(value), slack.NewTextBlockObject(slack.PlainTextType, "Silence 1h", false, false))
+	elements := []slack.BlockElement{ack, silence}
+
+	if target.Kind == common.DeploymentKind && SlackRestartAllowed(restartAllowlist, target.Namespace, target.Name) {
+		restart := slack.NewButtonBlockElement(SlackRestartAction, string(value), slack.NewTextBlockObject(slack.PlainTextType, "Rollout restart", false, false))
+		restart.Style = slack.StyleDanger
+		restart.Confirm = slack.NewConfirmationBlockObject(
+			slack.NewTextBlockObject(slack.PlainTextType, "Rollout restart", false, false),
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("Restart all the pods of deployment `%s/%s` in `%s`?", target.Namespace, target.Name, target.Cluster), false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, "Restart", false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		)
+
+		elements = append(elements, restart)
+	}
+
+	return slack.NewActionBlock("kubeobserver_actions", elements...)
+}
+
+// withSlackActions adds the action buttons of the event before the context footer of the message
+func withSlackActions(message slackMessage, receiverEvent ReceiverEvent, restartAllowlist []string) slackMessage {
+	actions := slackActionsBlock(receiverEvent, restartAllowlist)
+	if actions == nil || len(message.Blocks) == 0 {
+		return message
+	}
+
+	last := len(message.Blocks) - 1
+	blocks := make([]slack.Block, 0, len(message.Blocks)+1)
+	blocks = append(blocks, message.Blocks[:last]...)
+	blocks = append(blocks, actions, message.Blocks[last])
+
+	return slackMessage{Text: message.Text, Blocks: blocks}
+}
+
+// ReplySlackThread posts the text as a reply in the thread of the message, with the client of the current slack receiver
+func ReplySlackThread(channel string, threadTS string, text string) error {
+	receiver, ok := GetReceiver(slackReceiverName).(*SlackReceiver)
+	if !ok || receiver.SlackClient == nil {
+		return errors.New("slack receiver isn't configured")
+	}
+
+	message := slackMessage{Text: text, Blocks: []slack.Block{slack.NewSectionBlock(slackMarkdown(text), nil, nil)}}
+	_, _, err := postMessage(receiver.SlackClient, channel, message, threadTS)
+
+	return err
+}
@@ -0,0 +1,90 @@
+package receivers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func mockSlackActionsEvent(reason string) ReceiverEvent {
+	return ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Namespace: "payments", Name: "checkout-5d8f7c9b6-x2x4z",
+		Owner: Owner{Kind: "ReplicaSet", Name: "checkout-5d8f7c9b6"}, Labels: map[string]string{"pod-template-hash": "5d8f7c9b6"},
+		Severity: CriticalSeverity, Reason: reason, Container: "checkout", AdditionalInfo: map[string]interface{}{}}
+}
+
+func TestSlackActionsGolden(t *testing.T) {
+	event := mockSlackActionsEvent("CrashLoopBackOff")
+	event.Cluster = "prod-cluster"
+	message := withSlackActions(slackGoldenMessage(event, nil), event, []string{"payments/*"})
+
+	assertSlackGolden(t, "pod_crash_loop_actions", message)
+}
+
+func TestSlackActionsBlock(t *testing.T) {
+	if slackActionsBlock(mockSlackActionsEvent("Error"), []string{"*/*"}) != nil {
+		t.Error("TestSlackActionsBlock: only crash loop events should have buttons")
+	}
+
+	event := mockSlackActionsEvent("CrashLoopBackOff")
+	event.Cluster = "prod-cluster"
+
+	actions := slackActionsBlock(event, []string{"orders/*"})
+	if actions == nil || len(actions.Elements.ElementSet) != 2 {
+		t.Fatal("TestSlackActionsBlock: deployments outside of the allowlist should only have the ack and silence buttons")
+	}
+
+	button, ok := actions.Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	if !ok {
+		t.Fatal("TestSlackActionsBlock: expected a button element")
+	}
+
+	target, err := ParseSlackActionTarget(button.Value)
+	if err != nil {
+		t.Fatalf("TestSlackActionsBlock: couldn't parse the button value: %s", err.Error())
+	}
+
+	expected := SlackActionTarget{Cluster: "prod-cluster", Namespace: "payments", Kind: "Deployment", Name: "checkout", Pod: "checkout-5d8f7c9b6-x2x4z"}
+	if target != expected {
+		t.Errorf("TestSlackActionsBlock: the button should target the deployment of the pod, got %+v", target)
+	}
+
+	if actions = slackActionsBlock(event, []string{"payments/checkout"}); len(actions.Elements.ElementSet) != 3 {
+		t.Error("TestSlackActionsBlock: allowlisted deployments should have the rollout restart button")
+	}
+}
+
+func TestSlackRestartAllowed(t *testing.T) {
+	tests := []struct {
+		allowlist  []string
+		namespace  string
+		deployment string
+		expected   bool
+	}{
+		{nil, "payments", "checkout", false},
+		{[]string{"payments/checkout"}, "payments", "checkout", true},
+		{[]string{"payments/*"}, "payments", "checkout", true},
+		{[]string{"payments/*"}, "orders", "checkout", false},
+		{[]string{"*/checkout"}, "orders", "checkout", true},
+		{[]string{"payments/check"}, "payments", "checkout", false},
+	}
+
+	for _, test := range tests {
+		if allowed := SlackRestartAllowed(test.allowlist, test.namespace, test.deployment); allowed != test.expected {
+			t.Errorf("TestSlackRestartAllowed: %v with %s/%s expected %t, got %t", test.allowlist, test.namespace, test.deployment, test.expected, allowed)
+		}
+	}
+}
+
+func TestParseSlackActionTarget(t *testing.T) {
+	value, _ := json.Marshal(SlackActionTarget{Cluster: "prod-cluster", Namespace: "payments", Kind: "Pod", Name: "checkout"})
+	if _, err := ParseSlackActionTarget(string(value)); err != nil {
+		t.Errorf("TestParseSlackActionTarget: unexpected error: %s", err.Error())
+	}
+
+	for _, value := range []string{"", "not json", `{"cluster":"prod-cluster","namespace":"payments"}`} {
+		if _, err := ParseSlackActionTarget(value); err == nil {
+			t.Errorf("TestParseSlackActionTarget: expected an error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package receivers
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// slackThread is the first message posted to a channel for a resource.
+// follow-up events of the same resource are posted as replies to it
+type slackThread struct {
+	key        string
+	channel    string
+	ts         string
+	parent     slackMessage
+	replies    int
+	expiration time.Time
+}
+
+// slackThreadCache is an in memory LRU cache of slack threads with an expiration time.
+// it is shared by all the slack receivers so the threads survive a configuration reload
+type slackThreadCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	order   *list.List
+	threads map[string]*list.Element
+	locks   map[string]*slackThreadLock
+}
+
+// slackThreadLock is held while an event of the key is posted, refs counts the events that wait for it
+type slackThreadLock struct {
+	sync.Mutex
+	refs int
+}
+
+var slackThreads = newSlackThreadCache(time.Hour, 1000)
+
+func newSlackThreadCache(ttl time.Duration, maxSize int) *slackThreadCache {
+	return &slackThreadCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		order:   list.New(),
+		threads: make(map[string]*list.Element),
+		locks:   make(map[string]*slackThreadLock),
+	}
+}
+
+// lock waits for the other events of the key that are being posted and returns the function that
+// releases the key, so the first event of a resource adds its thread before the next events look it up
+func (tc *slackThreadCache) lock(key string) func() {
+	tc.Lock()
+	lock, ok := tc.locks[key]
+	if !ok {
+		lock = &slackThreadLock{}
+		tc.locks[key] = lock
+	}
+	lock.refs++
+	tc.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		tc.Lock()
+		defer tc.Unlock()
+
+		if lock.refs--; lock.refs == 0 {
+			delete(tc.locks, key)
+		}
+	}
+}
+
+// slackThreadKey identifies the thread of a resource in a channel of a slack receiver
+func slackThreadKey(receiverName string, channel string, receiverEvent ReceiverEvent) string {
+	return receiverName + "/" + channel + "/" + receiverEvent.Cluster + "/" + receiverEvent.Kind + "/" + receiverEvent.Namespace + "/" + receiverEvent.Name
+}
+
+// configure applies new limits and drops the threads that exceed them
+func (tc *slackThreadCache) configure(ttl time.Duration, maxSize int) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	tc.ttl = ttl
+	tc.maxSize = maxSize
+	tc.evict()
+}
+
+// get returns a copy of the thread of the key. expired threads are removed and not returned
+func (tc *slackThreadCache) get(key string) (slackThread, bool) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	element, ok := tc.threads[key]
+	if !ok {
+		return slackThread{}, false
+	}
+
+	thread := element.Value.(*slackThread)
+	if time.Now().After(thread.expiration) {
+		tc.remove(element)
+		return slackThread{}, false
+	}
+
+	tc.order.MoveToFront(element)
+
+	return *thread, true
+}
+
+// add stores a new thread, or replaces the existing thread of the same key
+func (tc *slackThreadCache) add(thread slackThread) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	if element, ok := tc.threads[thread.key]; ok {
+		tc.remove(element)
+	}
+
+	thread.expiration = time.Now().Add(tc.ttl)
+	tc.threads[thread.key] = tc.order.PushFront(&thread)
+	tc.evict()
+}
+
+// reply counts a reply in the thread
+func (tc *slackThreadCache) reply(key string) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	if element, ok := tc.threads[key]; ok {
+		element.Value.(*slackThread).replies++
+	}
+}
+
+// delete removes the thread of the key, for example when the resource itself was deleted
+func (tc *slackThreadCache) delete(key string) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	if element, ok := tc.threads[key]; ok {
+		tc.remove(element)
+	}
+}
+
+func (tc *slackThreadCache) len() int {
+	tc.Lock()
+	defer tc.Unlock()
+
+	return tc.order.Len()
+}
+
+// evict removes the least recently used threads until the cache fits its max size.
+// the caller must hold the lock
+func (tc *slackThreadCache) evict() {
+	for tc.order.Len() > 0 && tc.order.Len() > tc.maxSize {
+		tc.remove(tc.order.Back())
+	}
+}
+
+// remove deletes a thread from the cache. the caller must hold the lock
+func (tc *slackThreadCache) remove(element *list.Element) {
+	tc.order.Remove(element)
+	delete(tc.threads, element.Value.(*slackThread).key)
+}
@@ -0,0 +1,176 @@
+package receivers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSlackThreadCache(t *testing.T) {
+	cache := newSlackThreadCache(time.Hour, 2)
+
+	cache.add(slackThread{key: "first", ts: "1"})
+	cache.add(slackThread{key: "second", ts: "2"})
+
+	// reading the first thread makes the second the least recently used one
+	if _, ok := cache.get("first"); !ok {
+		t.Error("First thread should be cached")
+	}
+
+	cache.add(slackThread{key: "third", ts: "3"})
+
+	if _, ok := cache.get("second"); ok || cache.len() != 2 {
+		t.Error("Least recently used thread should be evicted when the cache is full")
+	}
+
+	cache.configure(time.Nanosecond, 2)
+	cache.add(slackThread{key: "fourth", ts: "4"})
+	time.Sleep(time.Millisecond)
+
+	if _, ok := cache.get("fourth"); ok {
+		t.Error("Expired thread shouldn't be returned")
+	}
+}
+
+func TestSlackThreadReplies(t *testing.T) {
+	var lock sync.Mutex
+	calls := make([]string, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		lock.Lock()
+		calls = append(calls, strings.TrimPrefix(r.URL.Path, "/")+":"+r.Form.Get("thread_ts"))
+		lock.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channel": "C123", "ts": "1000.01"})
+	}))
+	defer server.Close()
+
+	defer func(threads *slackThreadCache) { slackThreads = threads }(slackThreads)
+	slackThreads = newSlackThreadCache(time.Hour, 10)
+	receiver := &SlackReceiver{
+		ChannelNames:       []string{"#alerts"},
+		SlackClient:        slack.New("mock-token", slack.OptionAPIURL(server.URL+"/")),
+		Threads:            true,
+		UpdateThreadParent: true,
+	}
+
+	receiverEvent := ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Namespace: "default", Name: "mockPod", Reason: "CrashLoopBackOff"}
+
+	for i := 0; i < 2; i++ {
+		c := make(chan error, 1)
+		receiver.HandleEvent(receiverEvent, c)
+
+		if err := <-c; err != nil {
+			t.Fatalf("Slack receiver failed: %v", err)
+		}
+	}
+
+	expected := []string{"chat.postMessage:", "chat.postMessage:1000.01", "chat.update:"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("Follow-up event should be posted in the thread of the first message. expected %v, got %v", expected, calls)
+	}
+
+	receiverEvent.EventName = DeleteEvent
+	c := make(chan error, 1)
+	receiver.HandleEvent(receiverEvent, c)
+	<-c
+
+	if slackThreads.len() != 0 {
+		t.Error("Thread should be forgotten after the resource was deleted")
+	}
+}
+
+func TestSlackThreadConcurrentEvents(t *testing.T) {
+	var lock sync.Mutex
+	parents := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		lock.Lock()
+		if r.Form.Get("thread_ts") == "" {
+			parents++
+		}
+		lock.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channel": "C123", "ts": "1000.01"})
+	}))
+	defer server.Close()
+
+	defer func(threads *slackThreadCache) { slackThreads = threads }(slackThreads)
+	slackThreads = newSlackThreadCache(time.Hour, 10)
+	receiver := &SlackReceiver{
+		ChannelNames: []string{"#alerts"},
+		SlackClient:  slack.New("mock-token", slack.OptionAPIURL(server.URL+"/")),
+		Threads:      true,
+	}
+
+	receiverEvent := ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Namespace: "default", Name: "mockPod", Reason: "CrashLoopBackOff"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c := make(chan error, 1)
+			receiver.HandleEvent(receiverEvent, c)
+			<-c
+		}()
+	}
+	wg.Wait()
+
+	if parents != 1 {
+		t.Errorf("Concurrent events of a resource should post a single thread parent, got %d", parents)
+	}
+}
+
+func TestSlackThreadsOfReceiverInstances(t *testing.T) {
+	newMockWorkspace := func(parents *int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+
+			if r.Form.Get("thread_ts") == "" {
+				atomic.AddInt32(parents, 1)
+			}
+
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channel": "C123", "ts": "1000.01"})
+		}))
+	}
+
+	var defaultParents, instanceParents int32
+	defaultWorkspace, instanceWorkspace := newMockWorkspace(&defaultParents), newMockWorkspace(&instanceParents)
+	defer defaultWorkspace.Close()
+	defer instanceWorkspace.Close()
+
+	defer func(threads *slackThreadCache) { slackThreads = threads }(slackThreads)
+	slackThreads = newSlackThreadCache(time.Hour, 10)
+
+	receiverEvent := ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Namespace: "default", Name: "mockPod", Reason: "CrashLoopBackOff"}
+
+	// both receivers post to a channel with the same name, in different workspaces
+	for _, receiver := range []*SlackReceiver{
+		{Name: "slack", ChannelNames: []string{"#alerts"}, SlackClient: slack.New("mock-token", slack.OptionAPIURL(defaultWorkspace.URL+"/")), Threads: true},
+		{Name: "team-slack", ChannelNames: []string{"#alerts"}, SlackClient: slack.New("mock-token", slack.OptionAPIURL(instanceWorkspace.URL+"/")), Threads: true},
+	} {
+		c := make(chan error, 1)
+		receiver.HandleEvent(receiverEvent, c)
+
+		if err := <-c; err != nil {
+			t.Fatalf("Slack receiver %s failed: %v", receiver.Name, err)
+		}
+	}
+
+	if defaultParents != 1 || instanceParents != 1 {
+		t.Errorf("Every slack receiver should start its own thread, got %d and %d thread parents", defaultParents, instanceParents)
+	}
+}
@@ -1,10 +1,19 @@
 package receivers
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/PayU/kubeobserver/pkg/config"
 	"github.com/slack-go/slack"
 )
 
@@ -65,3 +74,201 @@ func TestHandleEvent(t *testing.T) {
 	default:
 	}
 }
+
+func TestSlackHandleEventChannelErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": "channel_not_found"})
+	}))
+	defer server.Close()
+
+	receiver := &SlackReceiver{
+		ChannelNames: []string{"#alerts", "#oncall"},
+		SlackClient:  slack.New("mock-token", slack.OptionAPIURL(server.URL+"/")),
+	}
+
+	c := make(chan error)
+	go receiver.HandleEvent(ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Namespace: "default", Name: "mockPod"}, c)
+
+	err := <-c
+	if err == nil || strings.Count(err.Error(), "channel_not_found") != 2 {
+		t.Errorf("Expected a single error with the failures of both channels, got %v", err)
+	}
+
+	select {
+	case err, ok := <-c:
+		if ok {
+			t.Errorf("Slack receiver should send a single error, got another one: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Slack receiver should close the channel after the error")
+	}
+}
+
+func TestSlackCrashLoopDetails(t *testing.T) {
+	receiverEvent := ReceiverEvent{
+		Container:       "app",
+		LastTermination: &ContainerTermination{ExitCode: 137, Reason: "OOMKilled", OOMKilled: true},
+		ContainerLogs:   "starting\n```\npanic\n",
+	}
+
+	details := slackCrashLoopDetails(receiverEvent)
+
+	if !strings.Contains(details, "Exit code: `137`") || !strings.Contains(details, "out of memory") {
+		t.Errorf("Termination details are missing: %s", details)
+	}
+
+	if strings.Count(details, "```") != 2 {
+		t.Errorf("Logs should be rendered in a single code block: %s", details)
+	}
+
+	if slackCrashLoopDetails(ReceiverEvent{}) != "" {
+		t.Error("Events without crash details should render nothing")
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the slack messages")
+
+var goldenTimestamp = time.Date(2021, 3, 17, 10, 5, 0, 0, time.UTC)
+
+// assertSlackGolden compares the slack message payload to testdata/slack/<name>.golden.json.
+// run the tests with -update to write the golden files after a change of the message layout
+func assertSlackGolden(t *testing.T, name string, message slackMessage) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(message); err != nil {
+		t.Fatalf("couldn't marshal the slack message: %v", err)
+	}
+
+	actual := buffer.Bytes()
+
+	path := filepath.Join("testdata", "slack", name+".golden.json")
+
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatalf("couldn't update golden file %s: %v", path, err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("couldn't read golden file %s: %v", path, err)
+	}
+
+	if string(expected) != string(actual) {
+		t.Errorf("Slack message of %s doesn't match the golden file %s. got:\n%s", name, path, actual)
+	}
+}
+
+func slackGoldenMessage(receiverEvent ReceiverEvent, mentions []string) slackMessage {
+	receiverEvent.Cluster = "prod-cluster"
+	receiverEvent.Timestamp = goldenTimestamp
+	if message := RenderMessage(receiverEvent); message != "" {
+		receiverEvent.Message = message
+	}
+
+	receiverEvent.Message = renderMessage(slackReceiverName, receiverEvent)
+
+	return buildSlackMessage(receiverEvent, mentions)
+}
+
+func TestSlackMessageGolden(t *testing.T) {
+	owner := Owner{Kind: "ReplicaSet", Name: "checkout-5d8f7c9b6"}
+
+	tests := []struct {
+		name     string
+		event    ReceiverEvent
+		mentions []string
+	}{
+		{
+			name: "pod_created",
+			event: ReceiverEvent{EventName: AddEvent, Kind: "Pod", Namespace: "payments", Name: "checkout-5d8f7c9b6-x2x4z",
+				Owner: owner, Severity: InfoSeverity, Reason: "Created", AdditionalInfo: map[string]interface{}{}},
+		},
+		{
+			name: "pod_updated",
+			event: ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Namespace: "payments", Name: "checkout-5d8f7c9b6-x2x4z",
+				Owner: owner, Severity: WarningSeverity, Reason: "Error", Container: "checkout",
+				AdditionalInfo: map[string]interface{}{"updates": []string{
+					"the container checkout has been terminated with exit code 1. Reason:`Error`\n",
+					"the container sidecar is running since 2021-03-17 10:04:00 +0000 UTC\n",
+				}}},
+		},
+		{
+			name: "pod_crash_loop",
+			event: ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Namespace: "payments", Name: "checkout-5d8f7c9b6-x2x4z",
+				Owner: owner, Severity: CriticalSeverity, Reason: "CrashLoopBackOff", Container: "checkout",
+				LastTermination: &ContainerTermination{ExitCode: 137, Reason: "OOMKilled", OOMKilled: true},
+				ContainerLogs:   "starting checkout\nallocating cache\n",
+				AdditionalInfo: map[string]interface{}{"updates": []interface{}{
+					"the container checkout is waiting since CrashLoopBackOff\n",
+				}}},
+			mentions: []string{"U0123456"},
+		},
+		{
+			name: "hpa_scale_up",
+			event: ReceiverEvent{EventName: UpdateEvent, Kind: "HorizontalPodAutoscaler", Namespace: "payments", Name: "checkout",
+				Severity: InfoSeverity, Reason: "ScaleUp", AdditionalInfo: map[string]interface{}{
+					"scale_direction": "UP", "scale_phase": "progress", "current_replicas": 3, "desired_replicas": 6}},
+		},
+		{
+			name: "deployment_stalled",
+			event: ReceiverEvent{EventName: UpdateEvent, Kind: "Deployment", Namespace: "payments", Name: "checkout",
+				Severity: CriticalSeverity, Reason: "ProgressDeadlineExceeded",
+				Message: "Rollout of deployment `payments/checkout` has stalled"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertSlackGolden(t, test.name, slackGoldenMessage(test.event, test.mentions))
+		})
+	}
+}
+
+func TestSlackThreadParent(t *testing.T) {
+	first := slackGoldenMessage(ReceiverEvent{EventName: AddEvent, Kind: "Pod", Namespace: "payments", Name: "checkout",
+		Reason: "Created", AdditionalInfo: map[string]interface{}{}}, nil)
+	latestEvent := ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Namespace: "payments", Name: "checkout",
+		Reason: "CrashLoopBackOff", Timestamp: goldenTimestamp, AdditionalInfo: map[string]interface{}{}}
+	latest := buildSlackMessage(latestEvent, nil)
+
+	parent := slackThreadParent(slackThread{parent: first, replies: 2}, latest, latestEvent)
+	assertSlackGolden(t, "thread_parent", parent)
+
+	if len(first.Blocks) != len(parent.Blocks)-1 {
+		t.Error("The first message of the thread shouldn't be changed")
+	}
+}
+
+func TestSlackReceiverChannels(t *testing.T) {
+	receiver := &SlackReceiver{
+		ChannelNames: []string{"#kubeobserver"},
+		ChannelRoutes: []config.SlackChannelRoute{
+			{Namespaces: []string{"payments"}, Channels: []string{"#payments-alerts"}},
+			{Kinds: []string{"HorizontalPodAutoscaler"}, Channels: []string{"#capacity"}},
+			{Namespaces: []string{"payments"}, Severities: []string{"critical"}, Channels: []string{"#payments-oncall", "#payments-alerts"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		event    ReceiverEvent
+		expected []string
+	}{
+		{"default channels", ReceiverEvent{Kind: "Pod", Namespace: "orders", Severity: InfoSeverity}, []string{"#kubeobserver"}},
+		{"namespace route", ReceiverEvent{Kind: "Pod", Namespace: "payments", Severity: InfoSeverity}, []string{"#payments-alerts"}},
+		{"kind route", ReceiverEvent{Kind: "HorizontalPodAutoscaler", Namespace: "orders", Severity: InfoSeverity}, []string{"#capacity"}},
+		{"all matching routes", ReceiverEvent{Kind: "Pod", Namespace: "payments", Severity: CriticalSeverity}, []string{"#payments-alerts", "#payments-oncall"}},
+		{"annotation", ReceiverEvent{Kind: "Pod", Namespace: "payments", Severity: InfoSeverity,
+			Annotations: map[string]string{SlackChannelsAnnotationName: "#checkout, #payments-alerts,"}}, []string{"#checkout", "#payments-alerts"}},
+	}
+
+	for _, test := range tests {
+		if channels := receiver.getChannels(test.event); strings.Join(channels, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("TestSlackReceiverChannels: %s expected %v, got %v", test.name, test.expected, channels)
+		}
+	}
+}
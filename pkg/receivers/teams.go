@@ -0,0 +1,285 @@
+package receivers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/common"
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
+var teamsReceiverName = "teams"
+
+// teamsMentionsAnnotationName is the resource annotation with the users (UPN or email)
+// that are mentioned on the Teams message of the resource events
+var teamsMentionsAnnotationName = "kubeobserver.io/teams_mentions"
+
+var teamsCardContentType = "application/vnd.microsoft.card.adaptive"
+var teamsCardSchema = "http://adaptivecards.io/schemas/adaptive-card.json"
+var teamsCardVersion = "1.4"
+
+// TeamsReceiver is a struct built for receiving and passing onward events as Adaptive Cards
+// to Microsoft Teams incoming webhooks or Workflows URLs
+type TeamsReceiver struct {
+	URLs         []string
+	Retries      int
+	RetryBackoff time.Duration
+	HTTPClient   *http.Client
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string                   `json:"$schema"`
+	Type    string                   `json:"type"`
+	Version string                   `json:"version"`
+	Body    []map[string]interface{} `json:"body"`
+	MSTeams teamsCardOptions         `json:"msteams"`
+}
+
+type teamsCardOptions struct {
+	Width    string         `json:"width"`
+	Entities []teamsMention `json:"entities,omitempty"`
+}
+
+type teamsMention struct {
+	Type      string             `json:"type"`
+	Text      string             `json:"text"`
+	Mentioned teamsMentionedUser `json:"mentioned"`
+}
+
+type teamsMentionedUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+func init() {
+	registerReceiver(teamsReceiverName, newTeamsReceiver)
+	registerReceiverType(teamsReceiverName, func(instance config.ReceiverInstance) Receiver {
+		return NewTeamsReceiver(instance.Teams)
+	})
+}
+
+func newTeamsReceiver() Receiver {
+	return NewTeamsReceiver(config.TeamsSettings{
+		WebhookURLs: config.TeamsWebhookURLs(),
+		Timeout:     config.WebhookTimeout(),
+		Retries:     config.WebhookRetries(),
+	})
+}
+
+// NewTeamsReceiver builds a teams receiver from its settings
+func NewTeamsReceiver(settings config.TeamsSettings) *TeamsReceiver {
+	return &TeamsReceiver{
+		URLs:         settings.WebhookURLs,
+		Retries:      settings.Retries,
+		RetryBackoff: time.Second,
+		HTTPClient:   &http.Client{Timeout: settings.Timeout},
+	}
+}
+
+// HandleEvent is an implementation of the Receiver interface for Microsoft Teams
+func (tr *TeamsReceiver) HandleEvent(receiverEvent ReceiverEvent, c chan error) {
+	// no matter what happens, close the channel after function exits
+	defer close(c)
+
+	receiverEvent.Message = renderMessage(teamsReceiverName, receiverEvent)
+
+	// this will be true in case some event has teams receiver
+	// but no urls were provided in the configuration
+	if len(tr.URLs) == 0 {
+		c <- errors.New("HandleEvent of teams was triggered but no teams webhook urls were found in configuration")
+		return
+	}
+
+	log.Debug().Msg(fmt.Sprintf("received %s message in teams receiver: %s", receiverEvent.EventName, receiverEvent.Message))
+
+	payload, err := json.Marshal(buildTeamsMessage(receiverEvent))
+	if err != nil {
+		c <- fmt.Errorf("teams receiver couldn't marshal the event -> %s", err.Error())
+		return
+	}
+
+	errorsStr := make([]string, 0)
+
+	for _, url := range tr.URLs {
+		err := retryRequest(teamsReceiverName, url, tr.Retries, tr.RetryBackoff, func() (bool, error) {
+			return tr.post(url, payload)
+		})
+
+		if err != nil {
+			errorsStr = append(errorsStr, err.Error())
+		}
+	}
+
+	// the receivers contract allows a single error per event
+	if len(errorsStr) > 0 {
+		c <- fmt.Errorf("teams receiver got unexpected error -> %s", strings.Join(errorsStr, "; "))
+	}
+}
+
+func buildTeamsMessage(receiverEvent ReceiverEvent) teamsMessage {
+	title := fmt.Sprintf("%s `%s` event", receiverEvent.Kind, receiverEvent.EventName)
+	if receiverEvent.Reason != "" {
+		title = fmt.Sprintf("%s `%s`", receiverEvent.Kind, receiverEvent.Reason)
+	}
+
+	body := []map[string]interface{}{
+		{
+			"type":  "Container",
+			"style": teamsStyle(receiverEvent),
+			"bleed": true,
+			"items": []map[string]interface{}{
+				{"type": "TextBlock", "text": teamsMarkdown(title), "weight": "Bolder", "size": "Medium", "wrap": true},
+			},
+		},
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(receiverEvent.Message), "\n") {
+		if line != "" {
+			body = append(body, map[string]interface{}{"type": "TextBlock", "text": teamsMarkdown(line), "wrap": true})
+		}
+	}
+
+	body = append(body, map[string]interface{}{"type": "FactSet", "facts": teamsFacts(receiverEvent)})
+
+	if receiverEvent.ContainerLogs != "" {
+		body = append(body,
+			map[string]interface{}{"type": "TextBlock", "text": fmt.Sprintf("Last logs of **%s**:", receiverEvent.Container), "wrap": true},
+			map[string]interface{}{"type": "TextBlock", "text": strings.TrimRight(receiverEvent.ContainerLogs, "\n"), "fontType": "Monospace", "size": "Small", "wrap": true},
+		)
+	}
+
+	mentions := teamsMentions(receiverEvent)
+	if len(mentions) > 0 {
+		texts := make([]string, 0, len(mentions))
+		for _, mention := range mentions {
+			texts = append(texts, mention.Text)
+		}
+
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": strings.Join(texts, " "), "wrap": true})
+	}
+
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: teamsCardContentType,
+			Content: teamsCard{
+				Schema:  teamsCardSchema,
+				Type:    "AdaptiveCard",
+				Version: teamsCardVersion,
+				Body:    body,
+				MSTeams: teamsCardOptions{Width: "Full", Entities: mentions},
+			},
+		}},
+	}
+}
+
+// teamsStyle returns the container style of the card title. like slack colors, the style
+// follows the event type, and the severity when the event is good or bad news
+func teamsStyle(receiverEvent ReceiverEvent) string {
+	switch {
+	case receiverEvent.Severity == CriticalSeverity || receiverEvent.Reason == common.PodCrashLoopbackStringIdentifier():
+		return "attention"
+	case receiverEvent.Reason == "RolloutCompleted":
+		return "good"
+	case receiverEvent.Severity == InfoSeverity && (receiverEvent.Kind == common.NodeKind || receiverEvent.Kind == common.JobKind):
+		return "good"
+	case receiverEvent.EventName == AddEvent && receiverEvent.Severity != InfoSeverity:
+		return "warning"
+	case receiverEvent.EventName == AddEvent:
+		return "good"
+	case receiverEvent.EventName == DeleteEvent:
+		return "attention"
+	default:
+		return "warning"
+	}
+}
+
+func teamsFacts(receiverEvent ReceiverEvent) []teamsFact {
+	facts := make([]teamsFact, 0)
+
+	addFact := func(title string, value string) {
+		if value != "" {
+			facts = append(facts, teamsFact{Title: title, Value: value})
+		}
+	}
+
+	addFact("Cluster", receiverEvent.Cluster)
+	addFact("Namespace", receiverEvent.Namespace)
+	addFact(receiverEvent.Kind, receiverEvent.Name)
+
+	if receiverEvent.Owner.Kind != "" {
+		addFact("Controller", fmt.Sprintf("%s/%s", receiverEvent.Owner.Kind, receiverEvent.Owner.Name))
+	}
+
+	addFact("Container", receiverEvent.Container)
+	addFact("Reason", receiverEvent.Reason)
+	addFact("Severity", string(receiverEvent.Severity))
+
+	if termination := receiverEvent.LastTermination; termination != nil {
+		addFact("Last termination", fmt.Sprintf("%s (exit code %d)", termination.Reason, termination.ExitCode))
+	}
+
+	return facts
+}
+
+// teamsMentions returns the mentions of the users in the teams mentions annotation.
+// like slack mentions, users are not mentioned on info events
+func teamsMentions(receiverEvent ReceiverEvent) []teamsMention {
+	mentions := make([]teamsMention, 0)
+
+	if receiverEvent.Severity == InfoSeverity {
+		return mentions
+	}
+
+	for _, user := range strings.Split(receiverEvent.Annotations[teamsMentionsAnnotationName], ",") {
+		if user = strings.TrimSpace(user); user != "" {
+			mentions = append(mentions, teamsMention{
+				Type:      "mention",
+				Text:      fmt.Sprintf("<at>%s</at>", user),
+				Mentioned: teamsMentionedUser{ID: user, Name: user},
+			})
+		}
+	}
+
+	return mentions
+}
+
+// teamsMarkdown converts the slack flavored inline code of the messages to bold text,
+// since Adaptive Cards don't support inline code
+func teamsMarkdown(text string) string {
+	return strings.ReplaceAll(text, "`", "**")
+}
+
+// post sends a single request and returns whether a failure is worth retrying
+func (tr *TeamsReceiver) post(url string, payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+
+	return doRequest(tr.HTTPClient, req, teamsReceiverName)
+}
@@ -0,0 +1,121 @@
+package receivers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMockTeamsReceiver(urls ...string) *TeamsReceiver {
+	return &TeamsReceiver{
+		URLs:         urls,
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+		HTTPClient:   &http.Client{Timeout: time.Second},
+	}
+}
+
+func TestTeamsHandleEvent(t *testing.T) {
+	event := ReceiverEvent{EventName: UpdateEvent, Message: "A `pod` has been `Updated`\nController kind:`ReplicaSet`", Cluster: "mockCluster",
+		Kind: "Pod", Namespace: "mockNamespace", Name: "mockPod", Owner: Owner{Kind: "ReplicaSet", Name: "mockReplicaSet"},
+		Severity: CriticalSeverity, Reason: "CrashLoopBackOff", Container: "mockContainer", ContainerLogs: "mockLogs\n",
+		Annotations: map[string]string{teamsMentionsAnnotationName: "first@mock.com, second@mock.com"}}
+
+	var message teamsMessage
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Errorf("TestTeamsHandleEvent: unexpected payload %s. err: %v", string(body), err)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	c := make(chan error)
+	go newMockTeamsReceiver(server.URL).HandleEvent(event, c)
+
+	if err := <-c; err != nil {
+		t.Fatalf("TestTeamsHandleEvent: unexpected error: %s", err)
+	}
+
+	if message.Type != "message" || len(message.Attachments) != 1 || message.Attachments[0].ContentType != teamsCardContentType {
+		t.Fatalf("TestTeamsHandleEvent: expected a message with a single adaptive card. got %+v", message)
+	}
+
+	card := message.Attachments[0].Content
+	if card.Type != "AdaptiveCard" || card.Body[0]["style"] != "attention" {
+		t.Errorf("TestTeamsHandleEvent: expected an adaptive card with attention style. got %+v", card)
+	}
+
+	if len(card.MSTeams.Entities) != 2 || card.MSTeams.Entities[0].Mentioned.ID != "first@mock.com" || card.MSTeams.Entities[1].Text != "<at>second@mock.com</at>" {
+		t.Errorf("TestTeamsHandleEvent: unexpected mentions %+v", card.MSTeams.Entities)
+	}
+
+	cardJSON, _ := json.Marshal(card)
+	for _, expected := range []string{`"title":"Namespace","value":"mockNamespace"`, `"title":"Pod","value":"mockPod"`,
+		`"title":"Controller","value":"ReplicaSet/mockReplicaSet"`, `"title":"Cluster","value":"mockCluster"`,
+		"A **pod** has been **Updated**", `"fontType":"Monospace"`} {
+		if !strings.Contains(string(cardJSON), expected) {
+			t.Errorf("TestTeamsHandleEvent: expected the card to contain %s. card: %s", expected, string(cardJSON))
+		}
+	}
+}
+
+func TestTeamsStyle(t *testing.T) {
+	tests := []struct {
+		event ReceiverEvent
+		style string
+	}{
+		{ReceiverEvent{EventName: AddEvent, Kind: "Pod", Severity: InfoSeverity}, "good"},
+		{ReceiverEvent{EventName: UpdateEvent, Kind: "Pod", Severity: InfoSeverity}, "warning"},
+		{ReceiverEvent{EventName: DeleteEvent, Kind: "Pod", Severity: WarningSeverity}, "attention"},
+		{ReceiverEvent{EventName: AddEvent, Kind: "Event", Severity: WarningSeverity}, "warning"},
+		{ReceiverEvent{EventName: UpdateEvent, Kind: "Deployment", Reason: "RolloutCompleted", Severity: InfoSeverity}, "good"},
+		{ReceiverEvent{EventName: UpdateEvent, Kind: "Node", Reason: "NodeReady", Severity: InfoSeverity}, "good"},
+		{ReceiverEvent{EventName: UpdateEvent, Kind: "Node", Reason: "NodeNotReady", Severity: CriticalSeverity}, "attention"},
+	}
+
+	for _, test := range tests {
+		if style := teamsStyle(test.event); style != test.style {
+			t.Errorf("TestTeamsStyle: expected %s style for %s %s event but got %s", test.style, test.event.Kind, test.event.EventName, style)
+		}
+	}
+}
+
+func TestTeamsMentionsOnInfoEvents(t *testing.T) {
+	event := ReceiverEvent{Severity: InfoSeverity, Annotations: map[string]string{teamsMentionsAnnotationName: "first@mock.com"}}
+
+	if mentions := teamsMentions(event); len(mentions) != 0 {
+		t.Errorf("TestTeamsMentionsOnInfoEvents: expected no mentions on info events but got %d", len(mentions))
+	}
+}
+
+func TestTeamsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c := make(chan error)
+	go newMockTeamsReceiver(server.URL).HandleEvent(ReceiverEvent{EventName: AddEvent}, c)
+
+	if err := <-c; err == nil {
+		t.Error("TestTeamsFailure: should receive an error for a bad request response")
+	}
+}
+
+func TestTeamsWithoutURLs(t *testing.T) {
+	c := make(chan error)
+	go newMockTeamsReceiver().HandleEvent(ReceiverEvent{EventName: AddEvent}, c)
+
+	if err := <-c; err == nil {
+		t.Error("TestTeamsWithoutURLs: should receive an error when no urls are configured")
+	}
+}
@@ -0,0 +1,225 @@
+package receivers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultTemplateNames maps the kinds that have a built-in message template to the template name
+var defaultTemplateNames = map[string]string{
+	"Pod":                     "pod",
+	"HorizontalPodAutoscaler": "hpa",
+}
+
+// defaultTemplates are the built-in message templates. they are rendered with the ReceiverEvent
+// and can be overridden by templates with the same name in the configuration
+var defaultTemplates = map[string]string{
+	"pod": `{{- if eq .EventName "Add" -}}
+A ` + "`pod`" + ` in namespace ` + "`{{.Namespace}}`" + ` has been ` + "`Created`" + `
+Pod name:` + "`{{podName .}}`" + `
+Environment:` + "`{{.Cluster}}`" + `
+Controller kind:` + "`{{.Owner.Kind}}`" + `. Controller name:` + "`{{.Owner.Name}}`" + `
+{{else if eq .EventName "Delete" -}}
+The pod ` + "`{{.Namespace}}/{{.Name}}`" + ` in ` + "`{{.Cluster}}`" + ` cluster has been deleted
+{{else -}}
+A ` + "`pod`" + ` in namespace ` + "`{{.Namespace}}`" + ` has been ` + "`Updated`" + `. Pod-Name:` + "`{{podName .}}`" + `. Environment:` + "`{{.Cluster}}`" + `.
+Controller kind:` + "`{{.Owner.Kind}}`" + `. Controller name:` + "`{{.Owner.Name}}`" + `. Updates:
+{{range .AdditionalInfo.updates}}- {{.}}{{end}}
+{{- end}}`,
+
+	"hpa": `{{- $hpa := printf "%s/%s" .Namespace .Name -}}
+{{- if eq .EventName "Add" -}}
+New HorizontalPodAutoscaler resource [` + "`{{$hpa}}`" + `] added to ` + "`{{.Cluster}}`" + ` cluster
+{{- else if eq .EventName "Delete" -}}
+HorizontalPodAutoscaler resource [` + "`{{$hpa}}`" + `] has been deleted from ` + "`{{.Cluster}}`" + ` cluster
+{{- else -}}
+{{- $direction := .AdditionalInfo.scale_direction -}}
+{{- $replicas := printf "current-replicas:` + "`%v`" + ` desired-replicas:` + "`%v`" + `" .AdditionalInfo.current_replicas .AdditionalInfo.desired_replicas -}}
+{{- if eq .AdditionalInfo.scale_phase "progress" -}}
+HorizontalPodAutoscaler[` + "`{{$hpa}}`" + `] scale ` + "`{{$direction}}`" + ` event progress has updated in ` + "`{{.Cluster}}`" + ` cluster. {{$replicas}}
+{{- else if eq .AdditionalInfo.scale_phase "finished" -}}
+HorizontalPodAutoscaler[` + "`{{$hpa}}`" + `] scale ` + "`{{$direction}}`" + ` event has finished in ` + "`{{.Cluster}}`" + ` cluster. {{$replicas}}
+{{- else -}}
+scale ` + "`{{$direction}}`" + ` event has been detected by HorizontalPodAutoscaler[` + "`{{$hpa}}`" + `] in ` + "`{{.Cluster}}`" + ` cluster. starting to ` + "`{{if eq $direction \"UP\"}}increase{{else}}decrease{{end}}`" + ` pod number. {{$replicas}}
+{{- end -}}
+{{- end -}}`,
+
+	// slack shows the pod details as message fields and the container updates as a code block
+	"slack.pod": `{{- if eq .EventName "Add" -}}
+A ` + "`pod`" + ` has been ` + "`Created`" + `
+{{- else if eq .EventName "Delete" -}}
+The pod has been ` + "`Deleted`" + `
+{{- else -}}
+A ` + "`pod`" + ` has been ` + "`Updated`" + `{{with .Reason}}. Reason:` + "`{{.}}`" + `{{end}}
+{{- end -}}`,
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"default": func(defaultValue interface{}, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return defaultValue
+		}
+
+		return value
+	},
+	// podName is the name of the pod in the messages. statefulset pods reuse their names,
+	// so the uid is added to tell the instances apart
+	"podName": func(receiverEvent ReceiverEvent) string {
+		name := fmt.Sprintf("%s/%s", receiverEvent.Namespace, receiverEvent.Name)
+		if receiverEvent.Owner.Kind == "StatefulSet" {
+			name = fmt.Sprintf("%s-%s", name, receiverEvent.UID)
+		}
+
+		return name
+	},
+}
+
+// messageTemplates holds the parsed templates of the configuration and the built-in templates, by name
+var messageTemplates = struct {
+	sync.RWMutex
+	builtIn    map[string]*template.Template
+	configured map[string]*template.Template
+}{builtIn: parseTemplates(defaultTemplates)}
+
+func init() {
+	loadMessageTemplates()
+}
+
+func parseTemplates(texts map[string]string) map[string]*template.Template {
+	templates := make(map[string]*template.Template)
+
+	for name, text := range texts {
+		t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			metrics.TemplateErrors.WithLabelValues(name).Inc()
+			log.Error().Msg(fmt.Sprintf("couldn't parse message template %s, the default template is used instead: %s", name, err.Error()))
+			continue
+		}
+
+		templates[name] = t
+	}
+
+	return templates
+}
+
+// loadMessageTemplates parses the message templates of the current configuration
+func loadMessageTemplates() {
+	templates := parseTemplates(config.MessageTemplates())
+
+	messageTemplates.Lock()
+	defer messageTemplates.Unlock()
+
+	messageTemplates.configured = templates
+}
+
+func getMessageTemplate(name string) *template.Template {
+	messageTemplates.RLock()
+	defer messageTemplates.RUnlock()
+
+	if t, ok := messageTemplates.configured[name]; ok {
+		return t
+	}
+
+	return messageTemplates.builtIn[name]
+}
+
+// RenderMessage renders the message of the event with the template of its kind. configured templates
+// override the built-in ones, and a template that fails falls back to the built-in template.
+// kinds without a template get an empty message, so their watchers build the message themselves
+func RenderMessage(receiverEvent ReceiverEvent) string {
+	name, ok := defaultTemplateNames[receiverEvent.Kind]
+	if !ok {
+		return ""
+	}
+
+	if message, err := renderTemplate(getMessageTemplate(name), receiverEvent); err == nil {
+		return message
+	}
+
+	message, _ := renderTemplate(messageTemplates.builtIn[name], receiverEvent)
+
+	return message
+}
+
+// renderMessage returns the message of the event for the given receiver. the template requested by
+// the event route and the templates named <receiver>.<template> take precedence over the message
+// the watcher has rendered. the message of the watcher is kept when they fail, and for aggregated
+// events that summarize several events
+func renderMessage(receiverName string, receiverEvent ReceiverEvent) string {
+	if isAggregated(receiverEvent) {
+		return receiverEvent.Message
+	}
+
+	templates := make([]*template.Template, 0, 3)
+
+	if receiverEvent.Template != "" {
+		if t := getMessageTemplate(receiverName + "." + receiverEvent.Template); t != nil {
+			templates = append(templates, t)
+		}
+
+		// the route template should exist, unlike the optional receiver templates
+		if t := getMessageTemplate(receiverEvent.Template); t != nil {
+			templates = append(templates, t)
+		} else {
+			metrics.TemplateErrors.WithLabelValues(receiverEvent.Template).Inc()
+			log.Error().Msg(fmt.Sprintf("message template %s of %s %s/%s doesn't exist", receiverEvent.Template, receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name))
+		}
+	}
+
+	if name, ok := defaultTemplateNames[receiverEvent.Kind]; ok {
+		if t := getReceiverTemplate(receiverName, name); t != nil {
+			templates = append(templates, t)
+		}
+	}
+
+	for _, t := range templates {
+		if message, err := renderTemplate(t, receiverEvent); err == nil {
+			return message
+		}
+	}
+
+	return receiverEvent.Message
+}
+
+// getReceiverTemplate returns the <receiver>.<name> template. a built-in receiver template
+// isn't used when the <name> template was configured, so the configured wording wins
+func getReceiverTemplate(receiverName string, name string) *template.Template {
+	messageTemplates.RLock()
+	defer messageTemplates.RUnlock()
+
+	if t, ok := messageTemplates.configured[receiverName+"."+name]; ok {
+		return t
+	}
+
+	if _, ok := messageTemplates.configured[name]; ok {
+		return nil
+	}
+
+	return messageTemplates.builtIn[receiverName+"."+name]
+}
+
+func renderTemplate(t *template.Template, receiverEvent ReceiverEvent) (string, error) {
+	if t == nil {
+		return "", fmt.Errorf("no template")
+	}
+
+	var message bytes.Buffer
+	if err := t.Execute(&message, receiverEvent); err != nil {
+		metrics.TemplateErrors.WithLabelValues(t.Name()).Inc()
+		log.Error().Msg(fmt.Sprintf("couldn't render message template %s of %s %s/%s: %s", t.Name(), receiverEvent.Kind, receiverEvent.Namespace, receiverEvent.Name, err.Error()))
+
+		return "", err
+	}
+
+	return message.String(), nil
+}
@@ -0,0 +1,135 @@
+package receivers
+
+import (
+	"testing"
+
+	"github.com/PayU/kubeobserver/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func setMockTemplates(texts map[string]string) func() {
+	messageTemplates.Lock()
+	defer messageTemplates.Unlock()
+
+	previous := messageTemplates.configured
+	messageTemplates.configured = parseTemplates(texts)
+
+	return func() {
+		messageTemplates.Lock()
+		defer messageTemplates.Unlock()
+
+		messageTemplates.configured = previous
+	}
+}
+
+func mockPodEvent(eventName EventName) ReceiverEvent {
+	return ReceiverEvent{EventName: eventName, Cluster: "mockCluster", Kind: "Pod", Namespace: "mockNamespace", Name: "mockPod",
+		UID: "1234", Owner: Owner{Kind: "StatefulSet", Name: "mockStatefulSet"}, AdditionalInfo: map[string]interface{}{}}
+}
+
+func TestRenderDefaultPodMessages(t *testing.T) {
+	added := "A `pod` in namespace `mockNamespace` has been `Created`\nPod name:`mockNamespace/mockPod-1234`\n" +
+		"Environment:`mockCluster`\nController kind:`StatefulSet`. Controller name:`mockStatefulSet`\n"
+	if message := RenderMessage(mockPodEvent(AddEvent)); message != added {
+		t.Errorf("TestRenderDefaultPodMessages: unexpected add message:\n%s", message)
+	}
+
+	deleted := "The pod `mockNamespace/mockPod` in `mockCluster` cluster has been deleted\n"
+	if message := RenderMessage(mockPodEvent(DeleteEvent)); message != deleted {
+		t.Errorf("TestRenderDefaultPodMessages: unexpected delete message:\n%s", message)
+	}
+
+	event := mockPodEvent(UpdateEvent)
+	event.AdditionalInfo["updates"] = []string{"the container app is running\n", "the container sidecar is running\n"}
+	updated := "A `pod` in namespace `mockNamespace` has been `Updated`. Pod-Name:`mockNamespace/mockPod-1234`. Environment:`mockCluster`.\n" +
+		"Controller kind:`StatefulSet`. Controller name:`mockStatefulSet`. Updates:\n" +
+		"- the container app is running\n- the container sidecar is running\n"
+	if message := RenderMessage(event); message != updated {
+		t.Errorf("TestRenderDefaultPodMessages: unexpected update message:\n%s", message)
+	}
+}
+
+func TestRenderDefaultHpaMessages(t *testing.T) {
+	event := ReceiverEvent{EventName: UpdateEvent, Cluster: "mockCluster", Kind: "HorizontalPodAutoscaler", Namespace: "mockNamespace", Name: "mockHpa",
+		AdditionalInfo: map[string]interface{}{"scale_direction": "UP", "scale_phase": "detected", "current_replicas": 2, "desired_replicas": 4}}
+
+	expected := "scale `UP` event has been detected by HorizontalPodAutoscaler[`mockNamespace/mockHpa`] in `mockCluster` cluster. " +
+		"starting to `increase` pod number. current-replicas:`2` desired-replicas:`4`"
+	if message := RenderMessage(event); message != expected {
+		t.Errorf("TestRenderDefaultHpaMessages: unexpected message:\n%s", message)
+	}
+
+	event.EventName = DeleteEvent
+	expected = "HorizontalPodAutoscaler resource [`mockNamespace/mockHpa`] has been deleted from `mockCluster` cluster"
+	if message := RenderMessage(event); message != expected {
+		t.Errorf("TestRenderDefaultHpaMessages: unexpected delete message:\n%s", message)
+	}
+}
+
+func TestRenderConfiguredTemplates(t *testing.T) {
+	defer setMockTemplates(map[string]string{
+		"pod":       "{{.Reason}} pod {{.Namespace}}/{{.Name}}",
+		"slack.pod": "slack: {{.Reason}} pod {{.Name}}",
+		"payments":  "payments: {{.Name}}",
+		"broken":    "{{.Name",
+	})()
+
+	event := mockPodEvent(UpdateEvent)
+	event.Reason = "OOMKilled"
+
+	event.Message = RenderMessage(event)
+	if event.Message != "OOMKilled pod mockNamespace/mockPod" {
+		t.Errorf("TestRenderConfiguredTemplates: configured template should override the default, got: %s", event.Message)
+	}
+
+	if message := renderMessage(slackReceiverName, event); message != "slack: OOMKilled pod mockPod" {
+		t.Errorf("TestRenderConfiguredTemplates: receiver template should be used, got: %s", message)
+	}
+
+	if message := renderMessage(logReceiverName, event); message != event.Message {
+		t.Errorf("TestRenderConfiguredTemplates: receiver without a template should keep the message, got: %s", message)
+	}
+
+	event.Template = "payments"
+	if message := renderMessage(slackReceiverName, event); message != "payments: mockPod" {
+		t.Errorf("TestRenderConfiguredTemplates: route template should take precedence, got: %s", message)
+	}
+
+	if getMessageTemplate("broken") != nil {
+		t.Error("TestRenderConfiguredTemplates: template with a parse error shouldn't be used")
+	}
+}
+
+func TestRenderTemplateErrorFallback(t *testing.T) {
+	defer setMockTemplates(map[string]string{
+		"pod":        "{{.Name.Missing}}",
+		"slack.fail": "{{.Name.Missing}}",
+	})()
+
+	errors := testutil.ToFloat64(metrics.TemplateErrors.WithLabelValues("pod"))
+
+	event := mockPodEvent(DeleteEvent)
+	event.Message = RenderMessage(event)
+	if event.Message != "The pod `mockNamespace/mockPod` in `mockCluster` cluster has been deleted\n" {
+		t.Errorf("TestRenderTemplateErrorFallback: failed template should fall back to the default, got: %s", event.Message)
+	}
+
+	if count := testutil.ToFloat64(metrics.TemplateErrors.WithLabelValues("pod")); count != errors+1 {
+		t.Errorf("TestRenderTemplateErrorFallback: expected the template error to be counted, got %v", count)
+	}
+
+	event.Template = "fail"
+	if message := renderMessage(slackReceiverName, event); message != event.Message {
+		t.Errorf("TestRenderTemplateErrorFallback: failed route template should keep the message, got: %s", message)
+	}
+
+	event.Template = "unknown"
+	missing := testutil.ToFloat64(metrics.TemplateErrors.WithLabelValues("unknown"))
+	if message := renderMessage(slackReceiverName, event); message != event.Message {
+		t.Errorf("TestRenderTemplateErrorFallback: unknown route template should keep the message, got: %s", message)
+	}
+
+	if count := testutil.ToFloat64(metrics.TemplateErrors.WithLabelValues("unknown")); count != missing+1 {
+		t.Errorf("TestRenderTemplateErrorFallback: expected the unknown template to be counted, got %v", count)
+	}
+}
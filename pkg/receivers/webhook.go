@@ -0,0 +1,132 @@
+package receivers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+)
+
+var webhookReceiverName = "webhook"
+var webhookSignatureHeaderName = "X-Kubeobserver-Signature"
+var webhookUserAgent = "kubeobserver"
+
+// WebhookReceiver is a struct built for receiving and passing onward events as JSON to http endpoints
+type WebhookReceiver struct {
+	URLs         []string
+	Headers      map[string]string
+	Secret       string
+	Retries      int
+	RetryBackoff time.Duration
+	HTTPClient   *http.Client
+}
+
+func init() {
+	registerReceiver(webhookReceiverName, newWebhookReceiver)
+	registerReceiverType(webhookReceiverName, func(instance config.ReceiverInstance) Receiver {
+		return NewWebhookReceiver(instance.Webhook)
+	})
+}
+
+func newWebhookReceiver() Receiver {
+	return NewWebhookReceiver(config.WebhookSettings{
+		URLs:    config.WebhookURLs(),
+		Headers: config.WebhookHeaders(),
+		Secret:  config.WebhookSecret(),
+		Timeout: config.WebhookTimeout(),
+		Retries: config.WebhookRetries(),
+	})
+}
+
+// NewWebhookReceiver builds a webhook receiver from its settings
+func NewWebhookReceiver(settings config.WebhookSettings) *WebhookReceiver {
+	return &WebhookReceiver{
+		URLs:         settings.URLs,
+		Headers:      settings.Headers,
+		Secret:       settings.Secret,
+		Retries:      settings.Retries,
+		RetryBackoff: time.Second,
+		HTTPClient:   &http.Client{Timeout: settings.Timeout},
+	}
+}
+
+// HandleEvent is an implementation of the Receiver interface for webhooks
+func (wr *WebhookReceiver) HandleEvent(receiverEvent ReceiverEvent, c chan error) {
+	// no matter what happens, close the channel after function exits
+	defer close(c)
+
+	receiverEvent.Message = renderMessage(webhookReceiverName, receiverEvent)
+
+	// this will be true in case some event has webhook receiver
+	// but no urls were provided in the configuration
+	if len(wr.URLs) == 0 {
+		c <- errors.New("HandleEvent of webhook was triggered but no webhook urls were found in configuration")
+		return
+	}
+
+	payload, err := json.Marshal(receiverEvent)
+	if err != nil {
+		c <- fmt.Errorf("webhook receiver couldn't marshal the event -> %s", err.Error())
+		return
+	}
+
+	errorsStr := make([]string, 0)
+
+	for _, url := range wr.URLs {
+		if err := wr.postWithRetries(url, payload); err != nil {
+			errorsStr = append(errorsStr, err.Error())
+		}
+	}
+
+	// the receivers contract allows a single error per event
+	if len(errorsStr) > 0 {
+		c <- fmt.Errorf("webhook receiver got unexpected error -> %s", strings.Join(errorsStr, "; "))
+	}
+}
+
+// postWithRetries posts the payload to the given url. failed requests (network errors,
+// 5xx and 429 responses) are retried with an exponential backoff
+func (wr *WebhookReceiver) postWithRetries(url string, payload []byte) error {
+	return retryRequest(webhookReceiverName, url, wr.Retries, wr.RetryBackoff, func() (bool, error) {
+		return wr.post(url, payload)
+	})
+}
+
+// post sends a single request and returns whether a failure is worth retrying
+func (wr *WebhookReceiver) post(url string, payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+
+	for name, value := range wr.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if wr.Secret != "" {
+		req.Header.Set(webhookSignatureHeaderName, SignWebhookPayload(wr.Secret, payload))
+	}
+
+	return doRequest(wr.HTTPClient, req, webhookReceiverName)
+}
+
+// SignWebhookPayload returns the value of the signature header for the given payload.
+// the signature is an HMAC-SHA256 of the request body, hex encoded and prefixed with the algorithm name.
+// endpoints should compute the same value with the shared secret in order to verify the request
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
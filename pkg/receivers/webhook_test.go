@@ -0,0 +1,109 @@
+package receivers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newMockWebhookReceiver(urls ...string) *WebhookReceiver {
+	return &WebhookReceiver{
+		URLs:         urls,
+		Headers:      map[string]string{"X-Mock-Header": "mockValue"},
+		Secret:       "mockSecret",
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+		HTTPClient:   &http.Client{Timeout: time.Second},
+	}
+}
+
+func TestWebhookHandleEvent(t *testing.T) {
+	event := ReceiverEvent{EventName: AddEvent, Message: "mockMessage", Kind: "Pod", Namespace: "mockNamespace", Name: "mockPod", Severity: InfoSeverity}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if r.Header.Get(webhookSignatureHeaderName) != SignWebhookPayload("mockSecret", body) {
+			t.Error("TestWebhookHandleEvent: request signature doesn't match the payload")
+		}
+
+		if r.Header.Get("X-Mock-Header") != "mockValue" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("TestWebhookHandleEvent: request headers weren't set properly: %v", r.Header)
+		}
+
+		received := ReceiverEvent{}
+		if err := json.Unmarshal(body, &received); err != nil || received.Name != "mockPod" || received.Namespace != "mockNamespace" {
+			t.Errorf("TestWebhookHandleEvent: unexpected payload %s. err: %v", string(body), err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := make(chan error)
+	go newMockWebhookReceiver(server.URL).HandleEvent(event, c)
+
+	if err := <-c; err != nil {
+		t.Errorf("TestWebhookHandleEvent: unexpected error: %s", err)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := make(chan error)
+	go newMockWebhookReceiver(server.URL).HandleEvent(ReceiverEvent{EventName: UpdateEvent}, c)
+
+	if err := <-c; err != nil {
+		t.Errorf("TestWebhookRetries: request should succeed on the third attempt. err: %s", err)
+	}
+
+	if requests != 3 {
+		t.Errorf("TestWebhookRetries: expected 3 requests but got %d", requests)
+	}
+}
+
+func TestWebhookFailure(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c := make(chan error)
+	go newMockWebhookReceiver(server.URL).HandleEvent(ReceiverEvent{EventName: DeleteEvent}, c)
+
+	if err := <-c; err == nil {
+		t.Error("TestWebhookFailure: should receive an error for a bad request response")
+	}
+
+	// client errors are not retried
+	if requests != 1 {
+		t.Errorf("TestWebhookFailure: expected a single request but got %d", requests)
+	}
+}
+
+func TestWebhookWithoutURLs(t *testing.T) {
+	c := make(chan error)
+	go newMockWebhookReceiver().HandleEvent(ReceiverEvent{EventName: AddEvent}, c)
+
+	if err := <-c; err == nil {
+		t.Error("TestWebhookWithoutURLs: should receive an error when no urls are configured")
+	}
+}
@@ -11,6 +11,7 @@ import (
 type healthResponse struct {
 	IsHealthy           bool `json:"is_healthy"`
 	IsPodControllerSync bool `json:"is_pod_controller_sync"`
+	IsLeader            bool `json:"is_leader"`
 }
 
 // HealthHandler is the handler function for GET /health
@@ -24,6 +25,7 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	resBody := healthResponse{
 		IsHealthy:           isHealthy,
 		IsPodControllerSync: isHealthy,
+		IsLeader:            controller.IsLeader(),
 	}
 
 	jsResponse, _ := json.Marshal(resBody)
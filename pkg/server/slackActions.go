@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/common"
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/controller"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/rs/zerolog/log"
+	"github.com/slack-go/slack"
+)
+
+// the duration of the silence button
+const slackSilenceDuration = time.Hour
+
+// slackActionMaxBodySize limits the body of the action requests, which are read before their signature is verified
+const slackActionMaxBodySize = 1 << 20
+
+// replaced in tests
+var (
+	slackSigningSecret = config.SlackSigningSecret
+	replySlackThread   = receivers.ReplySlackThread
+)
+
+// SlackActionsHandler is the handler function for POST /slack/actions.
+// slack sends it the interactivity payloads of the buttons of the messages
+func SlackActionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	signingSecret := slackSigningSecret()
+	if signingSecret == "" {
+		log.Debug().Msg("got POST /slack/actions request but slack actions aren't configured")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("rejected slack action request: %s", err.Error()))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.TeeReader(http.MaxBytesReader(w, r.Body, slackActionMaxBodySize), &verifier))
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("couldn't read slack action request: %s", err.Error()))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = verifier.Ensure(); err != nil {
+		log.Error().Msg(fmt.Sprintf("rejected slack action request with an invalid signature: %s", err.Error()))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("couldn't parse slack action request: %s", err.Error()))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var payload slack.InteractionCallback
+	if err = json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		log.Error().Msg(fmt.Sprintf("couldn't parse slack action payload: %s", err.Error()))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// slack expects a response within 3 seconds, so the actions run in the background
+	// and their result is written back into the thread of the message
+	w.WriteHeader(http.StatusOK)
+
+	if payload.Type != slack.InteractionTypeBlockActions {
+		log.Debug().Msg(fmt.Sprintf("ignoring slack interaction of type %s", payload.Type))
+		return
+	}
+
+	threadTS := slackActionThread(payload)
+	for _, action := range payload.ActionCallback.BlockActions {
+		go func(action *slack.BlockAction) {
+			text := handleSlackAction(payload.User.ID, action)
+			if text == "" {
+				return
+			}
+
+			if err := replySlackThread(payload.Channel.ID, threadTS, text); err != nil {
+				log.Error().Msg(fmt.Sprintf("couldn't reply to slack action %s: %s", action.ActionID, err.Error()))
+			}
+		}(action)
+	}
+}
+
+// slackActionThread returns the thread the reply of the action should be posted to. buttons
+// of messages in a thread reply there, otherwise the message becomes the parent of a new thread
+func slackActionThread(payload slack.InteractionCallback) string {
+	if payload.Message.ThreadTimestamp != "" {
+		return payload.Message.ThreadTimestamp
+	}
+
+	if payload.Message.Timestamp != "" {
+		return payload.Message.Timestamp
+	}
+
+	return payload.Container.MessageTs
+}
+
+// handleSlackAction runs the action and returns the text to write back into the thread,
+// or an empty string when the action is ignored
+func handleSlackAction(user string, action *slack.BlockAction) string {
+	target, err := receivers.ParseSlackActionTarget(action.Value)
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("ignoring slack action %s: %s", action.ActionID, err.Error()))
+		return ""
+	}
+
+	// all the replicas of all the clusters may share the same slack app
+	if target.Cluster != config.ClusterName() {
+		log.Debug().Msg(fmt.Sprintf("ignoring slack action %s of cluster %s", action.ActionID, target.Cluster))
+		return ""
+	}
+
+	workload := fmt.Sprintf("%s `%s/%s`", target.Kind, target.Namespace, target.Name)
+	log.Info().Msg(fmt.Sprintf("slack user %s triggered action %s on %s %s/%s", user, action.ActionID, target.Kind, target.Namespace, target.Name))
+
+	switch action.ActionID {
+	case receivers.SlackAckAction:
+		return fmt.Sprintf(":white_check_mark: <@%s> acknowledged %s", user, workload)
+	case receivers.SlackSilenceAction:
+		until, err := controller.SilenceWorkload(target.Namespace, target.Kind, target.Name, slackSilenceDuration)
+		if err != nil {
+			log.Error().Msg(err.Error())
+			return fmt.Sprintf(":x: <@%s> couldn't silence %s: %s", user, workload, err.Error())
+		}
+
+		return fmt.Sprintf(":mute: <@%s> silenced %s until %s", user, workload, until.UTC().Format(time.RFC1123))
+	case receivers.SlackRestartAction:
+		// the allowlist is checked again since the button may be older than the configuration
+		if target.Kind != common.DeploymentKind || !receivers.SlackRestartAllowed(config.SlackRestartAllowlist(), target.Namespace, target.Name) {
+			return fmt.Sprintf(":no_entry: <@%s> isn't allowed to restart %s", user, workload)
+		}
+
+		if err := controller.RolloutRestart(target.Namespace, target.Name); err != nil {
+			log.Error().Msg(err.Error())
+			return fmt.Sprintf(":x: <@%s> couldn't restart %s: %s", user, workload, err.Error())
+		}
+
+		return fmt.Sprintf(":arrows_counterclockwise: <@%s> restarted %s", user, workload)
+	default:
+		log.Debug().Msg(fmt.Sprintf("ignoring unknown slack action %s", action.ActionID))
+		return ""
+	}
+}
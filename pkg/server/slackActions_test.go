@@ -0,0 +1,143 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PayU/kubeobserver/pkg/config"
+	"github.com/PayU/kubeobserver/pkg/receivers"
+	"github.com/slack-go/slack"
+)
+
+const mockSigningSecret = "mock_signing_secret"
+
+type slackReply struct {
+	channel  string
+	threadTS string
+	text     string
+}
+
+func mockSlackActions() (chan slackReply, func()) {
+	replies := make(chan slackReply, 1)
+	previousSecret, previousReply := slackSigningSecret, replySlackThread
+
+	slackSigningSecret = func() string { return mockSigningSecret }
+	replySlackThread = func(channel string, threadTS string, text string) error {
+		replies <- slackReply{channel: channel, threadTS: threadTS, text: text}
+		return nil
+	}
+
+	return replies, func() {
+		slackSigningSecret, replySlackThread = previousSecret, previousReply
+	}
+}
+
+func mockSlackActionRequest(payload string, secret string, timestamp time.Time) *http.Request {
+	body := url.Values{"payload": {payload}}.Encode()
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+
+	r := httptest.NewRequest(http.MethodPost, "/slack/actions", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+	return r
+}
+
+func mockSlackActionPayload(actionID string, cluster string) string {
+	value := fmt.Sprintf(`{\"cluster\":\"%s\",\"namespace\":\"payments\",\"kind\":\"Deployment\",\"name\":\"checkout\",\"pod\":\"checkout-5d8f7-x2x1\"}`, cluster)
+	return fmt.Sprintf(`{"type":"block_actions","user":{"id":"U123"},"channel":{"id":"C123"},"message":{"ts":"1600000000.000100"},`+
+		`"actions":[{"type":"button","action_id":"%s","block_id":"kubeobserver_actions","value":"%s"}]}`, actionID, value)
+}
+
+func TestSlackActionsHandlerVerifiesSignature(t *testing.T) {
+	_, restore := mockSlackActions()
+	defer restore()
+
+	tests := []struct {
+		name    string
+		request *http.Request
+	}{
+		{"invalid signature", mockSlackActionRequest(mockSlackActionPayload(receivers.SlackAckAction, config.ClusterName()), "wrong_secret", time.Now())},
+		{"expired timestamp", mockSlackActionRequest(mockSlackActionPayload(receivers.SlackAckAction, config.ClusterName()), mockSigningSecret, time.Now().Add(-10*time.Minute))},
+		{"missing headers", httptest.NewRequest(http.MethodPost, "/slack/actions", strings.NewReader("payload={}"))},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		SlackActionsHandler(w, test.request)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("TestSlackActionsHandlerVerifiesSignature: %s should be rejected, got status %d", test.name, w.Code)
+		}
+	}
+}
+
+func TestSlackActionsHandlerLimitsBody(t *testing.T) {
+	_, restore := mockSlackActions()
+	defer restore()
+
+	payload := mockSlackActionPayload(receivers.SlackAckAction, config.ClusterName()) + strings.Repeat(" ", slackActionMaxBodySize)
+
+	w := httptest.NewRecorder()
+	SlackActionsHandler(w, mockSlackActionRequest(payload, mockSigningSecret, time.Now()))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("TestSlackActionsHandlerLimitsBody: a body over the limit should be rejected, got status %d", w.Code)
+	}
+}
+
+func TestSlackActionsHandlerAck(t *testing.T) {
+	replies, restore := mockSlackActions()
+	defer restore()
+
+	w := httptest.NewRecorder()
+	SlackActionsHandler(w, mockSlackActionRequest(mockSlackActionPayload(receivers.SlackAckAction, config.ClusterName()), mockSigningSecret, time.Now()))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("TestSlackActionsHandlerAck: expected status 200, got %d", w.Code)
+	}
+
+	select {
+	case reply := <-replies:
+		if reply.channel != "C123" || reply.threadTS != "1600000000.000100" {
+			t.Errorf("TestSlackActionsHandlerAck: reply should be posted in the thread of the message, got %+v", reply)
+		}
+
+		if reply.text != ":white_check_mark: <@U123> acknowledged Deployment `payments/checkout`" {
+			t.Errorf("TestSlackActionsHandlerAck: unexpected reply: %s", reply.text)
+		}
+	case <-time.After(time.Second):
+		t.Error("TestSlackActionsHandlerAck: the action wasn't written back into the thread")
+	}
+}
+
+func TestHandleSlackActionIgnoresOtherClusters(t *testing.T) {
+	action := &slack.BlockAction{ActionID: receivers.SlackAckAction,
+		Value: `{"cluster":"other_cluster","namespace":"payments","kind":"Deployment","name":"checkout"}`}
+
+	if text := handleSlackAction("U123", action); text != "" {
+		t.Errorf("TestHandleSlackActionIgnoresOtherClusters: action of another cluster should be ignored, got: %s", text)
+	}
+}
+
+func TestHandleSlackActionRestartNotAllowed(t *testing.T) {
+	action := &slack.BlockAction{ActionID: receivers.SlackRestartAction,
+		Value: fmt.Sprintf(`{"cluster":"%s","namespace":"payments","kind":"Deployment","name":"checkout"}`, config.ClusterName())}
+
+	if text := handleSlackAction("U123", action); !strings.HasPrefix(text, ":no_entry: <@U123> isn't allowed to restart") {
+		t.Errorf("TestHandleSlackActionRestartNotAllowed: restart outside of the allowlist should be refused, got: %s", text)
+	}
+}
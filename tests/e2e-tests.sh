@@ -79,7 +79,7 @@ echo "******************* RUNNING TESTS *********************"
 echo "1) pod watcher test"
 kubectl apply -f $PWD/tests/manifests/deployment.yaml
 sleep 5
-res=$(docker logs $DOCKER_NAME 2>&1 | grep "A \`pod\` in namesapce \`default\` has been \`Created\`")
+res=$(docker logs $DOCKER_NAME 2>&1 | grep "A \`pod\` in namespace \`default\` has been \`Created\`")
 
 if [ -z "$res" ]; then
     # we can miss the create event. so we will verify it happend